@@ -7,6 +7,7 @@ import (
 	"os"
 	"os/signal"
 	"path/filepath"
+	"sort"
 	"strings"
 	"syscall"
 	"time"
@@ -24,6 +25,7 @@ import (
 	"google.golang.org/protobuf/proto"
 
 	"whatsabladerunner/pkg/agent"
+	"whatsabladerunner/pkg/audit"
 	"whatsabladerunner/pkg/batata"
 	"whatsabladerunner/pkg/behaviors"
 	"whatsabladerunner/pkg/bot"
@@ -47,21 +49,12 @@ var (
 	historyStore   *history.HistoryStore
 	taskBot        *bot.Bot // Global bot instance for task handling
 	taskLocks      *locks.KeyedMutex
+	messenger      *whatsapp.Messenger // Reactions, edits, receipts and polls
 )
 
 // buttonManager handles interactive message context and responses
 var buttonManager *buttons.Manager
 
-// WithheldMessage stores a blocked message for potential "LET IT BE" override
-type WithheldMessage struct {
-	Message       string       // The blocked message text
-	TargetChatJID types.JID    // Where to send if unblocked
-	SendFunc      func(string) // Function to use for sending
-}
-
-// lastWithheldMessage stores the most recent watcher-blocked message
-var lastWithheldMessage *WithheldMessage
-
 const BotPrefix = "[Blady] : "
 
 func downloadMedia(msg *events.Message) ([]byte, string, string, error) {
@@ -108,6 +101,43 @@ func downloadMedia(msg *events.Message) ([]byte, string, string, error) {
 	return data, mtype, ext, nil
 }
 
+// mediaTypeOf returns the media type name used in storage (image, video, audio, docs)
+// or "text" for non-media messages.
+func mediaTypeOf(msg *events.Message) string {
+	if msg.Message == nil {
+		return "text"
+	}
+	switch {
+	case msg.Message.ImageMessage != nil:
+		return "image"
+	case msg.Message.VideoMessage != nil:
+		return "video"
+	case msg.Message.AudioMessage != nil:
+		return "audio"
+	case msg.Message.DocumentMessage != nil:
+		return "docs"
+	}
+	return "text"
+}
+
+// lookupActiveBehaviors returns the enabled behaviors for the message chat,
+// also matching SenderAlt for LID/JID ambiguity in 1:1 chats.
+func lookupActiveBehaviors(v *events.Message) []behaviors.Behavior {
+	if taskBot == nil || taskBot.BehaviorManager == nil {
+		return nil
+	}
+	var aliases []string
+	if !v.Info.IsGroup && !v.Info.MessageSource.SenderAlt.IsEmpty() {
+		aliases = append(aliases, v.Info.MessageSource.SenderAlt.String())
+	}
+	activeBehaviors, err := taskBot.BehaviorManager.GetActiveBehaviors(v.Info.Chat.String(), aliases...)
+	if err != nil {
+		fmt.Printf("Error checking behaviors: %v\n", err)
+		return nil
+	}
+	return activeBehaviors
+}
+
 func eventHandler(evt interface{}) {
 	switch v := evt.(type) {
 	case *events.Message:
@@ -122,33 +152,30 @@ func eventHandler(evt interface{}) {
 			// Extract text again or reuse if possible.
 			// Refactoring slightly to extract text earlier for both saving and workflow.
 
-			msgText := ""
+			msgText, msgMeta := whatsapp.Normalize(v.Message)
+			changed := false // An edit or revocation handled on the stored original
 			if v.Message != nil {
-				if v.Message.ExtendedTextMessage != nil {
-					msgText = *v.Message.ExtendedTextMessage.Text
-				} else if v.Message.Conversation != nil {
-					msgText = *v.Message.Conversation
-				} else if v.Message.ButtonsMessage != nil {
-					// Handle buttons message - extract content text and button options with JSON IDs
-					bm := v.Message.ButtonsMessage
-					if bm.ContentText != nil {
-						msgText = *bm.ContentText
+				switch msgMeta.Type {
+				case "edit", "revoke":
+					// Running tasks pick the change up through GetMessagesSince; the line is
+					// only stored when the original message isn't
+					changed = recordChange(v.Info.Chat.String(), v.Info.Sender.String(), v.Info.IsFromMe, msgMeta, v.Info.Timestamp)
+				case "reaction":
+					// Reactions become history lines so tasks and behaviors see them as answers
+					rm := v.Message.ReactionMessage
+					if target, err := historyStore.GetMessage(v.Info.Chat.String(), rm.GetKey().GetID()); err == nil && target != nil {
+						msgText = whatsapp.ReactionText(rm.GetText(), rm.GetKey().GetID(), target.Content)
 					}
-					// Append button options with both display text and button ID JSON
-					if len(bm.Buttons) > 0 {
-						msgText += "\n\n[Opciones de respuesta - responder con el buttonID JSON]:"
-						for _, btn := range bm.Buttons {
-							displayText := ""
-							buttonID := ""
-							if btn.ButtonText != nil && btn.ButtonText.DisplayText != nil {
-								displayText = *btn.ButtonText.DisplayText
-							}
-							if btn.ButtonID != nil {
-								buttonID = *btn.ButtonID
-							}
-							msgText += fmt.Sprintf("\n- \"%s\" -> buttonID: %s", displayText, buttonID)
-						}
+				case "poll":
+					msgText = messenger.RecordPoll(v)
+				case "poll_vote":
+					// Votes become history lines with the tally, so tasks see "3 votes for Friday"
+					text, err := messenger.RecordVote(v)
+					if err != nil {
+						fmt.Printf("Failed to record poll vote: %v\n", err)
 					}
+					msgText = text
+				case "buttons", "list":
 					// Store buttons context for later response
 					buttonManager.Store(v.Info.Chat.String(), &buttons.ButtonsContext{
 						MessageID: v.Info.ID,
@@ -157,47 +184,15 @@ func eventHandler(evt interface{}) {
 						SenderAlt: v.Info.MessageSource.SenderAlt,
 						Message:   v.Message,
 					})
-					fmt.Printf("[ButtonsContext] Stored buttons message ID=%s from chat=%s sender=%s senderAlt=%s\n",
-						v.Info.ID, v.Info.Chat, v.Info.Sender, v.Info.MessageSource.SenderAlt)
-				} else if v.Message.ListMessage != nil {
-					// Handle list message (dropdown options)
-					lm := v.Message.ListMessage
-					if lm.Description != nil {
-						msgText = *lm.Description
-					}
-					// Extract list sections and rows
-					if len(lm.Sections) > 0 {
-						msgText += "\n\n[Opciones de lista - responder con el rowID JSON]:"
-						for _, section := range lm.Sections {
-							for _, row := range section.Rows {
-								title := ""
-								rowID := ""
-								if row.Title != nil {
-									title = *row.Title
-								}
-								if row.RowID != nil {
-									rowID = *row.RowID
-								}
-								msgText += fmt.Sprintf("\n- \"%s\" -> rowID: %s", title, rowID)
-							}
-						}
-					}
-					// Store list context for later response
-					buttonManager.Store(v.Info.Chat.String(), &buttons.ButtonsContext{
-						MessageID: v.Info.ID,
-						ChatJID:   v.Info.Chat,
-						SenderJID: v.Info.Sender,
-						SenderAlt: v.Info.MessageSource.SenderAlt,
-						Message:   v.Message,
-					})
-					fmt.Printf("[ButtonsContext] Stored list message ID=%s from chat=%s sender=%s senderAlt=%s\n",
-						v.Info.ID, v.Info.Chat, v.Info.Sender, v.Info.MessageSource.SenderAlt)
+					fmt.Printf("[ButtonsContext] Stored %s message ID=%s from chat=%s sender=%s senderAlt=%s\n",
+						msgMeta.Type, v.Info.ID, v.Info.Chat, v.Info.Sender, v.Info.MessageSource.SenderAlt)
 				}
 			}
 
 			// Media Handling
 			isSelfChat := v.Info.IsFromMe && v.Info.Chat.User == v.Info.Sender.User
 			var activeTask *tasks.Task
+			var activeBehaviors []behaviors.Behavior
 
 			if v.Message != nil {
 				// Check if it's a media message
@@ -215,8 +210,14 @@ func eventHandler(evt interface{}) {
 					activeTask, _ = taskBot.TaskManager.GetTaskByContact(v.Info.MessageSource.SenderAlt.String())
 				}
 
-				if (isSelfChat || activeTask != nil) && isMedia {
-					fmt.Printf("Processing media message (Self: %v, Task: %v)\n", isSelfChat, activeTask != nil)
+				// Lookup active behaviors; their triggers may ask for this media to be downloaded
+				if !isSelfChat && !v.Info.IsFromMe {
+					activeBehaviors = lookupActiveBehaviors(v)
+				}
+				wantedByBehavior := len(activeBehaviors) > 0 && taskBot.BehaviorsWantMedia(activeBehaviors, mediaTypeOf(v))
+
+				if (isSelfChat || activeTask != nil || wantedByBehavior) && isMedia {
+					fmt.Printf("Processing media message (Self: %v, Task: %v, Behavior: %v)\n", isSelfChat, activeTask != nil, wantedByBehavior)
 					data, mtype, ext, err := downloadMedia(v)
 					if err != nil {
 						fmt.Printf("Failed to download media: %v\n", err)
@@ -303,8 +304,8 @@ func eventHandler(evt interface{}) {
 				}
 			}
 
-			if msgText != "" {
-				err := historyStore.SaveMessage(v.Info.ID, v.Info.Chat.String(), v.Info.Sender.String(), msgText, v.Info.Timestamp, v.Info.IsFromMe)
+			if msgText != "" && !changed {
+				err := historyStore.SaveMessageMeta(v.Info.ID, v.Info.Chat.String(), v.Info.Sender.String(), msgText, v.Info.Timestamp, v.Info.IsFromMe, msgMeta)
 				if err != nil {
 					fmt.Printf("Failed to save message to history: %v\n", err)
 				}
@@ -321,26 +322,19 @@ func eventHandler(evt interface{}) {
 						fmt.Println("Ignoring bot message")
 						return
 					}
+					// Reactions, votes, edits and deletions in the self-chat aren't commands:
+					// to re-run an edited command, send the new text as a new message
+					if msgMeta.Type == "reaction" || msgMeta.Type == "poll_vote" || msgMeta.Type == "edit" || msgMeta.Type == "revoke" {
+						return
+					}
 
-					// Handle "LET IT BE" override for watcher-blocked messages
-					if msgText == "LET IT BE" {
-						if lastWithheldMessage != nil {
-							fmt.Println("[LET IT BE] Overriding watcher block - sending withheld message")
-							lastWithheldMessage.SendFunc(lastWithheldMessage.Message)
-							lastWithheldMessage = nil
-							// Send confirmation
-							if whatsAppClient != nil {
-								whatsapp.SendWithStealth(context.Background(), whatsAppClient, v.Info.Chat, &waProto.Message{
-									Conversation: proto.String("[Blady][Watcher] : Message sent."),
-								})
-							}
-						} else {
-							fmt.Println("[LET IT BE] No withheld message to send")
-							if whatsAppClient != nil {
-								whatsapp.SendWithStealth(context.Background(), whatsAppClient, v.Info.Chat, &waProto.Message{
-									Conversation: proto.String("[Blady][Watcher] : No blocked message to release."),
-								})
-							}
+					// Handle watcher commands: release/retry/discard #N, LET IT BE, withheld, watcher stats
+					if reply, handled := taskBot.HandleWatcherCommand(msgText); handled {
+						fmt.Printf("[Watcher] Handled withheld command: %s\n", msgText)
+						if whatsAppClient != nil {
+							whatsapp.SendWithStealth(context.Background(), whatsAppClient, v.Info.Chat, &waProto.Message{
+								Conversation: proto.String(reply),
+							})
 						}
 						return
 					}
@@ -450,7 +444,10 @@ func eventHandler(evt interface{}) {
 							}
 						}
 
-						wf := workflows.NewCommandWorkflow(llmClient, sendFunc, sendMasterFunc, getAllContactsJSON(whatsAppClient), taskBot.StartTaskCallback, batataKernel, searchContacts)
+						wf := workflows.NewCommandWorkflow(llmClient, sendFunc, sendMasterFunc, getAllContactsJSON(whatsAppClient), taskBot.StartTaskCallback, batataKernel, searchContacts, batataKernel.LanguageName)
+						wf.Bot.Messenger = messenger
+						wf.Bot.Polls = messenger
+						wf.Bot.SearchHistoryFunc = historyStore.Search
 						wf.Run(ctx, msgText, contextMsgs)
 					})
 				} else {
@@ -467,16 +464,18 @@ func eventHandler(evt interface{}) {
 
 				if msgText != "" && taskBot != nil {
 					// 1. Check for Active Behaviors
-					activeBehaviors, err := taskBot.BehaviorManager.GetActiveBehaviors(chatJID)
-					if err != nil {
-						fmt.Printf("Error checking behaviors: %v\n", err)
-					} else if len(activeBehaviors) == 0 && !v.Info.IsGroup && !v.Info.MessageSource.SenderAlt.IsEmpty() {
-						// Fallback for LID/JID ambiguity in 1:1 chats
-						altJID := v.Info.MessageSource.SenderAlt.String()
-						activeBehaviors, err = taskBot.BehaviorManager.GetActiveBehaviors(altJID)
+					// Triggers are evaluated here, before any debounce or LLM call
+					if len(activeBehaviors) > 0 {
+						activeBehaviors = taskBot.FilterBehaviors(activeBehaviors, behaviors.TriggerInput{
+							Text:      msgText,
+							MediaType: mediaTypeOf(v),
+							Sender:    v.Info.Sender.String(),
+							SenderAlt: v.Info.MessageSource.SenderAlt.String(),
+							Time:      v.Info.Timestamp,
+						})
 					}
 
-					if err == nil && len(activeBehaviors) > 0 {
+					if len(activeBehaviors) > 0 {
 						fmt.Printf("Active behaviors found for %s: %d\n", chatJID, len(activeBehaviors))
 
 						go func(cJID string, behaviors []behaviors.Behavior) {
@@ -533,7 +532,7 @@ func eventHandler(evt interface{}) {
 							}
 
 							// Process
-							_, err = taskBot.ProcessBehaviors(behaviors, latestText, contextMsgs, sendToContact)
+							_, err = taskBot.ProcessBehaviors(cJID, behaviors, latestText, contextMsgs, sendToContact)
 							if err != nil {
 								fmt.Printf("Behavior processing failed: %v\n", err)
 							}
@@ -706,7 +705,12 @@ func eventHandler(evt interface{}) {
 					fmt.Printf("DEBUG: Sample Conversation during HistorySync: %+v\n", conv)
 				} */
 				chatJID := conv.GetID()
-				for _, msg := range conv.GetMessages() {
+				// Oldest first, so edits and revocations find the message they change
+				messages := conv.GetMessages()
+				sort.SliceStable(messages, func(i, j int) bool {
+					return messages[i].GetMessage().GetMessageTimestamp() < messages[j].GetMessage().GetMessageTimestamp()
+				})
+				for _, msg := range messages {
 					// history sync messages are wrapped in WebMessageInfo, we need to extract the actual message content
 					// The structure is slightly different or dependent on how whatsmeow exposes it.
 					// Actually conv.GetMessages() returns []*waHistorySync.HistorySyncMsg
@@ -748,20 +752,18 @@ func eventHandler(evt interface{}) {
 						}
 					}
 
-					// Extract Text
+					// Render the message like the live handler does (votes stay encrypted, skipped)
 					waMsg := msg.GetMessage().GetMessage() // This is *waE2E.Message
-					text := ""
-					if waMsg != nil {
-						if waMsg.ExtendedTextMessage != nil {
-							text = *waMsg.ExtendedTextMessage.Text
-						} else if waMsg.Conversation != nil {
-							text = *waMsg.Conversation
+					text, meta := whatsapp.Normalize(waMsg)
+					if meta.Type == "edit" || meta.Type == "revoke" {
+						if recordChange(chatJID, senderJID, isFromMe, meta, ts) {
+							continue
 						}
 					}
 
 					if text != "" {
 						msgID := msg.GetMessage().GetKey().GetID()
-						err := historyStore.SaveMessage(msgID, chatJID, senderJID, text, ts, isFromMe)
+						err := historyStore.SaveMessageMeta(msgID, chatJID, senderJID, text, ts, isFromMe, meta)
 						if err != nil {
 							fmt.Printf("Failed to save history sync message: %v\n", err)
 						}
@@ -773,7 +775,33 @@ func eventHandler(evt interface{}) {
 	}
 }
 
+// recordChange applies an edit or revocation to the stored original, reporting whether
+// it was handled. Only changes to messages that aren't stored are kept as lines.
+func recordChange(chatJID, senderJID string, fromMe bool, meta history.Meta, at time.Time) bool {
+	result, err := messenger.RecordChange(chatJID, senderJID, fromMe, meta, at)
+	if err != nil {
+		fmt.Printf("Failed to record %s of #%s: %v\n", meta.Type, meta.QuotedID, err)
+		return false
+	}
+	switch result {
+	case history.ChangeRejected:
+		fmt.Printf("Ignoring %s of #%s by %s: not the author\n", meta.Type, meta.QuotedID, senderJID)
+	case history.ChangeDeleted:
+		fmt.Printf("Ignoring %s of #%s: already deleted\n", meta.Type, meta.QuotedID)
+	}
+	return result != history.ChangeMissing
+}
+
 func main() {
+	// "audit" subcommand: query the action audit log and exit
+	if len(os.Args) > 1 && os.Args[1] == "audit" {
+		if err := audit.RunCLI(os.Args[2:], filepath.Join("config", bot.AuditFile), os.Stdout); err != nil {
+			fmt.Fprintf(os.Stderr, "audit: %v\n", err)
+			os.Exit(1)
+		}
+		return
+	}
+
 	// |------------------------------------------------------------------------------------------------------|
 	// | NOTE: You must also import the appropriate DB connector, e.g. github.com/mattn/go-sqlite3 for SQLite |
 	// |------------------------------------------------------------------------------------------------------|
@@ -811,6 +839,8 @@ func main() {
 	if err != nil {
 		panic(err)
 	}
+	// Context lines name the senders from the contact store
+	historyStore.Directory = &whatsapp.Directory{Client: func() *whatsmeow.Client { return whatsAppClient }}
 
 	// Initialize task bot with StartTaskCallback
 	// This callback is triggered when a task is confirmed via confirm_task action
@@ -830,18 +860,69 @@ func main() {
 	}
 	taskBot = bot.NewBot(llmClient, "config", nil, sendMasterFromTask, getAllContactsJSON(client), batataKernel)
 	taskBot.SendMediaFunc = sendMedia
-
-	// Set up OnWatcherBlock callback to store withheld messages for LET IT BE override
-	taskBot.OnWatcherBlock = func(blockedMsg string, targetChatID string, sendFunc func(string)) {
-		lastWithheldMessage = &WithheldMessage{
-			Message:  blockedMsg,
-			SendFunc: sendFunc,
+	taskBot.Language = batataKernel.LanguageName
+	taskBot.Shadow = batataKernel.ShadowMode
+	messenger = &whatsapp.Messenger{
+		Client:  func() *whatsmeow.Client { return whatsAppClient },
+		History: historyStore,
+	}
+	taskBot.Messenger = messenger
+	taskBot.Polls = messenger
+	taskBot.SearchHistoryFunc = historyStore.Search
+
+	// Used to release or retry withheld actions after the originating turn is gone
+	taskBot.ChatSender = func(chatJID string) func(string) {
+		return func(msg string) {
+			if whatsAppClient == nil {
+				return
+			}
+			jid, err := types.ParseJID(chatJID)
+			if err != nil {
+				fmt.Printf("Failed to parse chat JID %s: %v\n", chatJID, err)
+				return
+			}
+			resp, err := whatsapp.SendWithStealth(context.Background(), whatsAppClient, jid, &waProto.Message{
+				Conversation: proto.String(msg),
+			})
+			if err != nil {
+				fmt.Printf("Failed to send message to %s: %v\n", chatJID, err)
+				return
+			}
+			historyStore.SaveMessage(resp.ID, chatJID, "Me", msg, time.Now(), true)
 		}
-		// Parse target JID to store for potential later use
-		if targetJID, err := types.ParseJID(targetChatID); err == nil {
-			lastWithheldMessage.TargetChatJID = targetJID
+	}
+
+	// Used by the response action to quote an earlier message of the chat
+	taskBot.ChatReplier = func(chatJID string) func(string, string) {
+		return func(msg, messageID string) {
+			if whatsAppClient == nil {
+				return
+			}
+			jid, err := types.ParseJID(chatJID)
+			if err != nil {
+				fmt.Printf("Failed to parse chat JID %s: %v\n", chatJID, err)
+				return
+			}
+			quoted, err := historyStore.GetMessage(chatJID, messageID)
+			if err != nil || quoted == nil {
+				// Unknown message: send it unquoted rather than dropping the reply
+				fmt.Printf("Quoted message %s not found in %s, sending without quote\n", messageID, chatJID)
+				taskBot.ChatSender(chatJID)(msg)
+				return
+			}
+			var sender types.JID
+			if quoted.IsFromMe && whatsAppClient.Store.ID != nil {
+				sender = *whatsAppClient.Store.ID
+			} else if sender, err = types.ParseJID(quoted.SenderJID); err != nil {
+				sender = jid
+			}
+			resp, err := whatsapp.SendWithStealth(context.Background(), whatsAppClient, jid, whatsapp.ReplyMessage(msg, quoted.MessageID, sender, quoted.Content))
+			if err != nil {
+				fmt.Printf("Failed to send reply to %s: %v\n", chatJID, err)
+				return
+			}
+			historyStore.SaveMessage(resp.ID, chatJID, "Me", msg, time.Now(), true)
 		}
-		fmt.Printf("[Watcher] Stored withheld message for LET IT BE override: %s\n", blockedMsg)
 	}
 
 	taskBot.StartTaskCallback = func(task *tasks.Task) {
@@ -1095,6 +1176,7 @@ func main() {
 
 	<-c
 
+	bot.CloseMCP()
 	client.Disconnect()
 }
 
@@ -1119,6 +1201,21 @@ func startScheduledTasksTicker() {
 				}
 			}
 		}
+
+		if taskBot != nil && taskBot.BehaviorManager != nil {
+			expired, err := taskBot.BehaviorManager.CheckExpiredBehaviors()
+			if err != nil {
+				fmt.Printf("Error checking expired behaviors: %v\n", err)
+				continue
+			}
+
+			for _, b := range expired {
+				// Report automatic disable to the master
+				if taskBot.SendMasterFunc != nil {
+					taskBot.SendMasterFunc(fmt.Sprintf("[Blady] : Behavior %d (%s) for %s reached its end date and was disabled.", b.ID, b.Name, strings.Join(b.AllTargets(), ", ")))
+				}
+			}
+		}
 	}
 }
 
@@ -0,0 +1,301 @@
+package audit
+
+import (
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	_ "github.com/mattn/go-sqlite3"
+)
+
+// Entry statuses
+const (
+	StatusExecuted = "executed" // Ran without error
+	StatusFailed   = "failed"   // Ran and returned an error
+	StatusDenied   = "denied"   // Not permitted in the mode, task or behavior
+	StatusBlocked  = "blocked"  // Blocked by the watcher (withheld or retried with feedback)
+	StatusShadowed = "shadowed" // Captured by shadow mode instead of performed
+)
+
+// Watcher verdicts
+const (
+	VerdictNotReviewed = "not_reviewed" // The watch policy didn't require a review
+	VerdictAllow       = "allow"        // Approved by the LLM watcher
+	VerdictBlock       = "block"        // Blocked by the LLM watcher
+	VerdictRulesAllow  = "rules_allow"  // Allowed by the deterministic rules without asking the LLM watcher
+	VerdictRulesBlock  = "rules_block"  // Blocked by a deterministic rule
+	VerdictFailOpen    = "fail_open"    // Watcher unavailable, run by the failure policy
+	VerdictRulesOnly   = "rules_only"   // Watcher unavailable, run checked by the deterministic rules only
+	VerdictFailClosed  = "fail_closed"  // Watcher unavailable, held by the failure policy
+	VerdictReleased    = "released"     // Withheld and released by the master
+)
+
+// Entry is an action execution
+type Entry struct {
+	ID          int64
+	Time        time.Time
+	TraceID     string // Correlates the entry with the LLM logs (logs/llm) of the run
+	Mode        string
+	TaskID      int
+	BehaviorIDs []int
+	ChatJID     string
+	Action      string
+	Payload     string
+	Status      string
+	Error       string
+	Verdict     string
+	ToolOutputs []string
+}
+
+// Query filters entries. Zero fields match everything.
+type Query struct {
+	ChatJID    string // A JID, or a number matching any JID of that user
+	TaskID     int
+	BehaviorID int
+	Action     string
+	Mode       string
+	Status     string
+	TraceID    string
+	Since      time.Time
+	Until      time.Time
+	Limit      int // Defaults to 50, newest first
+}
+
+// Log stores action executions in SQLite
+type Log struct {
+	db *sql.DB
+}
+
+// Open opens (or creates) the audit database
+func Open(dbPath string) (*Log, error) {
+	db, err := sql.Open("sqlite3", dbPath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open audit db: %w", err)
+	}
+
+	query := `
+	CREATE TABLE IF NOT EXISTS actions (
+		id INTEGER PRIMARY KEY AUTOINCREMENT,
+		created_at INTEGER,
+		trace_id TEXT,
+		mode TEXT,
+		task_id INTEGER,
+		behavior_ids TEXT,
+		chat_jid TEXT,
+		action TEXT,
+		payload TEXT,
+		status TEXT,
+		error TEXT,
+		verdict TEXT,
+		tool_outputs TEXT
+	);
+	CREATE INDEX IF NOT EXISTS idx_actions_chat ON actions(chat_jid, created_at);
+	CREATE INDEX IF NOT EXISTS idx_actions_task ON actions(task_id, created_at);
+	CREATE INDEX IF NOT EXISTS idx_actions_trace ON actions(trace_id);
+	`
+	if _, err := db.Exec(query); err != nil {
+		db.Close()
+		return nil, fmt.Errorf("failed to create audit tables: %w", err)
+	}
+	return &Log{db: db}, nil
+}
+
+// Close closes the database
+func (l *Log) Close() error {
+	return l.db.Close()
+}
+
+// Record stores an entry. The time defaults to now.
+func (l *Log) Record(e Entry) error {
+	if e.Time.IsZero() {
+		e.Time = time.Now()
+	}
+	outputs, err := json.Marshal(e.ToolOutputs)
+	if err != nil {
+		return fmt.Errorf("failed to encode tool outputs: %w", err)
+	}
+	query := `INSERT INTO actions (created_at, trace_id, mode, task_id, behavior_ids, chat_jid, action, payload, status, error, verdict, tool_outputs)
+	VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`
+	_, err = l.db.Exec(query, e.Time.Unix(), e.TraceID, e.Mode, e.TaskID, encodeIDs(e.BehaviorIDs), e.ChatJID, e.Action, e.Payload, e.Status, e.Error, e.Verdict, string(outputs))
+	if err != nil {
+		return fmt.Errorf("failed to record action: %w", err)
+	}
+	return nil
+}
+
+// Search returns the entries matching the query, newest first
+func (l *Log) Search(q Query) ([]Entry, error) {
+	var where []string
+	var args []interface{}
+	if q.ChatJID != "" {
+		if strings.Contains(q.ChatJID, "@") {
+			where = append(where, "chat_jid = ?")
+			args = append(args, q.ChatJID)
+		} else {
+			where = append(where, "(chat_jid LIKE ? OR chat_jid LIKE ?)")
+			args = append(args, q.ChatJID+"@%", q.ChatJID+":%")
+		}
+	}
+	if q.TaskID != 0 {
+		where = append(where, "task_id = ?")
+		args = append(args, q.TaskID)
+	}
+	if q.BehaviorID != 0 {
+		where = append(where, "behavior_ids LIKE ?")
+		args = append(args, "%,"+strconv.Itoa(q.BehaviorID)+",%")
+	}
+	for column, value := range map[string]string{"action": q.Action, "mode": q.Mode, "status": q.Status, "trace_id": q.TraceID} {
+		if value != "" {
+			where = append(where, column+" = ?")
+			args = append(args, value)
+		}
+	}
+	if !q.Since.IsZero() {
+		where = append(where, "created_at >= ?")
+		args = append(args, q.Since.Unix())
+	}
+	if !q.Until.IsZero() {
+		where = append(where, "created_at < ?")
+		args = append(args, q.Until.Unix())
+	}
+	limit := q.Limit
+	if limit <= 0 {
+		limit = 50
+	}
+
+	query := `SELECT id, created_at, trace_id, mode, task_id, behavior_ids, chat_jid, action, payload, status, error, verdict, tool_outputs FROM actions`
+	if len(where) > 0 {
+		query += " WHERE " + strings.Join(where, " AND ")
+	}
+	query += " ORDER BY created_at DESC, id DESC LIMIT ?"
+	args = append(args, limit)
+
+	rows, err := l.db.Query(query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query audit log: %w", err)
+	}
+	defer rows.Close()
+
+	var entries []Entry
+	for rows.Next() {
+		var e Entry
+		var created int64
+		var behaviorIDs, outputs string
+		if err := rows.Scan(&e.ID, &created, &e.TraceID, &e.Mode, &e.TaskID, &behaviorIDs, &e.ChatJID, &e.Action, &e.Payload, &e.Status, &e.Error, &e.Verdict, &outputs); err != nil {
+			return nil, err
+		}
+		e.Time = time.Unix(created, 0)
+		e.BehaviorIDs = decodeIDs(behaviorIDs)
+		json.Unmarshal([]byte(outputs), &e.ToolOutputs)
+		entries = append(entries, e)
+	}
+	return entries, rows.Err()
+}
+
+// encodeIDs stores behavior IDs as ",1,2," so a single ID can be matched with LIKE
+func encodeIDs(ids []int) string {
+	if len(ids) == 0 {
+		return ""
+	}
+	parts := make([]string, len(ids))
+	for i, id := range ids {
+		parts[i] = strconv.Itoa(id)
+	}
+	return "," + strings.Join(parts, ",") + ","
+}
+
+func decodeIDs(s string) []int {
+	var ids []int
+	for _, part := range strings.Split(strings.Trim(s, ","), ",") {
+		if id, err := strconv.Atoi(part); err == nil {
+			ids = append(ids, id)
+		}
+	}
+	return ids
+}
+
+// Source describes where the entry came from
+func (e *Entry) Source() string {
+	switch {
+	case e.TaskID != 0:
+		return fmt.Sprintf("task %d", e.TaskID)
+	case len(e.BehaviorIDs) > 0:
+		ids := make([]string, len(e.BehaviorIDs))
+		for i, id := range e.BehaviorIDs {
+			ids[i] = "#" + strconv.Itoa(id)
+		}
+		return "behavior " + strings.Join(ids, ", ")
+	}
+	return e.Mode
+}
+
+// Format renders the entry on one line (plus its tool outputs), cutting long payloads
+func (e *Entry) Format() string {
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "%s [%s] %s", e.Time.Format("2006-01-02 15:04:05"), e.Source(), e.Action)
+	if e.ChatJID != "" {
+		fmt.Fprintf(&sb, " in %s", e.ChatJID)
+	}
+	fmt.Fprintf(&sb, ": %s (watcher: %s)", e.Status, e.Verdict)
+	if e.Error != "" {
+		fmt.Fprintf(&sb, " error: %s", e.Error)
+	}
+	if e.Payload != "" {
+		fmt.Fprintf(&sb, "\n  payload: %s", cut(e.Payload, 300))
+	}
+	for _, out := range e.ToolOutputs {
+		fmt.Fprintf(&sb, "\n  output: %s", cut(out, 300))
+	}
+	if e.TraceID != "" {
+		fmt.Fprintf(&sb, "\n  trace: %s", e.TraceID)
+	}
+	return sb.String()
+}
+
+func cut(s string, max int) string {
+	s = strings.ReplaceAll(s, "\n", " ")
+	if len(s) <= max {
+		return s
+	}
+	return s[:max] + "..."
+}
+
+// ParseDay resolves "today", "yesterday" or a YYYY-MM-DD date to the start and end of that day
+func ParseDay(day string, now time.Time) (time.Time, time.Time, error) {
+	start := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, now.Location())
+	switch strings.ToLower(strings.TrimSpace(day)) {
+	case "today":
+	case "yesterday":
+		start = start.AddDate(0, 0, -1)
+	default:
+		t, err := time.ParseInLocation("2006-01-02", strings.TrimSpace(day), now.Location())
+		if err != nil {
+			return time.Time{}, time.Time{}, fmt.Errorf("invalid day '%s' (expected today, yesterday or YYYY-MM-DD)", day)
+		}
+		start = t
+	}
+	return start, start.AddDate(0, 0, 1), nil
+}
+
+// ParseTime resolves a point in time: a duration before now ("24h", "3d"),
+// a YYYY-MM-DD date, YYYY-MM-DDTHH:MM or RFC 3339
+func ParseTime(s string, now time.Time) (time.Time, error) {
+	s = strings.TrimSpace(s)
+	if strings.HasSuffix(s, "d") {
+		if days, err := strconv.Atoi(strings.TrimSuffix(s, "d")); err == nil {
+			return now.AddDate(0, 0, -days), nil
+		}
+	}
+	if d, err := time.ParseDuration(s); err == nil {
+		return now.Add(-d), nil
+	}
+	for _, layout := range []string{"2006-01-02", "2006-01-02T15:04", time.RFC3339} {
+		if t, err := time.ParseInLocation(layout, s, now.Location()); err == nil {
+			return t, nil
+		}
+	}
+	return time.Time{}, fmt.Errorf("invalid time '%s' (expected a duration like 24h or 3d, or a date)", s)
+}
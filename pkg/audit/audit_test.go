@@ -0,0 +1,57 @@
+package audit
+
+import (
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestRecordAndSearch(t *testing.T) {
+	log, err := Open(filepath.Join(t.TempDir(), "audit.db"))
+	if err != nil {
+		t.Fatalf("Open failed: %v", err)
+	}
+	defer log.Close()
+
+	now := time.Date(2026, 10, 18, 12, 0, 0, 0, time.Local)
+	yesterday := now.AddDate(0, 0, -1)
+	entries := []Entry{
+		{Time: yesterday, Mode: "task", TaskID: 3, ChatJID: "5491100000000@s.whatsapp.net", Action: "response", Payload: `"hola"`, Status: StatusExecuted, Verdict: VerdictAllow},
+		{Time: yesterday, Mode: "behavior", BehaviorIDs: []int{2, 12}, ChatJID: "5491100000000@s.whatsapp.net", Action: "send_media", Status: StatusBlocked, Verdict: VerdictRulesBlock},
+		{Time: now, Mode: "behavior", BehaviorIDs: []int{1}, ChatJID: "5491199999999@s.whatsapp.net", Action: "weather", Status: StatusExecuted, ToolOutputs: []string{"sunny"}},
+	}
+	for _, e := range entries {
+		if err := log.Record(e); err != nil {
+			t.Fatalf("Record failed: %v", err)
+		}
+	}
+
+	start, end, err := ParseDay("yesterday", now)
+	if err != nil {
+		t.Fatalf("ParseDay failed: %v", err)
+	}
+	got, err := log.Search(Query{ChatJID: "5491100000000", Since: start, Until: end})
+	if err != nil {
+		t.Fatalf("Search failed: %v", err)
+	}
+	if len(got) != 2 {
+		t.Fatalf("expected 2 entries for the chat yesterday, got %d", len(got))
+	}
+
+	got, _ = log.Search(Query{BehaviorID: 2})
+	if len(got) != 1 || got[0].Action != "send_media" || len(got[0].BehaviorIDs) != 2 {
+		t.Errorf("unexpected behavior search result: %+v", got)
+	}
+	got, _ = log.Search(Query{Status: StatusExecuted, Mode: "behavior"})
+	if len(got) != 1 || len(got[0].ToolOutputs) != 1 || got[0].ToolOutputs[0] != "sunny" {
+		t.Errorf("unexpected status search result: %+v", got)
+	}
+
+	since, err := ParseTime("3d", now)
+	if err != nil || !since.Equal(now.AddDate(0, 0, -3)) {
+		t.Errorf("ParseTime(3d) = %v, %v", since, err)
+	}
+	if _, err := ParseTime("last week", now); err == nil {
+		t.Error("expected an error for an invalid time")
+	}
+}
@@ -0,0 +1,86 @@
+package audit
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"time"
+)
+
+// RunCLI implements the "audit" subcommand: it queries the log at dbPath and
+// prints the matching entries, oldest first
+func RunCLI(args []string, dbPath string, out io.Writer) error {
+	fs := flag.NewFlagSet("audit", flag.ContinueOnError)
+	fs.SetOutput(out)
+	var q Query
+	var day, since, until string
+	var asJSON bool
+	fs.StringVar(&q.ChatJID, "chat", "", "chat JID or number")
+	fs.IntVar(&q.TaskID, "task", 0, "task ID")
+	fs.IntVar(&q.BehaviorID, "behavior", 0, "behavior ID")
+	fs.StringVar(&q.Action, "action", "", "action type")
+	fs.StringVar(&q.Mode, "mode", "", "command, task or behavior")
+	fs.StringVar(&q.Status, "status", "", "executed, failed, denied, blocked or shadowed")
+	fs.StringVar(&q.TraceID, "trace", "", "LLM trace ID")
+	fs.StringVar(&day, "day", "", "today, yesterday or YYYY-MM-DD")
+	fs.StringVar(&since, "since", "", "start: duration ago (24h, 3d) or date")
+	fs.StringVar(&until, "until", "", "end: duration ago (24h, 3d) or date")
+	fs.IntVar(&q.Limit, "limit", 50, "maximum entries")
+	fs.BoolVar(&asJSON, "json", false, "print JSON")
+	if err := fs.Parse(args); err != nil {
+		if err == flag.ErrHelp {
+			return nil
+		}
+		return err
+	}
+	if err := q.SetRange(day, since, until, time.Now()); err != nil {
+		return err
+	}
+
+	log, err := Open(dbPath)
+	if err != nil {
+		return err
+	}
+	defer log.Close()
+	entries, err := log.Search(q)
+	if err != nil {
+		return err
+	}
+
+	if asJSON {
+		enc := json.NewEncoder(out)
+		enc.SetIndent("", "  ")
+		return enc.Encode(entries)
+	}
+	if len(entries) == 0 {
+		fmt.Fprintln(out, "No actions found.")
+		return nil
+	}
+	for i := len(entries) - 1; i >= 0; i-- {
+		fmt.Fprintln(out, entries[i].Format())
+	}
+	return nil
+}
+
+// SetRange fills Since and Until from a day or since/until expressions
+func (q *Query) SetRange(day, since, until string, now time.Time) error {
+	var err error
+	if day != "" {
+		q.Since, q.Until, err = ParseDay(day, now)
+		if err != nil {
+			return err
+		}
+	}
+	if since != "" {
+		if q.Since, err = ParseTime(since, now); err != nil {
+			return err
+		}
+	}
+	if until != "" {
+		if q.Until, err = ParseTime(until, now); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -47,6 +47,7 @@ type Config struct {
 	TranscriptionServer    string   `json:"transcription_server"`
 	TranscriptionServerKey string   `json:"transcription_server_key"`
 	TranscriptionModel     string   `json:"transcription_model"`
+	Shadow                 bool     `json:"shadow,omitempty"` // Run every task and behavior in shadow mode
 }
 
 type Kernel struct {
@@ -307,6 +308,16 @@ func (k *Kernel) askTranscriptionConfig(sendFunc func(string)) {
 	sendFunc(msg(k.s(func(s Strings) string { return s.TranscriptionSetupPrompt })))
 }
 
+// LanguageName returns the configured language name for the bot prompts
+func (k *Kernel) LanguageName() string {
+	return k.Config.Language.Name()
+}
+
+// ShadowMode reports whether tasks and behaviors run in shadow mode globally
+func (k *Kernel) ShadowMode() bool {
+	return k.Config.Shadow
+}
+
 func (k *Kernel) s(selector func(Strings) string) string {
 	return GetString(k.Config.Language, selector)
 }
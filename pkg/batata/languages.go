@@ -1,6 +1,9 @@
 package batata
 
-import "fmt"
+import (
+	"fmt"
+	"strings"
+)
 
 type Language int
 
@@ -605,6 +608,19 @@ func GetString(lang Language, selector func(Strings) string) string {
 	return selector(strs)
 }
 
+// Name returns the English name of the language, as used in prompts, or "" when
+// none was chosen (the bot then uses language.Default)
+func (l Language) Name() string {
+	if l < 1 || int(l) > len(SupportedLanguages) {
+		return ""
+	}
+	name := SupportedLanguages[l-1]
+	if i := strings.Index(name, "("); i >= 0 {
+		return strings.TrimSuffix(name[i+1:], ")")
+	}
+	return name
+}
+
 func LangSelectMenu() string {
 	menu := ""
 	for i, l := range SupportedLanguages {
@@ -0,0 +1,145 @@
+package behaviors
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// ActivityEntry records one behavior run and the actions it executed
+type ActivityEntry struct {
+	RunID     string           `json:"run_id"`
+	Timestamp int64            `json:"timestamp"`
+	Contact   string           `json:"contact"`
+	Trigger   string           `json:"trigger"`             // Message that triggered the run
+	CoActive  []int            `json:"co_active,omitempty"` // Other behaviors evaluated in the same run
+	Actions   []ActivityAction `json:"actions"`
+	Error     string           `json:"error,omitempty"`  // Run level error (LLM, parsing...)
+	Shadow    bool             `json:"shadow,omitempty"` // Dry run: captured actions didn't run
+}
+
+// ActivityAction is an action executed during a behavior run
+type ActivityAction struct {
+	Type     string `json:"type"`
+	Content  string `json:"content"`
+	Error    string `json:"error,omitempty"`
+	Shadowed bool   `json:"shadowed,omitempty"` // Captured by shadow mode instead of running
+}
+
+// activityPath returns the activity log path, stored next to the behavior file
+func (bm *BehaviorManager) activityPath(id int) string {
+	return filepath.Join(bm.BehaviorsDir, fmt.Sprintf("%d.activity.jsonl", id))
+}
+
+// LogActivity appends an entry to the behavior activity log
+func (bm *BehaviorManager) LogActivity(id int, entry ActivityEntry) error {
+	if entry.Timestamp == 0 {
+		entry.Timestamp = time.Now().Unix()
+	}
+	data, err := json.Marshal(entry)
+	if err != nil {
+		return fmt.Errorf("failed to marshal activity entry: %w", err)
+	}
+
+	if err := os.MkdirAll(bm.BehaviorsDir, 0755); err != nil {
+		return fmt.Errorf("failed to create behaviors directory: %w", err)
+	}
+	f, err := os.OpenFile(bm.activityPath(id), os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
+	if err != nil {
+		return fmt.Errorf("failed to open activity log of behavior %d: %w", id, err)
+	}
+	defer f.Close()
+
+	if _, err := f.Write(append(data, '\n')); err != nil {
+		return fmt.Errorf("failed to write activity log of behavior %d: %w", id, err)
+	}
+	return nil
+}
+
+// GetActivity returns the last `limit` activity entries of a behavior (oldest first).
+// Archived behaviors are also looked up.
+func (bm *BehaviorManager) GetActivity(id int, limit int) ([]ActivityEntry, error) {
+	path := bm.activityPath(id)
+	if _, err := os.Stat(path); os.IsNotExist(err) {
+		path = filepath.Join(bm.ArchivedDir, filepath.Base(path))
+	}
+
+	f, err := os.Open(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return []ActivityEntry{}, nil
+		}
+		return nil, fmt.Errorf("failed to open activity log of behavior %d: %w", id, err)
+	}
+	defer f.Close()
+
+	var entries []ActivityEntry
+	scanner := bufio.NewScanner(f)
+	scanner.Buffer(make([]byte, 0, 64*1024), 4*1024*1024)
+	for scanner.Scan() {
+		var entry ActivityEntry
+		if err := json.Unmarshal(scanner.Bytes(), &entry); err != nil {
+			continue
+		}
+		entries = append(entries, entry)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read activity log of behavior %d: %w", id, err)
+	}
+
+	if limit > 0 && len(entries) > limit {
+		entries = entries[len(entries)-limit:]
+	}
+	return entries, nil
+}
+
+// FormatActivity renders activity entries as readable lines for the master
+func FormatActivity(entries []ActivityEntry) string {
+	if len(entries) == 0 {
+		return "No activity recorded."
+	}
+
+	var sb strings.Builder
+	for _, e := range entries {
+		run := fmt.Sprintf("[%s] run %s in %s", time.Unix(e.Timestamp, 0).Format("2006-01-02 15:04:05"), e.RunID, e.Contact)
+		if e.Shadow {
+			run += " (shadow)"
+		}
+		sb.WriteString(run + "\n")
+		if e.Trigger != "" {
+			sb.WriteString(fmt.Sprintf("  trigger: %s\n", truncate(e.Trigger, 200)))
+		}
+		if len(e.CoActive) > 0 {
+			sb.WriteString(fmt.Sprintf("  together with behaviors: %v\n", e.CoActive))
+		}
+		if len(e.Actions) == 0 && e.Error == "" {
+			sb.WriteString("  no actions\n")
+		}
+		for _, a := range e.Actions {
+			line := fmt.Sprintf("  - %s: %s", a.Type, truncate(a.Content, 200))
+			if a.Shadowed {
+				line += " (shadowed, not run)"
+			}
+			if a.Error != "" {
+				line += fmt.Sprintf(" (error: %s)", a.Error)
+			}
+			sb.WriteString(line + "\n")
+		}
+		if e.Error != "" {
+			sb.WriteString(fmt.Sprintf("  error: %s\n", e.Error))
+		}
+	}
+	return strings.TrimRight(sb.String(), "\n")
+}
+
+func truncate(s string, max int) string {
+	r := []rune(s)
+	if len(r) <= max {
+		return s
+	}
+	return string(r[:max]) + "…"
+}
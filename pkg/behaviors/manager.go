@@ -5,6 +5,7 @@ import (
 	"fmt"
 	"os"
 	"path/filepath"
+	"sort"
 	"strconv"
 	"strings"
 	"time"
@@ -18,23 +19,67 @@ const (
 
 // Behavior represents a behavior instance stored as a JSON file
 type Behavior struct {
-	ID        int    `json:"id"`
-	Contact   string `json:"contact"`
-	Name      string `json:"name"` // Matches filename in config/modes/behavior/ (without extension)
-	Comments  string `json:"comments"`
-	Status    string `json:"status"`
-	Timestamp int64  `json:"timestamp"` // Unix timestamp of creation
+	ID         int       `json:"id"`
+	Contact    string    `json:"contact"`           // Main target: contact/group JID or any target expression
+	Targets    []string  `json:"targets,omitempty"` // Extra targets: JIDs, set:<name>, tag:<name>, all_groups, all_chats
+	Exclude    []string  `json:"exclude,omitempty"` // Chats never targeted (same syntax as targets)
+	Name       string    `json:"name"`              // Matches filename in config/modes/behavior/ (without extension)
+	Comments   string    `json:"comments"`
+	Status     string    `json:"status"`
+	Timestamp  int64     `json:"timestamp"`             // Unix timestamp of creation
+	UpdatedAt  int64     `json:"updated_at,omitempty"`  // Unix timestamp of last update
+	DisabledAt int64     `json:"disabled_at,omitempty"` // Unix timestamp of last disable
+	EnabledAt  int64     `json:"enabled_at,omitempty"`  // Unix timestamp of the last re-enable
+	Schedule   *Schedule `json:"schedule,omitempty"`    // Optional active hours and date range
+	Actions    []string  `json:"actions,omitempty"`     // Allowed actions, narrowing the behavior mode permissions
+	Shadow     bool      `json:"shadow,omitempty"`      // Capture outbound side effects instead of performing them
+}
+
+// BehaviorUpdate holds the fields to change in UpdateBehavior. Nil fields are left untouched.
+type BehaviorUpdate struct {
+	Contact       *string
+	Targets       *[]string
+	Exclude       *[]string
+	Comments      *string
+	Status        *string
+	Schedule      *Schedule
+	ClearSchedule bool
+	Actions       *[]string
+	Shadow        *bool
+}
+
+// IsActiveAt reports whether the behavior is enabled and its schedule allows it to run at t.
+// The end date no longer applies once the master re-enabled the behavior after it.
+func (b *Behavior) IsActiveAt(t time.Time) bool {
+	if b.Status != StatusEnabled {
+		return false
+	}
+	if b.reenabledAfterEnd() {
+		schedule := *b.Schedule
+		schedule.EndDate = ""
+		return schedule.ActiveAt(t)
+	}
+	return b.Schedule.ActiveAt(t)
+}
+
+// reenabledAfterEnd reports whether the behavior was re-enabled at or after its schedule end date
+func (b *Behavior) reenabledAfterEnd() bool {
+	end, ok := b.Schedule.End()
+	return ok && b.EnabledAt != 0 && !time.Unix(b.EnabledAt, 0).Before(end)
 }
 
 // BehaviorManager handles all behavior file operations
 type BehaviorManager struct {
-	BehaviorsDir string
+	BehaviorsDir    string
+	ArchivedDir     string
+	ContactSetsPath string // Named contact sets and tags used by targets
 }
 
 // NewBehaviorManager creates a new BehaviorManager for the given behaviors directory
 func NewBehaviorManager(behaviorsDir string) *BehaviorManager {
 	return &BehaviorManager{
 		BehaviorsDir: behaviorsDir,
+		ArchivedDir:  filepath.Join(behaviorsDir, "archived"),
 	}
 }
 
@@ -76,14 +121,22 @@ func (bm *BehaviorManager) behaviorPath(id int) string {
 	return filepath.Join(bm.BehaviorsDir, fmt.Sprintf("%d.json", id))
 }
 
-// EnableBehavior creates a new enabled behavior
-func (bm *BehaviorManager) EnableBehavior(contact, name, comments string) (*Behavior, error) {
+// EnableBehavior creates a new enabled behavior from the given definition
+// (Contact/Targets/Exclude, Name, Comments, Schedule, Actions and Shadow are used)
+func (bm *BehaviorManager) EnableBehavior(def Behavior) (*Behavior, error) {
 	// Validate that the behavior template exists in config/modes/behavior/
 	// We need to know where config/modes/behavior is.
 	// Actually, the Manager only knows about storage. The validation might belong higher up?
 	// But it's good to prevent enabling non-existent behaviors.
 	// For now, we'll assume the caller (Action) validates or we trust the user.
 
+	if err := def.Schedule.Validate(); err != nil {
+		return nil, fmt.Errorf("invalid schedule: %w", err)
+	}
+	if err := bm.validateTargets(&def); err != nil {
+		return nil, err
+	}
+
 	nextID, err := bm.getNextID()
 	if err != nil {
 		return nil, fmt.Errorf("failed to get next ID: %w", err)
@@ -91,11 +144,16 @@ func (bm *BehaviorManager) EnableBehavior(contact, name, comments string) (*Beha
 
 	behavior := &Behavior{
 		ID:        nextID,
-		Contact:   contact,
-		Name:      name,
-		Comments:  comments,
+		Contact:   def.Contact,
+		Targets:   def.Targets,
+		Exclude:   def.Exclude,
+		Name:      def.Name,
+		Comments:  def.Comments,
 		Status:    StatusEnabled,
 		Timestamp: time.Now().Unix(),
+		Schedule:  def.Schedule,
+		Actions:   def.Actions,
+		Shadow:    def.Shadow,
 	}
 
 	data, err := json.MarshalIndent(behavior, "", "  ")
@@ -111,60 +169,173 @@ func (bm *BehaviorManager) EnableBehavior(contact, name, comments string) (*Beha
 	return behavior, nil
 }
 
-// DisableBehavior removes the behavior file
+// LoadBehavior loads a single (non archived) behavior by ID
+func (bm *BehaviorManager) LoadBehavior(id int) (*Behavior, error) {
+	data, err := os.ReadFile(bm.behaviorPath(id))
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, fmt.Errorf("behavior %d not found", id)
+		}
+		return nil, fmt.Errorf("failed to read behavior %d: %w", id, err)
+	}
+
+	var b Behavior
+	if err := json.Unmarshal(data, &b); err != nil {
+		return nil, fmt.Errorf("failed to parse behavior %d: %w", id, err)
+	}
+	return &b, nil
+}
+
+// SaveBehavior writes a behavior to its file
+func (bm *BehaviorManager) SaveBehavior(b *Behavior) error {
+	data, err := json.MarshalIndent(b, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to marshal behavior %d: %w", b.ID, err)
+	}
+	if err := os.WriteFile(bm.behaviorPath(b.ID), data, 0644); err != nil {
+		return fmt.Errorf("failed to write behavior %d: %w", b.ID, err)
+	}
+	return nil
+}
+
+// DisableBehavior marks a behavior as disabled, keeping its file (and activity log)
 func (bm *BehaviorManager) DisableBehavior(id int) error {
-	path := bm.behaviorPath(id)
-	if _, err := os.Stat(path); os.IsNotExist(err) {
-		return fmt.Errorf("behavior %d not found", id)
+	b, err := bm.LoadBehavior(id)
+	if err != nil {
+		return err
 	}
 
-	if err := os.Remove(path); err != nil {
-		return fmt.Errorf("failed to remove behavior file: %w", err)
+	b.Status = StatusDisabled
+	b.DisabledAt = time.Now().Unix()
+	if err := bm.SaveBehavior(b); err != nil {
+		return err
 	}
 
-	fmt.Printf("[BehaviorManager] Disabled (removed) behavior %d\n", id)
+	fmt.Printf("[BehaviorManager] Disabled behavior %d\n", id)
 	return nil
 }
 
-// GetActiveBehaviors returns all enabled behaviors for a specific contact
-func (bm *BehaviorManager) GetActiveBehaviors(contact string) ([]Behavior, error) {
-	entries, err := os.ReadDir(bm.BehaviorsDir)
+// UpdateBehavior applies the given changes to a behavior and returns the updated version
+func (bm *BehaviorManager) UpdateBehavior(id int, update BehaviorUpdate) (*Behavior, error) {
+	b, err := bm.LoadBehavior(id)
 	if err != nil {
-		if os.IsNotExist(err) {
-			return []Behavior{}, nil
+		return nil, err
+	}
+
+	if update.Contact != nil {
+		b.Contact = *update.Contact
+	}
+	if update.Targets != nil {
+		b.Targets = *update.Targets
+	}
+	if update.Exclude != nil {
+		b.Exclude = *update.Exclude
+	}
+	if update.Contact != nil || update.Targets != nil || update.Exclude != nil {
+		if err := bm.validateTargets(b); err != nil {
+			return nil, err
 		}
-		return nil, fmt.Errorf("failed to read behaviors directory: %w", err)
+	}
+	if update.Comments != nil {
+		b.Comments = *update.Comments
+	}
+	if update.Status != nil {
+		switch *update.Status {
+		case StatusEnabled:
+			if b.Status != StatusEnabled {
+				b.EnabledAt = time.Now().Unix()
+			}
+			b.Status = StatusEnabled
+		case StatusDisabled:
+			if b.Status != StatusDisabled {
+				b.DisabledAt = time.Now().Unix()
+			}
+			b.Status = StatusDisabled
+		default:
+			return nil, fmt.Errorf("invalid status '%s' (expected %s or %s)", *update.Status, StatusEnabled, StatusDisabled)
+		}
+	}
+	if update.ClearSchedule {
+		b.Schedule = nil
+	} else if update.Schedule != nil {
+		if err := update.Schedule.Validate(); err != nil {
+			return nil, fmt.Errorf("invalid schedule: %w", err)
+		}
+		b.Schedule = update.Schedule
+	}
+	if update.Actions != nil {
+		b.Actions = *update.Actions
+	}
+	if update.Shadow != nil {
+		b.Shadow = *update.Shadow
 	}
 
-	var matchBehaviors []Behavior
+	b.UpdatedAt = time.Now().Unix()
+	if err := bm.SaveBehavior(b); err != nil {
+		return nil, err
+	}
 
-	for _, entry := range entries {
-		if entry.IsDir() || !strings.HasSuffix(entry.Name(), ".json") || entry.Name() == "_last_id" {
-			continue
-		}
+	fmt.Printf("[BehaviorManager] Updated behavior %d\n", id)
+	return b, nil
+}
 
-		path := filepath.Join(bm.BehaviorsDir, entry.Name())
-		data, err := os.ReadFile(path)
-		if err != nil {
-			continue
+// ArchiveBehavior disables a behavior and moves it (with its activity log) to the archived directory
+func (bm *BehaviorManager) ArchiveBehavior(id int) error {
+	b, err := bm.LoadBehavior(id)
+	if err != nil {
+		return err
+	}
+
+	if b.Status != StatusDisabled {
+		b.Status = StatusDisabled
+		b.DisabledAt = time.Now().Unix()
+		if err := bm.SaveBehavior(b); err != nil {
+			return err
 		}
+	}
 
-		var b Behavior
-		if err := json.Unmarshal(data, &b); err != nil {
-			continue
+	if err := os.MkdirAll(bm.ArchivedDir, 0755); err != nil {
+		return fmt.Errorf("failed to create archived directory: %w", err)
+	}
+
+	if err := os.Rename(bm.behaviorPath(id), filepath.Join(bm.ArchivedDir, fmt.Sprintf("%d.json", id))); err != nil {
+		return fmt.Errorf("failed to move behavior %d to archived: %w", id, err)
+	}
+	logPath := bm.activityPath(id)
+	if _, err := os.Stat(logPath); err == nil {
+		if err := os.Rename(logPath, filepath.Join(bm.ArchivedDir, filepath.Base(logPath))); err != nil {
+			fmt.Printf("Warning: failed to archive activity log of behavior %d: %v\n", id, err)
 		}
+	}
 
-		if b.Status == StatusEnabled && b.Contact == contact {
-			matchBehaviors = append(matchBehaviors, b)
+	fmt.Printf("[BehaviorManager] Archived behavior %d (moved to archived/)\n", id)
+	return nil
+}
+
+// ListBehaviors returns all behaviors sorted by ID, optionally including archived ones
+func (bm *BehaviorManager) ListBehaviors(includeArchived bool) ([]Behavior, error) {
+	all, err := bm.loadBehaviors()
+	if err != nil {
+		return nil, err
+	}
+	if includeArchived {
+		archived, err := loadBehaviorsFrom(bm.ArchivedDir)
+		if err != nil {
+			return nil, err
 		}
+		all = append(all, archived...)
 	}
+	sort.Slice(all, func(i, j int) bool { return all[i].ID < all[j].ID })
+	return all, nil
+}
 
-	return matchBehaviors, nil
+// loadBehaviors reads every behavior file in the behaviors directory
+func (bm *BehaviorManager) loadBehaviors() ([]Behavior, error) {
+	return loadBehaviorsFrom(bm.BehaviorsDir)
 }
 
-// GetAllActiveBehaviors returns all enabled behaviors across all contacts
-func (bm *BehaviorManager) GetAllActiveBehaviors() ([]Behavior, error) {
-	entries, err := os.ReadDir(bm.BehaviorsDir)
+func loadBehaviorsFrom(dir string) ([]Behavior, error) {
+	entries, err := os.ReadDir(dir)
 	if err != nil {
 		if os.IsNotExist(err) {
 			return []Behavior{}, nil
@@ -172,14 +343,13 @@ func (bm *BehaviorManager) GetAllActiveBehaviors() ([]Behavior, error) {
 		return nil, fmt.Errorf("failed to read behaviors directory: %w", err)
 	}
 
-	var matchBehaviors []Behavior
-
+	var all []Behavior
 	for _, entry := range entries {
 		if entry.IsDir() || !strings.HasSuffix(entry.Name(), ".json") || entry.Name() == "_last_id" {
 			continue
 		}
 
-		path := filepath.Join(bm.BehaviorsDir, entry.Name())
+		path := filepath.Join(dir, entry.Name())
 		data, err := os.ReadFile(path)
 		if err != nil {
 			continue
@@ -189,7 +359,74 @@ func (bm *BehaviorManager) GetAllActiveBehaviors() ([]Behavior, error) {
 		if err := json.Unmarshal(data, &b); err != nil {
 			continue
 		}
+		all = append(all, b)
+	}
+	return all, nil
+}
+
+// validateTargets checks that the behavior has at least one target and that named sets exist
+func (bm *BehaviorManager) validateTargets(b *Behavior) error {
+	targets := b.AllTargets()
+	if len(targets) == 0 {
+		return fmt.Errorf("behavior needs a contact or at least one target")
+	}
+	sets, err := LoadContactSets(bm.ContactSetsPath)
+	if err != nil {
+		return err
+	}
+	for _, t := range append(targets, b.Exclude...) {
+		if err := sets.ValidateTarget(t); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// GetActiveBehaviors returns the enabled behaviors targeting a chat whose schedule allows them to run now.
+// Aliases are other JIDs of the same chat (e.g. SenderAlt for LID chats). Targets are resolved here
+// (JIDs, contact sets, tags, all_groups, all_chats and exclusions); when several instances of the
+// same template apply, the most specific target wins.
+func (bm *BehaviorManager) GetActiveBehaviors(contact string, aliases ...string) ([]Behavior, error) {
+	all, err := bm.loadBehaviors()
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	var candidates []Behavior
+	for _, b := range all {
+		if b.IsActiveAt(now) {
+			candidates = append(candidates, b)
+		}
+	}
+	if len(candidates) == 0 {
+		return []Behavior{}, nil
+	}
+
+	sets, err := LoadContactSets(bm.ContactSetsPath)
+	if err != nil {
+		fmt.Printf("Warning: %v (sets and tags ignored)\n", err)
+		sets = &ContactSets{}
+	}
 
+	ids := []string{contact}
+	for _, alias := range aliases {
+		if alias != "" {
+			ids = append(ids, alias)
+		}
+	}
+	return resolveTargets(candidates, ids, sets), nil
+}
+
+// GetAllActiveBehaviors returns all enabled behaviors across all contacts (regardless of schedule)
+func (bm *BehaviorManager) GetAllActiveBehaviors() ([]Behavior, error) {
+	all, err := bm.loadBehaviors()
+	if err != nil {
+		return nil, err
+	}
+
+	var matchBehaviors []Behavior
+	for _, b := range all {
 		if b.Status == StatusEnabled {
 			matchBehaviors = append(matchBehaviors, b)
 		}
@@ -197,3 +434,31 @@ func (bm *BehaviorManager) GetAllActiveBehaviors() ([]Behavior, error) {
 
 	return matchBehaviors, nil
 }
+
+// CheckExpiredBehaviors disables enabled behaviors whose schedule end date has passed
+// and returns them so the caller can report it. Behaviors the master re-enabled after
+// the end date are left running.
+func (bm *BehaviorManager) CheckExpiredBehaviors() ([]Behavior, error) {
+	all, err := bm.loadBehaviors()
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	var expired []Behavior
+	for _, b := range all {
+		if b.Status != StatusEnabled || !b.Schedule.Expired(now) {
+			continue
+		}
+		if b.reenabledAfterEnd() {
+			continue
+		}
+		if err := bm.DisableBehavior(b.ID); err != nil {
+			fmt.Printf("Failed to disable expired behavior %d: %v\n", b.ID, err)
+			continue
+		}
+		fmt.Printf("[BehaviorManager] Behavior %d expired (end date %s)\n", b.ID, b.Schedule.EndDate)
+		expired = append(expired, b)
+	}
+	return expired, nil
+}
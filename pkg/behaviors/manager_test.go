@@ -0,0 +1,107 @@
+package behaviors
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestBehaviorLifecycle(t *testing.T) {
+	bm := NewBehaviorManager(t.TempDir())
+
+	b, err := bm.EnableBehavior(Behavior{Contact: "123@s.whatsapp.net", Name: "agenda", Comments: "family"})
+	if err != nil {
+		t.Fatalf("EnableBehavior failed: %v", err)
+	}
+
+	// Soft disable keeps the file
+	if err := bm.DisableBehavior(b.ID); err != nil {
+		t.Fatalf("DisableBehavior failed: %v", err)
+	}
+	loaded, err := bm.LoadBehavior(b.ID)
+	if err != nil {
+		t.Fatalf("LoadBehavior failed after disable: %v", err)
+	}
+	if loaded.Status != StatusDisabled || loaded.DisabledAt == 0 {
+		t.Errorf("Expected disabled behavior with DisabledAt, got %+v", loaded)
+	}
+	if active, _ := bm.GetActiveBehaviors(b.Contact); len(active) != 0 {
+		t.Errorf("Disabled behavior should not be active, got %d", len(active))
+	}
+
+	// Update re-enables and changes comments
+	status, comments := StatusEnabled, "work group"
+	updated, err := bm.UpdateBehavior(b.ID, BehaviorUpdate{Status: &status, Comments: &comments})
+	if err != nil {
+		t.Fatalf("UpdateBehavior failed: %v", err)
+	}
+	if updated.Status != StatusEnabled || updated.Comments != comments || updated.UpdatedAt == 0 {
+		t.Errorf("Unexpected updated behavior: %+v", updated)
+	}
+	bad := "paused"
+	if _, err := bm.UpdateBehavior(b.ID, BehaviorUpdate{Status: &bad}); err == nil {
+		t.Error("Expected error for invalid status")
+	}
+
+	// Activity log
+	entry := ActivityEntry{RunID: "1", Contact: b.Contact, Trigger: "see you at 10", Actions: []ActivityAction{{Type: "message_master", Content: "Event at 10"}}}
+	if err := bm.LogActivity(b.ID, entry); err != nil {
+		t.Fatalf("LogActivity failed: %v", err)
+	}
+	entry.RunID = "2"
+	if err := bm.LogActivity(b.ID, entry); err != nil {
+		t.Fatalf("LogActivity failed: %v", err)
+	}
+	entries, err := bm.GetActivity(b.ID, 1)
+	if err != nil {
+		t.Fatalf("GetActivity failed: %v", err)
+	}
+	if len(entries) != 1 || entries[0].RunID != "2" {
+		t.Errorf("Expected only the latest run, got %+v", entries)
+	}
+
+	// Archive moves behavior and log
+	if err := bm.ArchiveBehavior(b.ID); err != nil {
+		t.Fatalf("ArchiveBehavior failed: %v", err)
+	}
+	if _, err := os.Stat(filepath.Join(bm.ArchivedDir, "1.json")); err != nil {
+		t.Errorf("Archived behavior file missing: %v", err)
+	}
+	if list, _ := bm.ListBehaviors(false); len(list) != 0 {
+		t.Errorf("Archived behavior should not be listed, got %d", len(list))
+	}
+	if list, _ := bm.ListBehaviors(true); len(list) != 1 {
+		t.Errorf("Expected archived behavior when including archived, got %d", len(list))
+	}
+	if entries, _ := bm.GetActivity(b.ID, 0); len(entries) != 2 {
+		t.Errorf("Expected activity log to survive archive, got %d entries", len(entries))
+	}
+}
+
+func TestCheckExpiredBehaviors(t *testing.T) {
+	bm := NewBehaviorManager(t.TempDir())
+	b, err := bm.EnableBehavior(Behavior{Contact: "123@s.whatsapp.net", Name: "agenda", Schedule: &Schedule{EndDate: "2024-01-01"}})
+	if err != nil {
+		t.Fatalf("EnableBehavior failed: %v", err)
+	}
+
+	expired, err := bm.CheckExpiredBehaviors()
+	if err != nil || len(expired) != 1 {
+		t.Fatalf("expected the behavior to expire, got %v, %v", expired, err)
+	}
+
+	// Re-enabled by the master after the end date: left running
+	status := StatusEnabled
+	if _, err := bm.UpdateBehavior(b.ID, BehaviorUpdate{Status: &status}); err != nil {
+		t.Fatalf("UpdateBehavior failed: %v", err)
+	}
+	if expired, _ := bm.CheckExpiredBehaviors(); len(expired) != 0 {
+		t.Errorf("expected the re-enabled behavior to be kept, got %v", expired)
+	}
+	if loaded, _ := bm.LoadBehavior(b.ID); loaded.Status != StatusEnabled {
+		t.Errorf("expected the behavior to stay enabled, got %s", loaded.Status)
+	}
+	if active, _ := bm.GetActiveBehaviors("123@s.whatsapp.net"); len(active) != 1 {
+		t.Errorf("expected the re-enabled behavior to run, got %v", active)
+	}
+}
@@ -0,0 +1,146 @@
+package behaviors
+
+import (
+	"fmt"
+	"strings"
+	"time"
+)
+
+// Schedule limits when an enabled behavior is active.
+// Outside the date range the behavior is off. Inside it, holidays are active all day,
+// otherwise the behavior must be inside ActiveHours (if any) and outside InactiveHours.
+type Schedule struct {
+	Timezone      string       `json:"timezone,omitempty"`       // IANA name (e.g. America/Argentina/Buenos_Aires), defaults to local
+	ActiveHours   []TimeWindow `json:"active_hours,omitempty"`   // Active only inside these weekly windows
+	InactiveHours []TimeWindow `json:"inactive_hours,omitempty"` // Never active inside these weekly windows (e.g. office hours)
+	Holidays      []string     `json:"holidays,omitempty"`       // YYYY-MM-DD dates active all day regardless of hours
+	StartDate     string       `json:"start_date,omitempty"`     // YYYY-MM-DD or YYYY-MM-DDTHH:MM
+	EndDate       string       `json:"end_date,omitempty"`       // YYYY-MM-DD (inclusive) or YYYY-MM-DDTHH:MM
+}
+
+const (
+	dateLayout     = "2006-01-02"
+	dateTimeLayout = "2006-01-02T15:04"
+)
+
+// Validate checks timezone, dates and windows
+func (s *Schedule) Validate() error {
+	if s == nil {
+		return nil
+	}
+	if s.Timezone != "" {
+		if _, err := time.LoadLocation(s.Timezone); err != nil {
+			return fmt.Errorf("invalid timezone '%s': %w", s.Timezone, err)
+		}
+	}
+	for _, d := range []string{s.StartDate, s.EndDate} {
+		if d == "" {
+			continue
+		}
+		if _, _, err := parseDate(d, time.Local); err != nil {
+			return err
+		}
+	}
+	for _, d := range s.Holidays {
+		if _, err := time.Parse(dateLayout, strings.TrimSpace(d)); err != nil {
+			return fmt.Errorf("invalid holiday '%s' (expected YYYY-MM-DD): %w", d, err)
+		}
+	}
+	for _, windows := range [][]TimeWindow{s.ActiveHours, s.InactiveHours} {
+		for _, w := range windows {
+			if err := w.validate(); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// Location returns the schedule timezone, falling back to local time
+func (s *Schedule) Location() *time.Location {
+	if s == nil || s.Timezone == "" {
+		return time.Local
+	}
+	loc, err := time.LoadLocation(s.Timezone)
+	if err != nil {
+		fmt.Printf("Warning: invalid behavior timezone '%s', using local: %v\n", s.Timezone, err)
+		return time.Local
+	}
+	return loc
+}
+
+// ActiveAt reports whether the schedule allows the behavior to run at t
+func (s *Schedule) ActiveAt(t time.Time) bool {
+	if s == nil {
+		return true
+	}
+	loc := s.Location()
+	t = t.In(loc)
+
+	if s.StartDate != "" {
+		if start, _, err := parseDate(s.StartDate, loc); err == nil && t.Before(start) {
+			return false
+		}
+	}
+	if s.Expired(t) {
+		return false
+	}
+
+	today := t.Format(dateLayout)
+	for _, d := range s.Holidays {
+		if strings.TrimSpace(d) == today {
+			return true
+		}
+	}
+
+	if len(s.ActiveHours) > 0 {
+		inside := false
+		for _, w := range s.ActiveHours {
+			if w.Contains(t) {
+				inside = true
+				break
+			}
+		}
+		if !inside {
+			return false
+		}
+	}
+	for _, w := range s.InactiveHours {
+		if w.Contains(t) {
+			return false
+		}
+	}
+	return true
+}
+
+// End returns the instant the schedule ends, if it has an end date
+func (s *Schedule) End() (time.Time, bool) {
+	if s == nil || s.EndDate == "" {
+		return time.Time{}, false
+	}
+	_, end, err := parseDate(s.EndDate, s.Location())
+	if err != nil {
+		return time.Time{}, false
+	}
+	return end, true
+}
+
+// Expired reports whether the schedule end date has passed at t
+func (s *Schedule) Expired(t time.Time) bool {
+	end, ok := s.End()
+	return ok && !t.Before(end)
+}
+
+// parseDate parses a schedule date and returns its start and (exclusive) end instant.
+// A date without time covers the whole day.
+func parseDate(s string, loc *time.Location) (time.Time, time.Time, error) {
+	s = strings.TrimSpace(s)
+	if t, err := time.ParseInLocation(dateTimeLayout, s, loc); err == nil {
+		return t, t, nil
+	}
+	t, err := time.ParseInLocation(dateLayout, s, loc)
+	if err != nil {
+		return time.Time{}, time.Time{}, fmt.Errorf("invalid date '%s' (expected YYYY-MM-DD or YYYY-MM-DDTHH:MM)", s)
+	}
+	return t, t.AddDate(0, 0, 1), nil
+}
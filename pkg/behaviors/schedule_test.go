@@ -0,0 +1,71 @@
+package behaviors
+
+import (
+	"testing"
+	"time"
+)
+
+func TestSchedule_ActiveAt(t *testing.T) {
+	// Out-of-office: active outside weekday office hours and all day on holidays
+	s := &Schedule{
+		Timezone: "UTC",
+		InactiveHours: []TimeWindow{
+			{Days: []string{"mon", "tue", "wed", "thu", "fri"}, Start: "09:00", End: "18:00"},
+		},
+		Holidays:  []string{"2025-01-07"},
+		StartDate: "2025-01-01",
+		EndDate:   "2025-01-31",
+	}
+	if err := s.Validate(); err != nil {
+		t.Fatalf("Validate failed: %v", err)
+	}
+
+	at := func(month time.Month, day, hour int) time.Time {
+		return time.Date(2025, month, day, hour, 0, 0, 0, time.UTC)
+	}
+
+	cases := []struct {
+		name string
+		t    time.Time
+		want bool
+	}{
+		{"office hours", at(1, 6, 10), false},
+		{"evening", at(1, 6, 20), true},
+		{"weekend", at(1, 11, 10), true},
+		{"holiday during office hours", at(1, 7, 10), true},
+		{"before start", at(12, 31, 20).AddDate(-1, 0, 0), false},
+		{"last day", at(1, 31, 20), true},
+		{"after end", at(2, 1, 20), false},
+	}
+	for _, c := range cases {
+		if got := s.ActiveAt(c.t); got != c.want {
+			t.Errorf("%s: expected %v, got %v", c.name, c.want, got)
+		}
+	}
+
+	if !s.Expired(at(2, 1, 0)) {
+		t.Error("Schedule should be expired after the end date")
+	}
+	if s.Expired(at(1, 31, 23)) {
+		t.Error("Schedule should not be expired on the end date")
+	}
+}
+
+func TestSchedule_Validate(t *testing.T) {
+	bad := []*Schedule{
+		{Timezone: "Mars/Olympus"},
+		{StartDate: "tomorrow"},
+		{Holidays: []string{"2025-13-01"}},
+		{ActiveHours: []TimeWindow{{Start: "9", End: "18:00"}}},
+	}
+	for i, s := range bad {
+		if err := s.Validate(); err == nil {
+			t.Errorf("case %d: expected validation error", i)
+		}
+	}
+
+	var none *Schedule
+	if !none.ActiveAt(time.Now()) {
+		t.Error("nil schedule should always be active")
+	}
+}
@@ -0,0 +1,197 @@
+package behaviors
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+)
+
+// Special targets
+const (
+	TargetAllGroups = "all_groups" // Every group chat
+	TargetAllChats  = "all_chats"  // Every 1:1 chat
+
+	targetSetPrefix = "set:"
+	targetTagPrefix = "tag:"
+)
+
+// Target specificity, used as precedence when several behaviors with the same
+// template match a chat: the most specific one wins.
+const (
+	matchNone = iota
+	matchAll
+	matchTag
+	matchSet
+	matchJID
+)
+
+// ContactSets holds named contact sets and contact tags, stored in config/contact_sets.json
+type ContactSets struct {
+	Sets map[string][]string `json:"sets"` // Set name -> member JIDs or numbers
+	Tags map[string][]string `json:"tags"` // JID or number -> tags
+}
+
+// LoadContactSets reads the contact sets file. A missing file means no sets or tags.
+func LoadContactSets(path string) (*ContactSets, error) {
+	cs := &ContactSets{}
+	if path == "" {
+		return cs, nil
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return cs, nil
+		}
+		return nil, fmt.Errorf("failed to read contact sets: %w", err)
+	}
+	if err := json.Unmarshal(data, cs); err != nil {
+		return nil, fmt.Errorf("failed to parse contact sets: %w", err)
+	}
+	return cs, nil
+}
+
+// inSet reports whether any of the ids belongs to the named set
+func (cs *ContactSets) inSet(name string, ids []string) bool {
+	for _, member := range cs.Sets[name] {
+		for _, id := range ids {
+			if MatchJID(member, id) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// hasTag reports whether any of the ids is tagged with tag
+func (cs *ContactSets) hasTag(tag string, ids []string) bool {
+	for contact, tags := range cs.Tags {
+		matched := false
+		for _, id := range ids {
+			if MatchJID(contact, id) {
+				matched = true
+				break
+			}
+		}
+		if !matched {
+			continue
+		}
+		for _, t := range tags {
+			if strings.EqualFold(t, tag) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// ValidateTarget checks a target expression against the known sets
+func (cs *ContactSets) ValidateTarget(target string) error {
+	target = strings.TrimSpace(target)
+	switch {
+	case target == "":
+		return fmt.Errorf("empty target")
+	case strings.HasPrefix(target, targetSetPrefix):
+		name := strings.TrimPrefix(target, targetSetPrefix)
+		if _, ok := cs.Sets[name]; !ok {
+			var names []string
+			for n := range cs.Sets {
+				names = append(names, n)
+			}
+			sort.Strings(names)
+			return fmt.Errorf("unknown contact set '%s' (available: %s)", name, strings.Join(names, ", "))
+		}
+	}
+	return nil
+}
+
+// matchTarget returns the specificity with which target matches the chat identified by ids
+func matchTarget(target string, ids []string, sets *ContactSets) int {
+	target = strings.TrimSpace(target)
+	switch {
+	case target == "":
+		return matchNone
+	case target == TargetAllGroups:
+		for _, id := range ids {
+			if strings.HasSuffix(id, "@g.us") {
+				return matchAll
+			}
+		}
+	case target == TargetAllChats:
+		for _, id := range ids {
+			if strings.HasSuffix(id, "@s.whatsapp.net") || strings.HasSuffix(id, "@lid") {
+				return matchAll
+			}
+		}
+	case strings.HasPrefix(target, targetSetPrefix):
+		if sets.inSet(strings.TrimPrefix(target, targetSetPrefix), ids) {
+			return matchSet
+		}
+	case strings.HasPrefix(target, targetTagPrefix):
+		if sets.hasTag(strings.TrimPrefix(target, targetTagPrefix), ids) {
+			return matchTag
+		}
+	default:
+		for _, id := range ids {
+			if MatchJID(target, id) {
+				return matchJID
+			}
+		}
+	}
+	return matchNone
+}
+
+// AllTargets returns Contact together with Targets
+func (b *Behavior) AllTargets() []string {
+	var targets []string
+	if b.Contact != "" {
+		targets = append(targets, b.Contact)
+	}
+	return append(targets, b.Targets...)
+}
+
+// targetMatch returns the best specificity of the behavior targets for the chat,
+// or matchNone if the chat is excluded
+func (b *Behavior) targetMatch(ids []string, sets *ContactSets) int {
+	for _, ex := range b.Exclude {
+		if matchTarget(ex, ids, sets) != matchNone {
+			return matchNone
+		}
+	}
+	best := matchNone
+	for _, target := range b.AllTargets() {
+		if m := matchTarget(target, ids, sets); m > best {
+			best = m
+		}
+	}
+	return best
+}
+
+// resolveTargets picks the behaviors that apply to the chat identified by ids.
+// When several instances of the same template match, only the most specific
+// target wins (JID > set > tag > all_groups/all_chats); ties go to the newest instance.
+func resolveTargets(candidates []Behavior, ids []string, sets *ContactSets) []Behavior {
+	type scored struct {
+		behavior Behavior
+		score    int
+	}
+	best := map[string]scored{}
+	for _, b := range candidates {
+		score := b.targetMatch(ids, sets)
+		if score == matchNone {
+			continue
+		}
+		current, ok := best[b.Name]
+		if !ok || score > current.score || (score == current.score && b.ID > current.behavior.ID) {
+			best[b.Name] = scored{behavior: b, score: score}
+		}
+	}
+
+	resolved := make([]Behavior, 0, len(best))
+	for _, s := range best {
+		resolved = append(resolved, s.behavior)
+	}
+	sort.Slice(resolved, func(i, j int) bool { return resolved[i].ID < resolved[j].ID })
+	return resolved
+}
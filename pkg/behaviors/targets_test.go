@@ -0,0 +1,42 @@
+package behaviors
+
+import "testing"
+
+func TestResolveTargetsPrecedence(t *testing.T) {
+	sets := &ContactSets{
+		Sets: map[string][]string{"family": {"111"}},
+		Tags: map[string][]string{"111@s.whatsapp.net": {"vip"}},
+	}
+	candidates := []Behavior{
+		{ID: 1, Name: "agenda", Contact: TargetAllChats},
+		{ID: 2, Name: "agenda", Contact: "set:family"},
+		{ID: 3, Name: "agenda", Contact: "tag:vip"},
+		{ID: 4, Name: "audio_brief", Contact: TargetAllGroups},
+		{ID: 5, Name: "sales", Contact: TargetAllChats, Exclude: []string{"111"}},
+	}
+
+	got := resolveTargets(candidates, []string{"111@s.whatsapp.net"}, sets)
+	if len(got) != 1 || got[0].ID != 2 {
+		t.Fatalf("expected only behavior 2 (set beats tag and all_chats, exclusion drops 5), got %+v", got)
+	}
+
+	got = resolveTargets(candidates, []string{"222@s.whatsapp.net"}, sets)
+	if len(got) != 2 || got[0].ID != 1 || got[1].ID != 5 {
+		t.Fatalf("expected behaviors 1 and 5, got %+v", got)
+	}
+
+	got = resolveTargets(candidates, []string{"999-123@g.us"}, sets)
+	if len(got) != 1 || got[0].ID != 4 {
+		t.Fatalf("expected behavior 4 for a group, got %+v", got)
+	}
+}
+
+func TestValidateTargetUnknownSet(t *testing.T) {
+	sets := &ContactSets{Sets: map[string][]string{"family": {"111"}}}
+	if err := sets.ValidateTarget("set:family"); err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+	if err := sets.ValidateTarget("set:work"); err == nil {
+		t.Error("expected error for unknown set")
+	}
+}
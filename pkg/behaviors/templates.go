@@ -0,0 +1,306 @@
+package behaviors
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// MaxTemplateSize limits the size of a behavior template written from chat
+const MaxTemplateSize = 32 * 1024
+
+var templateNameRe = regexp.MustCompile(`^[a-z0-9][a-z0-9_-]{0,63}$`)
+
+// TemplateStore manages behavior templates (config/modes/behavior/<name>.txt and
+// the optional <name>.json trigger) and keeps a version history of every change.
+type TemplateStore struct {
+	Dir        string
+	HistoryDir string
+}
+
+// TemplateVersion is a snapshot of a template taken before it was changed
+type TemplateVersion struct {
+	Version    int    `json:"version"`   // Sequential per template, starting at 1
+	Timestamp  int64  `json:"timestamp"` // Unix timestamp of the snapshot
+	Reason     string `json:"reason"`    // edit, delete, rollback
+	Size       int    `json:"size"`
+	HasTrigger bool   `json:"has_trigger"`
+}
+
+// NewTemplateStore creates a TemplateStore for the behavior templates directory
+func NewTemplateStore(dir string) *TemplateStore {
+	return &TemplateStore{
+		Dir:        dir,
+		HistoryDir: filepath.Join(dir, "history"),
+	}
+}
+
+// ValidateTemplateName checks a template name. `__` prefixed base templates are protected.
+func ValidateTemplateName(name string) error {
+	if strings.HasPrefix(name, "__") {
+		return fmt.Errorf("template '%s' is protected", name)
+	}
+	if !templateNameRe.MatchString(name) {
+		return fmt.Errorf("invalid template name '%s' (use lowercase letters, digits, '_' and '-')", name)
+	}
+	return nil
+}
+
+func (ts *TemplateStore) textPath(name string) string {
+	return filepath.Join(ts.Dir, name+".txt")
+}
+
+func (ts *TemplateStore) triggerPath(name string) string {
+	return filepath.Join(ts.Dir, name+".json")
+}
+
+// Exists reports whether a template exists
+func (ts *TemplateStore) Exists(name string) bool {
+	_, err := os.Stat(ts.textPath(name))
+	return err == nil
+}
+
+// ListTemplates returns the names of the editable templates
+func (ts *TemplateStore) ListTemplates() ([]string, error) {
+	entries, err := os.ReadDir(ts.Dir)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return []string{}, nil
+		}
+		return nil, fmt.Errorf("failed to read templates directory: %w", err)
+	}
+	var names []string
+	for _, entry := range entries {
+		if !entry.IsDir() && strings.HasSuffix(entry.Name(), ".txt") && !strings.HasPrefix(entry.Name(), "__") {
+			names = append(names, strings.TrimSuffix(entry.Name(), ".txt"))
+		}
+	}
+	return names, nil
+}
+
+// ReadTemplate returns the template text and its trigger JSON ("" if none)
+func (ts *TemplateStore) ReadTemplate(name string) (string, string, error) {
+	if err := ValidateTemplateName(name); err != nil {
+		return "", "", err
+	}
+	content, err := os.ReadFile(ts.textPath(name))
+	if err != nil {
+		if os.IsNotExist(err) {
+			return "", "", fmt.Errorf("template '%s' not found", name)
+		}
+		return "", "", fmt.Errorf("failed to read template %s: %w", name, err)
+	}
+	trigger, err := os.ReadFile(ts.triggerPath(name))
+	if err != nil && !os.IsNotExist(err) {
+		return "", "", fmt.Errorf("failed to read trigger of %s: %w", name, err)
+	}
+	return string(content), string(trigger), nil
+}
+
+// CheckTemplate validates template content and an optional trigger definition
+func CheckTemplate(content string, trigger json.RawMessage) error {
+	if strings.TrimSpace(content) == "" {
+		return fmt.Errorf("template content is empty")
+	}
+	if len(content) > MaxTemplateSize {
+		return fmt.Errorf("template is too large (%d bytes, max %d)", len(content), MaxTemplateSize)
+	}
+	if len(trigger) > 0 && string(trigger) != "null" {
+		var t Trigger
+		if err := json.Unmarshal(trigger, &t); err != nil {
+			return fmt.Errorf("invalid trigger: %w", err)
+		}
+		if err := t.compile(); err != nil {
+			return fmt.Errorf("invalid trigger: %w", err)
+		}
+	}
+	return nil
+}
+
+// SaveTemplate writes a template, snapshotting the previous version first.
+// A nil trigger keeps the current trigger file, a JSON null removes it.
+func (ts *TemplateStore) SaveTemplate(name, content string, trigger json.RawMessage) error {
+	if err := ValidateTemplateName(name); err != nil {
+		return err
+	}
+	if err := CheckTemplate(content, trigger); err != nil {
+		return err
+	}
+	if err := os.MkdirAll(ts.Dir, 0755); err != nil {
+		return fmt.Errorf("failed to create templates directory: %w", err)
+	}
+	if ts.Exists(name) {
+		if _, err := ts.snapshot(name, "edit"); err != nil {
+			return err
+		}
+	}
+
+	if err := os.WriteFile(ts.textPath(name), []byte(content), 0644); err != nil {
+		return fmt.Errorf("failed to write template %s: %w", name, err)
+	}
+	return ts.writeTrigger(name, trigger)
+}
+
+func (ts *TemplateStore) writeTrigger(name string, trigger json.RawMessage) error {
+	if trigger == nil {
+		return nil
+	}
+	if string(trigger) == "null" || len(trigger) == 0 {
+		if err := os.Remove(ts.triggerPath(name)); err != nil && !os.IsNotExist(err) {
+			return fmt.Errorf("failed to remove trigger of %s: %w", name, err)
+		}
+		return nil
+	}
+	var pretty interface{}
+	if err := json.Unmarshal(trigger, &pretty); err != nil {
+		return fmt.Errorf("invalid trigger: %w", err)
+	}
+	data, _ := json.MarshalIndent(pretty, "", "  ")
+	if err := os.WriteFile(ts.triggerPath(name), data, 0644); err != nil {
+		return fmt.Errorf("failed to write trigger of %s: %w", name, err)
+	}
+	return nil
+}
+
+// DeleteTemplate removes a template and its trigger. A snapshot is kept in the history.
+func (ts *TemplateStore) DeleteTemplate(name string) error {
+	if err := ValidateTemplateName(name); err != nil {
+		return err
+	}
+	if !ts.Exists(name) {
+		return fmt.Errorf("template '%s' not found", name)
+	}
+	if _, err := ts.snapshot(name, "delete"); err != nil {
+		return err
+	}
+	if err := os.Remove(ts.textPath(name)); err != nil {
+		return fmt.Errorf("failed to delete template %s: %w", name, err)
+	}
+	if err := os.Remove(ts.triggerPath(name)); err != nil && !os.IsNotExist(err) {
+		return fmt.Errorf("failed to delete trigger of %s: %w", name, err)
+	}
+	return nil
+}
+
+// versionDir returns the history directory of a template
+func (ts *TemplateStore) versionDir(name string) string {
+	return filepath.Join(ts.HistoryDir, name)
+}
+
+// snapshot copies the current template (and trigger) to the history
+func (ts *TemplateStore) snapshot(name, reason string) (int, error) {
+	content, trigger, err := ts.ReadTemplate(name)
+	if err != nil {
+		return 0, err
+	}
+	dir := ts.versionDir(name)
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return 0, fmt.Errorf("failed to create history directory: %w", err)
+	}
+
+	existing, err := ts.Versions(name)
+	if err != nil {
+		return 0, err
+	}
+	version := 1
+	if len(existing) > 0 {
+		version = existing[0].Version + 1
+	}
+	base := filepath.Join(dir, strconv.Itoa(version))
+	if err := os.WriteFile(base+".txt", []byte(content), 0644); err != nil {
+		return 0, fmt.Errorf("failed to write template snapshot: %w", err)
+	}
+	if trigger != "" {
+		if err := os.WriteFile(base+".json", []byte(trigger), 0644); err != nil {
+			return 0, fmt.Errorf("failed to write trigger snapshot: %w", err)
+		}
+	}
+	meta, _ := json.Marshal(TemplateVersion{Version: version, Timestamp: time.Now().Unix(), Reason: reason, Size: len(content), HasTrigger: trigger != ""})
+	if err := os.WriteFile(base+".meta", meta, 0644); err != nil {
+		return 0, fmt.Errorf("failed to write snapshot metadata: %w", err)
+	}
+	return version, nil
+}
+
+// Versions lists the saved versions of a template, newest first
+func (ts *TemplateStore) Versions(name string) ([]TemplateVersion, error) {
+	if err := ValidateTemplateName(name); err != nil {
+		return nil, err
+	}
+	entries, err := os.ReadDir(ts.versionDir(name))
+	if err != nil {
+		if os.IsNotExist(err) {
+			return []TemplateVersion{}, nil
+		}
+		return nil, fmt.Errorf("failed to read history of %s: %w", name, err)
+	}
+
+	versions := []TemplateVersion{}
+	for _, entry := range entries {
+		if entry.IsDir() || !strings.HasSuffix(entry.Name(), ".meta") {
+			continue
+		}
+		data, err := os.ReadFile(filepath.Join(ts.versionDir(name), entry.Name()))
+		if err != nil {
+			continue
+		}
+		var v TemplateVersion
+		if err := json.Unmarshal(data, &v); err != nil {
+			continue
+		}
+		versions = append(versions, v)
+	}
+	sort.Slice(versions, func(i, j int) bool { return versions[i].Version > versions[j].Version })
+	return versions, nil
+}
+
+// ReadVersion returns the template text and trigger JSON of a saved version
+func (ts *TemplateStore) ReadVersion(name string, version int) (string, string, error) {
+	if err := ValidateTemplateName(name); err != nil {
+		return "", "", err
+	}
+	base := filepath.Join(ts.versionDir(name), strconv.Itoa(version))
+	content, err := os.ReadFile(base + ".txt")
+	if err != nil {
+		if os.IsNotExist(err) {
+			return "", "", fmt.Errorf("version %d of template '%s' not found", version, name)
+		}
+		return "", "", fmt.Errorf("failed to read version %d of %s: %w", version, name, err)
+	}
+	trigger, err := os.ReadFile(base + ".json")
+	if err != nil && !os.IsNotExist(err) {
+		return "", "", fmt.Errorf("failed to read trigger of version %d: %w", version, err)
+	}
+	return string(content), string(trigger), nil
+}
+
+// Rollback restores a saved version. The current template (if any) is snapshotted first,
+// so a rollback can be undone too.
+func (ts *TemplateStore) Rollback(name string, version int) error {
+	content, trigger, err := ts.ReadVersion(name, version)
+	if err != nil {
+		return err
+	}
+	if ts.Exists(name) {
+		if _, err := ts.snapshot(name, "rollback"); err != nil {
+			return err
+		}
+	}
+	if err := os.MkdirAll(ts.Dir, 0755); err != nil {
+		return fmt.Errorf("failed to create templates directory: %w", err)
+	}
+	if err := os.WriteFile(ts.textPath(name), []byte(content), 0644); err != nil {
+		return fmt.Errorf("failed to restore template %s: %w", name, err)
+	}
+	triggerJSON := json.RawMessage("null")
+	if trigger != "" {
+		triggerJSON = json.RawMessage(trigger)
+	}
+	return ts.writeTrigger(name, triggerJSON)
+}
@@ -0,0 +1,46 @@
+package behaviors
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+)
+
+func TestTemplateStoreHistory(t *testing.T) {
+	ts := NewTemplateStore(t.TempDir())
+
+	if err := ts.SaveTemplate("__base", "x", nil); err == nil {
+		t.Fatal("expected protected template to be refused")
+	}
+	if err := ts.SaveTemplate("greeter", "v1", json.RawMessage(`{"keywords":["hola"]}`)); err != nil {
+		t.Fatalf("SaveTemplate failed: %v", err)
+	}
+	if err := ts.SaveTemplate("greeter", "v2", json.RawMessage(`null`)); err != nil {
+		t.Fatalf("SaveTemplate failed: %v", err)
+	}
+	if err := ts.SaveTemplate("greeter", "v3", json.RawMessage(`{"regex":["("]}`)); err == nil || !strings.Contains(err.Error(), "bad regex") {
+		t.Fatalf("expected invalid trigger regex to be refused, got %v", err)
+	}
+	if err := ts.SaveTemplate("greeter", "v3", json.RawMessage(`{"time_windows":[{"days":["monday"],"start":"09:00","end":"18:00"}]}`)); err == nil {
+		t.Fatal("expected an unknown weekday to be refused")
+	}
+
+	versions, err := ts.Versions("greeter")
+	if err != nil || len(versions) != 1 || versions[0].Version != 1 || !versions[0].HasTrigger {
+		t.Fatalf("unexpected versions %+v (err %v)", versions, err)
+	}
+
+	if err := ts.DeleteTemplate("greeter"); err != nil {
+		t.Fatalf("DeleteTemplate failed: %v", err)
+	}
+	if err := ts.Rollback("greeter", 1); err != nil {
+		t.Fatalf("Rollback failed: %v", err)
+	}
+	content, trigger, err := ts.ReadTemplate("greeter")
+	if err != nil || content != "v1" || trigger == "" {
+		t.Fatalf("expected v1 with trigger restored, got %q %q (err %v)", content, trigger, err)
+	}
+	if versions, _ := ts.Versions("greeter"); len(versions) != 2 {
+		t.Fatalf("expected 2 versions after delete, got %d", len(versions))
+	}
+}
@@ -0,0 +1,265 @@
+package behaviors
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"time"
+	"unicode/utf8"
+)
+
+// Trigger declares deterministic conditions a message must meet before a behavior
+// is evaluated by the LLM. It is stored as <name>.json next to the behavior template
+// in config/modes/behavior/. Every declared condition must match; within a condition
+// any of the listed values is enough.
+type Trigger struct {
+	Keywords    []string     `json:"keywords,omitempty"`     // Case-insensitive substrings
+	Regex       []string     `json:"regex,omitempty"`        // Regular expressions (matched together with keywords)
+	MediaTypes  []string     `json:"media_types,omitempty"`  // text, image, video, audio, docs
+	Senders     []string     `json:"senders,omitempty"`      // Sender JIDs or numbers (useful in groups)
+	MinLength   int          `json:"min_length,omitempty"`   // Minimum message length in characters
+	TimeWindows []TimeWindow `json:"time_windows,omitempty"` // Local time windows
+
+	compiled []*regexp.Regexp
+}
+
+// TimeWindow is a daily time range, optionally limited to some weekdays
+type TimeWindow struct {
+	Days  []string `json:"days,omitempty"` // mon, tue, wed, thu, fri, sat, sun (empty means every day)
+	Start string   `json:"start"`          // HH:MM
+	End   string   `json:"end"`            // HH:MM, lower than Start for overnight windows
+}
+
+// TriggerInput is the message data triggers are evaluated against
+type TriggerInput struct {
+	Text      string
+	MediaType string // "text" for plain messages, otherwise the media type
+	Sender    string
+	SenderAlt string
+	Time      time.Time
+}
+
+// LoadTrigger reads the trigger file for a behavior template.
+// Returns nil (and no error) when the template has no trigger file.
+func LoadTrigger(templatesDir, name string) (*Trigger, error) {
+	path := filepath.Join(templatesDir, name+".json")
+	data, err := os.ReadFile(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("failed to read trigger file %s: %w", path, err)
+	}
+
+	var t Trigger
+	if err := json.Unmarshal(data, &t); err != nil {
+		return nil, fmt.Errorf("failed to parse trigger file %s: %w", path, err)
+	}
+	if err := t.compile(); err != nil {
+		return nil, fmt.Errorf("invalid trigger %s: %w", name, err)
+	}
+	return &t, nil
+}
+
+func (t *Trigger) compile() error {
+	t.compiled = nil
+	for _, expr := range t.Regex {
+		re, err := regexp.Compile(expr)
+		if err != nil {
+			return fmt.Errorf("bad regex '%s': %w", expr, err)
+		}
+		t.compiled = append(t.compiled, re)
+	}
+	for _, w := range t.TimeWindows {
+		if err := w.validate(); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// WantsMedia reports whether the trigger explicitly asks for the given media type,
+// meaning the media must be downloaded (and transcribed) before evaluation.
+func (t *Trigger) WantsMedia(mediaType string) bool {
+	for _, m := range t.MediaTypes {
+		if strings.EqualFold(m, mediaType) {
+			return true
+		}
+	}
+	return false
+}
+
+// Match evaluates the trigger. When it doesn't match, the returned string explains why.
+func (t *Trigger) Match(in TriggerInput) (bool, string) {
+	if t == nil {
+		return true, ""
+	}
+	if t.compiled == nil && len(t.Regex) > 0 {
+		if err := t.compile(); err != nil {
+			return false, err.Error()
+		}
+	}
+
+	if len(t.MediaTypes) > 0 {
+		mediaType := in.MediaType
+		if mediaType == "" {
+			mediaType = "text"
+		}
+		if !t.WantsMedia(mediaType) {
+			return false, fmt.Sprintf("media type '%s' not in %v", mediaType, t.MediaTypes)
+		}
+	}
+
+	if len(t.Senders) > 0 {
+		found := false
+		for _, s := range t.Senders {
+			if MatchJID(s, in.Sender) || MatchJID(s, in.SenderAlt) {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return false, fmt.Sprintf("sender '%s' not allowed", in.Sender)
+		}
+	}
+
+	if t.MinLength > 0 && utf8.RuneCountInString(strings.TrimSpace(in.Text)) < t.MinLength {
+		return false, fmt.Sprintf("text shorter than %d characters", t.MinLength)
+	}
+
+	if len(t.Keywords) > 0 || len(t.compiled) > 0 {
+		if !t.matchText(in.Text) {
+			return false, "no keyword or regex matched"
+		}
+	}
+
+	if len(t.TimeWindows) > 0 {
+		inWindow := false
+		for _, w := range t.TimeWindows {
+			if w.Contains(in.Time) {
+				inWindow = true
+				break
+			}
+		}
+		if !inWindow {
+			return false, fmt.Sprintf("outside time windows at %s", in.Time.Format("Mon 15:04"))
+		}
+	}
+
+	return true, ""
+}
+
+func (t *Trigger) matchText(text string) bool {
+	lower := strings.ToLower(text)
+	for _, k := range t.Keywords {
+		if k != "" && strings.Contains(lower, strings.ToLower(k)) {
+			return true
+		}
+	}
+	for _, re := range t.compiled {
+		if re.MatchString(text) {
+			return true
+		}
+	}
+	return false
+}
+
+// Contains reports whether t falls inside the window (in t's location)
+func (w TimeWindow) Contains(t time.Time) bool {
+	start, err := parseClock(w.Start)
+	if err != nil {
+		return false
+	}
+	end, err := parseClock(w.End)
+	if err != nil {
+		return false
+	}
+	now := t.Hour()*60 + t.Minute()
+
+	// Overnight windows belong to the day they start on
+	day := t.Weekday()
+	var inside bool
+	if start <= end {
+		inside = now >= start && now < end
+	} else if now >= start {
+		inside = true
+	} else if now < end {
+		inside = true
+		day = (day + 6) % 7
+	}
+	if !inside {
+		return false
+	}
+
+	if len(w.Days) == 0 {
+		return true
+	}
+	for _, d := range w.Days {
+		if wd, ok := weekdays[strings.ToLower(strings.TrimSpace(d))]; ok && wd == day {
+			return true
+		}
+	}
+	return false
+}
+
+var weekdays = map[string]time.Weekday{
+	"sun": time.Sunday,
+	"mon": time.Monday,
+	"tue": time.Tuesday,
+	"wed": time.Wednesday,
+	"thu": time.Thursday,
+	"fri": time.Friday,
+	"sat": time.Saturday,
+}
+
+// validate checks the clock times and weekday names of the window
+func (w TimeWindow) validate() error {
+	if _, err := parseClock(w.Start); err != nil {
+		return err
+	}
+	if _, err := parseClock(w.End); err != nil {
+		return err
+	}
+	for _, d := range w.Days {
+		if _, ok := weekdays[strings.ToLower(strings.TrimSpace(d))]; !ok {
+			return fmt.Errorf("invalid day '%s' (expected mon, tue, wed, thu, fri, sat or sun)", d)
+		}
+	}
+	return nil
+}
+
+// parseClock converts HH:MM into minutes since midnight
+func parseClock(s string) (int, error) {
+	t, err := time.Parse("15:04", strings.TrimSpace(s))
+	if err != nil {
+		return 0, fmt.Errorf("invalid time '%s' (expected HH:MM): %w", s, err)
+	}
+	return t.Hour()*60 + t.Minute(), nil
+}
+
+// MatchJID compares a configured contact against a JID. Patterns without a server
+// part (just the number) match the user part of the JID.
+func MatchJID(pattern, jid string) bool {
+	pattern = strings.TrimSpace(pattern)
+	if pattern == "" || jid == "" {
+		return false
+	}
+	if pattern == jid {
+		return true
+	}
+	user, server := jid, ""
+	if i := strings.Index(jid, "@"); i >= 0 {
+		user, server = jid[:i], jid[i+1:]
+	}
+	// Drop device part (e.g. 12345:3@s.whatsapp.net)
+	if i := strings.Index(user, ":"); i >= 0 {
+		user = user[:i]
+	}
+	if !strings.Contains(pattern, "@") {
+		return strings.TrimPrefix(pattern, "+") == user
+	}
+	return pattern == user+"@"+server
+}
@@ -0,0 +1,100 @@
+package behaviors
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestLoadTrigger_Missing(t *testing.T) {
+	trigger, err := LoadTrigger(t.TempDir(), "nothing")
+	if err != nil {
+		t.Fatalf("LoadTrigger failed: %v", err)
+	}
+	if trigger != nil {
+		t.Fatal("Expected nil trigger for missing file")
+	}
+	// A nil trigger always matches
+	if ok, _ := trigger.Match(TriggerInput{Text: "ok"}); !ok {
+		t.Error("nil trigger should match")
+	}
+}
+
+func TestLoadTrigger_InvalidRegex(t *testing.T) {
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, "bad.json"), []byte(`{"regex": ["("]}`), 0644); err != nil {
+		t.Fatalf("failed to write trigger: %v", err)
+	}
+	if _, err := LoadTrigger(dir, "bad"); err == nil {
+		t.Error("Expected error for invalid regex")
+	}
+}
+
+func TestTrigger_Match(t *testing.T) {
+	dir := t.TempDir()
+	content := `{
+		"keywords": ["meeting"],
+		"regex": ["\\b\\d{1,2}:\\d{2}\\b"],
+		"media_types": ["text"],
+		"senders": ["5491122223333"],
+		"min_length": 5
+	}`
+	if err := os.WriteFile(filepath.Join(dir, "agenda.json"), []byte(content), 0644); err != nil {
+		t.Fatalf("failed to write trigger: %v", err)
+	}
+	trigger, err := LoadTrigger(dir, "agenda")
+	if err != nil {
+		t.Fatalf("LoadTrigger failed: %v", err)
+	}
+
+	sender := "5491122223333@s.whatsapp.net"
+	cases := []struct {
+		name string
+		in   TriggerInput
+		want bool
+	}{
+		{"keyword", TriggerInput{Text: "Team MEETING on friday", Sender: sender}, true},
+		{"regex", TriggerInput{Text: "see you at 18:30", Sender: sender}, true},
+		{"no match", TriggerInput{Text: "ok thanks", Sender: sender}, false},
+		{"too short", TriggerInput{Text: "9:00", Sender: sender}, false},
+		{"wrong media", TriggerInput{Text: "meeting audio", MediaType: "audio", Sender: sender}, false},
+		{"other sender", TriggerInput{Text: "meeting tomorrow", Sender: "111@s.whatsapp.net"}, false},
+		{"sender alt", TriggerInput{Text: "meeting tomorrow", Sender: "999@lid", SenderAlt: sender}, true},
+	}
+	for _, c := range cases {
+		if got, reason := trigger.Match(c.in); got != c.want {
+			t.Errorf("%s: expected %v, got %v (%s)", c.name, c.want, got, reason)
+		}
+	}
+}
+
+func TestTimeWindow_Contains(t *testing.T) {
+	// 2025-01-06 is a Monday
+	at := func(day, hour, min int) time.Time {
+		return time.Date(2025, 1, day, hour, min, 0, 0, time.Local)
+	}
+
+	office := TimeWindow{Days: []string{"mon", "tue", "wed", "thu", "fri"}, Start: "09:00", End: "18:00"}
+	if !office.Contains(at(6, 10, 0)) {
+		t.Error("Monday 10:00 should be inside office hours")
+	}
+	if office.Contains(at(6, 18, 0)) {
+		t.Error("End time should be exclusive")
+	}
+	if office.Contains(at(5, 10, 0)) {
+		t.Error("Sunday should be outside office hours")
+	}
+
+	// Overnight window belongs to the day it starts on
+	night := TimeWindow{Days: []string{"fri"}, Start: "22:00", End: "02:00"}
+	if !night.Contains(at(10, 23, 0)) {
+		t.Error("Friday 23:00 should be inside the night window")
+	}
+	if !night.Contains(at(11, 1, 0)) {
+		t.Error("Saturday 01:00 should be inside Friday's night window")
+	}
+	if night.Contains(at(10, 1, 0)) {
+		t.Error("Friday 01:00 belongs to Thursday's night")
+	}
+}
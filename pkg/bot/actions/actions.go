@@ -17,11 +17,18 @@ type ActionSchema struct {
 
 // ActionContext holds execution context
 type ActionContext struct {
+	Mode            string // ModeCommand, ModeTask or ModeBehavior
+	Message         string // Incoming message of the current turn
+	ChatJID         string // Chat of the current turn in behavior mode
+	Behaviors       []int  // IDs of the behaviors running in behavior mode
 	Context         []string
 	Task            *tasks.Task // nil if not in task mode
 	BehaviorManager *behaviors.BehaviorManager
 	SendToContact   func(string)
-	ToolOutputs     *[]string // New: For returning tool results to the loop
+	ReplyToContact  func(text, messageID string) // Sends text quoting a stored message of the chat; nil when unavailable
+	ToolOutputs     *[]string                    // New: For returning tool results to the loop
+	Shadow          bool                         // Outbound side effects are captured and mirrored to the master instead of performed
+	TraceID         string                       // Correlates the run with the audit log and the LLM logs
 }
 
 // Action represents an executable action
@@ -58,16 +65,21 @@ func (r *Registry) GetSchemas() []ActionSchema {
 
 // GetSchemasFiltered returns a list of schemas for actions NOT in the exclude list, sorted by name
 func (r *Registry) GetSchemasFiltered(exclude []string) []ActionSchema {
-	schemas := make([]ActionSchema, 0, len(r.actions))
-	for name, a := range r.actions {
-		excluded := false
+	return r.GetSchemasWhere(func(name string) bool {
 		for _, e := range exclude {
 			if e == name {
-				excluded = true
-				break
+				return false
 			}
 		}
-		if !excluded {
+		return true
+	})
+}
+
+// GetSchemasWhere returns the schemas of the actions keep accepts, sorted by name
+func (r *Registry) GetSchemasWhere(keep func(name string) bool) []ActionSchema {
+	schemas := make([]ActionSchema, 0, len(r.actions))
+	for name, a := range r.actions {
+		if keep(name) {
 			schemas = append(schemas, a.GetSchema())
 		}
 	}
@@ -2,8 +2,11 @@ package actions
 
 import (
 	"encoding/json"
+	"fmt"
 	"os"
+	"strings"
 	"testing"
+	"whatsabladerunner/pkg/tasks"
 )
 
 func TestRegistry_RegistrationAndRetrieval(t *testing.T) {
@@ -139,3 +142,241 @@ func TestMemoryActions(t *testing.T) {
 		t.Errorf("Expected 'New content only', got '%s'", string(content))
 	}
 }
+
+func TestWatchPolicies_ReviewFor(t *testing.T) {
+	policies := DefaultWatchPolicies()
+	response := &ResponseAction{}
+	payload := json.RawMessage(`"Hi"`)
+
+	// Response to the master in command mode is internal
+	if _, needed := policies.ReviewFor(response, ActionContext{Mode: ModeCommand}, payload); needed {
+		t.Error("command mode response should not be reviewed")
+	}
+
+	// Response to a contact is external
+	ctx := ActionContext{Mode: ModeBehavior, SendToContact: func(string) {}}
+	review, needed := policies.ReviewFor(response, ctx, payload)
+	if !needed || review.Summary != "Hi" || review.Action != "response" {
+		t.Errorf("expected contact response to be reviewed, got %+v (needed=%v)", review, needed)
+	}
+
+	// Non reviewable actions are skipped unless forced
+	master := &MessageMasterAction{}
+	if _, needed := policies.ReviewFor(master, ctx, payload); needed {
+		t.Error("message_master should never be reviewed")
+	}
+	policies.Actions["message_master"] = WatchAlways
+	if _, needed := policies.ReviewFor(master, ctx, payload); !needed {
+		t.Error("message_master should be reviewed with policy always")
+	}
+
+	// Memory changes from a third party conversation are reviewed
+	memory := &MemoryAppendAction{}
+	if _, needed := policies.ReviewFor(memory, ActionContext{Mode: ModeTask}, payload); !needed {
+		t.Error("memory_append in task mode should be reviewed")
+	}
+}
+
+func TestPermissions_Allowed(t *testing.T) {
+	path := t.TempDir() + "/permissions.json"
+	os.WriteFile(path, []byte(`{"modes": {"behavior": {"allow": ["response", "github_*"], "deny": ["github_delete_*"]}}}`), 0644)
+	p, err := LoadPermissions(path)
+	if err != nil {
+		t.Fatalf("LoadPermissions failed: %v", err)
+	}
+
+	cases := []struct {
+		mode, action string
+		narrow       []string
+		want         bool
+	}{
+		{ModeCommand, "create_task", nil, true},
+		{ModeCommand, "message_master", nil, false},
+		{ModeTask, "update_behavior", nil, false},
+		{ModeTask, "response", []string{"response"}, true},
+		{ModeTask, "send_media", []string{"response"}, false},
+		{ModeBehavior, "github_issues", nil, true},
+		{ModeBehavior, "github_delete_repo", nil, false},
+		{ModeBehavior, "memory_update", nil, false},
+		{"unknown", "response", nil, false},
+	}
+	for _, c := range cases {
+		if got := p.Allowed(c.mode, c.action, c.narrow); got != c.want {
+			t.Errorf("Allowed(%s, %s, %v) = %v, want %v", c.mode, c.action, c.narrow, got, c.want)
+		}
+	}
+
+	// Defaults are default-deny for tasks and behaviors; a glob allow doesn't reach the
+	// default denies, naming the action does
+	defaults := DefaultPermissions()
+	os.WriteFile(path, []byte(`{"modes": {"task": {"allow": ["*", "memory_append"]}}}`), 0644)
+	if p, err = LoadPermissions(path); err != nil {
+		t.Fatalf("LoadPermissions failed: %v", err)
+	}
+	for _, c := range []struct {
+		p            *Permissions
+		mode, action string
+		want         bool
+	}{
+		{defaults, ModeBehavior, "ledger", false},
+		{defaults, ModeBehavior, "create_task", false},
+		{defaults, ModeTask, "send_poll", true},
+		{defaults, ModeTask, "pause_task", true},
+		{defaults, ModeTask, "finish_task", true},
+		{defaults, ModeTask, "delete_task", false},
+		{defaults, ModeBehavior, "pause_task", false},
+		{p, ModeTask, "ledger", true},
+		{p, ModeTask, "memory_append", true},
+		{p, ModeTask, "memory_update", false},
+		{p, ModeTask, "enable_behavior", false},
+	} {
+		if got := c.p.Allowed(c.mode, c.action); got != c.want {
+			t.Errorf("Allowed(%s, %s) = %v, want %v", c.mode, c.action, got, c.want)
+		}
+	}
+
+	os.WriteFile(path, []byte(`{"modes": {"task": {"allow": ["[bad"]}}}`), 0644)
+	if _, err := LoadPermissions(path); err == nil {
+		t.Error("expected an error for an invalid pattern")
+	}
+}
+
+func TestTaskManagementAction_OwnTask(t *testing.T) {
+	tm := tasks.NewTaskManager(t.TempDir())
+	own, _ := tm.CreateTask("Book a table", "123@s.whatsapp.net", "", "")
+	other, _ := tm.CreateTask("Pay the bill", "456@s.whatsapp.net", "", "")
+	tm.ConfirmTask(own.ID)
+	tm.ConfirmTask(other.ID)
+	ctx := ActionContext{Mode: ModeTask, Task: own}
+
+	pause := &TaskManagementAction{Type: TaskPause, TaskManager: tm}
+	if err := pause.Execute(ctx, json.RawMessage(fmt.Sprintf(`"%d"`, other.ID))); err == nil {
+		t.Error("expected a task to be refused pausing another task")
+	}
+	finish := &TaskManagementAction{Type: TaskFinish, TaskManager: tm}
+	if err := finish.Execute(ctx, json.RawMessage(fmt.Sprintf(`"%d"`, own.ID))); err != nil {
+		t.Fatalf("finish_task failed: %v", err)
+	}
+	if task, _ := tm.LoadTask(own.ID); task.Status != tasks.StatusFinished {
+		t.Errorf("expected the task to be finished, got %s", task.Status)
+	}
+}
+
+func TestResponseAction_ReplyTo(t *testing.T) {
+	var sent, replied, quoted string
+	ctx := ActionContext{
+		Mode:           ModeTask,
+		SendToContact:  func(msg string) { sent = msg },
+		ReplyToContact: func(msg, id string) { replied, quoted = msg, id },
+	}
+	response := &ResponseAction{}
+
+	if err := response.Execute(ctx, json.RawMessage(`"plain"`)); err != nil || sent != "plain" {
+		t.Errorf("expected a plain send, got %q (err=%v)", sent, err)
+	}
+	payload := json.RawMessage(`{"text": "yes", "reply_to": "#3EB0C7"}`)
+	if err := response.Execute(ctx, payload); err != nil || replied != "yes" || quoted != "3EB0C7" {
+		t.Errorf("expected a reply to 3EB0C7, got %q to %q (err=%v)", replied, quoted, err)
+	}
+	if review := response.Review(ctx, payload); review.Summary != "yes (reply to #3EB0C7)" {
+		t.Errorf("unexpected review summary %q", review.Summary)
+	}
+
+	// Without a replier the text is still sent
+	ctx.ReplyToContact = nil
+	if err := response.Execute(ctx, json.RawMessage(`{"text": "fallback", "reply_to": "X"}`)); err != nil || sent != "fallback" {
+		t.Errorf("expected a plain fallback send, got %q (err=%v)", sent, err)
+	}
+}
+
+type fakeMessenger struct {
+	calls []string
+}
+
+func (m *fakeMessenger) React(chat, id, emoji string) error {
+	m.calls = append(m.calls, "react "+chat+" "+id+" "+emoji)
+	return nil
+}
+func (m *fakeMessenger) MarkRead(chat string, ids []string) error {
+	m.calls = append(m.calls, fmt.Sprintf("read %s %v", chat, ids))
+	return nil
+}
+func (m *fakeMessenger) Edit(chat, id, text string) error {
+	return fmt.Errorf("#%s is not your message", id)
+}
+func (m *fakeMessenger) Delete(chat, id string) error {
+	m.calls = append(m.calls, "delete "+chat+" "+id)
+	return nil
+}
+
+func TestMessageActions(t *testing.T) {
+	m := &fakeMessenger{}
+	get := func() Messenger { return m }
+	var outputs []string
+	task := &tasks.Task{ID: 1, Contact: "111@s.whatsapp.net", ChatID: "222@s.whatsapp.net"}
+	ctx := ActionContext{Mode: ModeTask, Task: task, ToolOutputs: &outputs}
+
+	// Tasks act on their own chat, whatever the payload says
+	if err := NewReactAction(get).Execute(ctx, json.RawMessage(`{"message_id": "#ABC", "emoji": "👍", "chat": "999@s.whatsapp.net"}`)); err != nil {
+		t.Fatalf("react failed: %v", err)
+	}
+	if err := NewMarkReadAction(get).Execute(ctx, json.RawMessage(`{}`)); err != nil {
+		t.Fatalf("mark_read failed: %v", err)
+	}
+	// Command mode needs an explicit chat
+	cmd := ActionContext{Mode: ModeCommand, ToolOutputs: &outputs}
+	if err := NewDeleteMessageAction(get).Execute(cmd, json.RawMessage(`{"message_id": "X"}`)); err == nil {
+		t.Error("expected delete_message without a chat to fail in command mode")
+	}
+	if err := NewDeleteMessageAction(get).Execute(cmd, json.RawMessage(`{"message_id": "X", "chat": "333@s.whatsapp.net"}`)); err != nil {
+		t.Fatalf("delete_message failed: %v", err)
+	}
+	want := []string{"react 222@s.whatsapp.net ABC 👍", "read 222@s.whatsapp.net []", "delete 333@s.whatsapp.net X"}
+	if fmt.Sprint(m.calls) != fmt.Sprint(want) {
+		t.Errorf("calls = %v, want %v", m.calls, want)
+	}
+
+	// Failures are reported to the LLM
+	if err := NewEditMessageAction(get).Execute(ctx, json.RawMessage(`{"message_id": "ABC", "text": "fixed"}`)); err == nil {
+		t.Error("expected edit_message to fail")
+	}
+	if len(outputs) != 2 || !strings.Contains(outputs[1], "[edit_message] Failed") {
+		t.Errorf("unexpected tool outputs %v", outputs)
+	}
+
+	// The watcher sees the chat the operation acts on
+	contactTask := ActionContext{Mode: ModeTask, Task: &tasks.Task{ID: 2, Contact: "111@s.whatsapp.net"}}
+	if r := NewReactAction(get).Review(contactTask, json.RawMessage(`{"message_id": "A", "emoji": "👍"}`)); r.Target != "111@s.whatsapp.net" {
+		t.Errorf("review target = %q, want the task contact", r.Target)
+	}
+	behavior := ActionContext{Mode: ModeBehavior, ChatJID: "444@s.whatsapp.net"}
+	if r := NewDeleteMessageAction(get).Review(behavior, json.RawMessage(`{"message_id": "A"}`)); r.Target != "444@s.whatsapp.net" {
+		t.Errorf("review target = %q, want the behavior chat", r.Target)
+	}
+}
+
+type fakePolls struct{}
+
+func (fakePolls) SendPoll(chat, question string, options []string, multi bool) (string, error) {
+	return "3EB0P1", nil
+}
+func (fakePolls) PollResults(chat, pollID string, limit int) ([]string, error) {
+	return nil, nil
+}
+
+func TestSendPollAction(t *testing.T) {
+	var outputs []string
+	action := &SendPollAction{Polls: func() Polls { return fakePolls{} }}
+	ctx := ActionContext{Mode: ModeTask, Task: &tasks.Task{ID: 1, Contact: "111@s.whatsapp.net"}, ToolOutputs: &outputs}
+	payload := json.RawMessage(`{"question": "¿Qué día?", "options": ["viernes", "sábado"]}`)
+
+	if r := action.Review(ctx, payload); r.Target != "111@s.whatsapp.net" {
+		t.Errorf("review target = %q, want the task contact", r.Target)
+	}
+	if err := action.Execute(ctx, payload); err != nil {
+		t.Fatalf("send_poll failed: %v", err)
+	}
+	if len(outputs) != 1 || !strings.HasPrefix(outputs[0], "[send_poll] Sent poll #3EB0P1") {
+		t.Errorf("unexpected tool outputs %v", outputs)
+	}
+}
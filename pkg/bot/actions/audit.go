@@ -0,0 +1,94 @@
+package actions
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+	"whatsabladerunner/pkg/audit"
+)
+
+// maxAuditEntries bounds what the audit action feeds back to the LLM
+const maxAuditEntries = 30
+
+// AuditAction queries the action audit log
+type AuditAction struct {
+	Log *audit.Log
+}
+
+func (a *AuditAction) GetSchema() ActionSchema {
+	return ActionSchema{
+		Name:        "audit",
+		Description: "Look up what Blady did: the recorded actions (messages sent, tool calls, blocks) filtered by chat, task, behavior, action and time. Use it to answer questions like 'what did you do in chat X yesterday?'.",
+		Parameters: json.RawMessage(`{
+			"type": "object",
+			"properties": {
+				"chat": {"type": "string", "description": "Chat JID or number."},
+				"task_id": {"type": "integer"},
+				"behavior_id": {"type": "integer"},
+				"action": {"type": "string", "description": "Action type, e.g. response."},
+				"status": {"type": "string", "enum": ["executed", "failed", "denied", "blocked", "shadowed"]},
+				"day": {"type": "string", "description": "today, yesterday or YYYY-MM-DD."},
+				"since": {"type": "string", "description": "A duration ago (24h, 3d) or a date. Use instead of day."},
+				"until": {"type": "string"},
+				"limit": {"type": "integer", "description": "Defaults to 20."}
+			}
+		}`),
+	}
+}
+
+func (a *AuditAction) Execute(ctx ActionContext, payload json.RawMessage) error {
+	var input struct {
+		Chat       string `json:"chat"`
+		TaskID     int    `json:"task_id"`
+		BehaviorID int    `json:"behavior_id"`
+		Action     string `json:"action"`
+		Status     string `json:"status"`
+		Day        string `json:"day"`
+		Since      string `json:"since"`
+		Until      string `json:"until"`
+		Limit      int    `json:"limit"`
+	}
+	if len(payload) > 0 && string(payload) != "null" && string(payload) != `""` {
+		if err := unmarshalObjectPayload(payload, &input); err != nil {
+			return fmt.Errorf("invalid payload for audit: %w", err)
+		}
+	}
+	if a.Log == nil {
+		return fmt.Errorf("audit log not available")
+	}
+
+	q := audit.Query{
+		ChatJID:    input.Chat,
+		TaskID:     input.TaskID,
+		BehaviorID: input.BehaviorID,
+		Action:     input.Action,
+		Status:     input.Status,
+		Limit:      input.Limit,
+	}
+	if q.Limit <= 0 {
+		q.Limit = 20
+	}
+	q.Limit = min(q.Limit, maxAuditEntries)
+	if err := q.SetRange(input.Day, input.Since, input.Until, time.Now()); err != nil {
+		return err
+	}
+	entries, err := a.Log.Search(q)
+	if err != nil {
+		return err
+	}
+
+	var sb strings.Builder
+	if len(entries) == 0 {
+		sb.WriteString("Audit: no recorded actions match.")
+	} else {
+		fmt.Fprintf(&sb, "Audit: %d recorded actions, oldest first:", len(entries))
+		for i := len(entries) - 1; i >= 0; i-- {
+			sb.WriteString("\n" + entries[i].Format())
+		}
+	}
+	if ctx.ToolOutputs != nil {
+		*ctx.ToolOutputs = append(*ctx.ToolOutputs, sb.String())
+	}
+	return nil
+}
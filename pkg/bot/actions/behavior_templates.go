@@ -0,0 +1,360 @@
+package actions
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+	"whatsabladerunner/pkg/behaviors"
+)
+
+// PreviewFunc renders the behavior prompt for a template, failing if it, its trigger
+// (nil checks the saved one) or the schedule is invalid
+type PreviewFunc func(name, content string, trigger json.RawMessage, schedule *behaviors.Schedule, message string) (string, error)
+
+// maxPreviewOutput limits the rendered prompt returned to the LLM
+const maxPreviewOutput = 6000
+
+// --- CreateBehaviorTemplateAction ---
+
+type CreateBehaviorTemplateAction struct {
+	Templates *behaviors.TemplateStore
+	Preview   PreviewFunc
+}
+
+func (a *CreateBehaviorTemplateAction) GetSchema() ActionSchema {
+	return ActionSchema{
+		Name:        "create_behavior_template",
+		Description: "Create a new behavior template (the instructions a behavior follows). It is validated before being saved. Enable it afterwards with enable_behavior.",
+		Parameters: json.RawMessage(`{
+			"type": "object",
+			"properties": {
+				"name": {"type": "string", "description": "Template name: lowercase letters, digits, '_' and '-'."},
+				"content": {"type": "string", "description": "The behavior instructions."},
+				"trigger": {"type": "object", "description": "Optional trigger filter: keywords, regex, media_types, senders, min_length, time_windows."}
+			},
+			"required": ["name", "content"]
+		}`),
+	}
+}
+
+func (a *CreateBehaviorTemplateAction) Execute(ctx ActionContext, payload json.RawMessage) error {
+	var input struct {
+		Name    string          `json:"name"`
+		Content string          `json:"content"`
+		Trigger json.RawMessage `json:"trigger"`
+	}
+	if err := unmarshalObjectPayload(payload, &input); err != nil {
+		return fmt.Errorf("invalid payload for create_behavior_template: %w", err)
+	}
+	if err := behaviors.ValidateTemplateName(input.Name); err != nil {
+		return err
+	}
+	if a.Templates.Exists(input.Name) {
+		return fmt.Errorf("template '%s' already exists, use edit_behavior_template", input.Name)
+	}
+	if err := validateTemplate(a.Preview, input.Name, input.Content, input.Trigger); err != nil {
+		return err
+	}
+	if err := a.Templates.SaveTemplate(input.Name, input.Content, input.Trigger); err != nil {
+		return err
+	}
+
+	if ctx.ToolOutputs != nil {
+		*ctx.ToolOutputs = append(*ctx.ToolOutputs, fmt.Sprintf("Behavior template '%s' created.", input.Name))
+	}
+	return nil
+}
+
+// --- EditBehaviorTemplateAction ---
+
+type EditBehaviorTemplateAction struct {
+	Templates *behaviors.TemplateStore
+	Preview   PreviewFunc
+}
+
+func (a *EditBehaviorTemplateAction) GetSchema() ActionSchema {
+	return ActionSchema{
+		Name:        "edit_behavior_template",
+		Description: "Revise an existing behavior template. The previous version is kept and can be restored with rollback_behavior_template. Running behaviors pick up the change on their next message.",
+		Parameters: json.RawMessage(`{
+			"type": "object",
+			"properties": {
+				"name": {"type": "string"},
+				"content": {"type": "string", "description": "Optional. Replaces the whole template text."},
+				"append": {"type": "string", "description": "Optional. Text added at the end of the template."},
+				"trigger": {"type": ["object", "null"], "description": "Optional. Replaces the trigger filter, null removes it."}
+			},
+			"required": ["name"]
+		}`),
+	}
+}
+
+func (a *EditBehaviorTemplateAction) Execute(ctx ActionContext, payload json.RawMessage) error {
+	var input struct {
+		Name    string          `json:"name"`
+		Content *string         `json:"content"`
+		Append  string          `json:"append"`
+		Trigger json.RawMessage `json:"trigger"`
+	}
+	if err := unmarshalObjectPayload(payload, &input); err != nil {
+		return fmt.Errorf("invalid payload for edit_behavior_template: %w", err)
+	}
+
+	current, _, err := a.Templates.ReadTemplate(input.Name)
+	if err != nil {
+		return err
+	}
+	if input.Content == nil && input.Append == "" && input.Trigger == nil {
+		return fmt.Errorf("nothing to change: give content, append or trigger")
+	}
+
+	content := current
+	if input.Content != nil {
+		content = *input.Content
+	}
+	if input.Append != "" {
+		content = strings.TrimRight(content, "\n") + "\n" + input.Append
+	}
+
+	if err := validateTemplate(a.Preview, input.Name, content, input.Trigger); err != nil {
+		return err
+	}
+	if err := a.Templates.SaveTemplate(input.Name, content, input.Trigger); err != nil {
+		return err
+	}
+
+	if ctx.ToolOutputs != nil {
+		*ctx.ToolOutputs = append(*ctx.ToolOutputs, fmt.Sprintf("Behavior template '%s' updated (%d bytes).", input.Name, len(content)))
+	}
+	return nil
+}
+
+// --- PreviewBehaviorTemplateAction ---
+
+type PreviewBehaviorTemplateAction struct {
+	Templates *behaviors.TemplateStore
+	Preview   PreviewFunc
+}
+
+func (a *PreviewBehaviorTemplateAction) GetSchema() ActionSchema {
+	return ActionSchema{
+		Name:        "preview_behavior_template",
+		Description: "Show a behavior template: its text, trigger, saved versions and the prompt it renders for a sample message. Give content to preview a draft without saving it. Results are returned to you.",
+		Parameters: json.RawMessage(`{
+			"type": "object",
+			"properties": {
+				"name": {"type": "string"},
+				"content": {"type": "string", "description": "Optional. Draft text to preview instead of the saved one."},
+				"trigger": {"type": ["object", "null"], "description": "Optional. Draft trigger filter to check instead of the saved one."},
+				"schedule": {"type": "object", "description": "Optional. Schedule to check (timezone, active_hours, inactive_hours, holidays, start_date, end_date)."},
+				"message": {"type": "string", "description": "Optional. Sample incoming message."}
+			},
+			"required": ["name"]
+		}`),
+	}
+}
+
+func (a *PreviewBehaviorTemplateAction) Execute(ctx ActionContext, payload json.RawMessage) error {
+	var input struct {
+		Name     string              `json:"name"`
+		Content  string              `json:"content"`
+		Trigger  json.RawMessage     `json:"trigger"`
+		Schedule *behaviors.Schedule `json:"schedule"`
+		Message  string              `json:"message"`
+	}
+	if err := unmarshalObjectPayload(payload, &input); err != nil {
+		return fmt.Errorf("invalid payload for preview_behavior_template: %w", err)
+	}
+	if err := behaviors.ValidateTemplateName(input.Name); err != nil {
+		return err
+	}
+
+	var sb strings.Builder
+	content := input.Content
+	if content == "" {
+		saved, trigger, err := a.Templates.ReadTemplate(input.Name)
+		if err != nil {
+			return err
+		}
+		content = saved
+		sb.WriteString(fmt.Sprintf("[preview_behavior_template] Template '%s':\n%s\n", input.Name, saved))
+		if trigger != "" {
+			sb.WriteString(fmt.Sprintf("Trigger: %s\n", trigger))
+		}
+	} else {
+		sb.WriteString(fmt.Sprintf("[preview_behavior_template] Draft for '%s' (not saved).\n", input.Name))
+	}
+
+	versions, err := a.Templates.Versions(input.Name)
+	if err != nil {
+		return err
+	}
+	sb.WriteString(formatTemplateVersions(versions))
+
+	if a.Preview != nil {
+		rendered, err := a.Preview(input.Name, content, input.Trigger, input.Schedule, input.Message)
+		if err != nil {
+			sb.WriteString(fmt.Sprintf("\nValidation FAILED: %v\n", err))
+		} else {
+			if len(rendered) > maxPreviewOutput {
+				rendered = rendered[:maxPreviewOutput] + "\n...(truncated)"
+			}
+			sb.WriteString("\nValidation OK. Rendered prompt:\n")
+			sb.WriteString(rendered)
+		}
+	}
+
+	if ctx.ToolOutputs != nil {
+		*ctx.ToolOutputs = append(*ctx.ToolOutputs, sb.String())
+	}
+	return nil
+}
+
+// --- DeleteBehaviorTemplateAction ---
+
+type DeleteBehaviorTemplateAction struct {
+	Templates *behaviors.TemplateStore
+}
+
+func (a *DeleteBehaviorTemplateAction) GetSchema() ActionSchema {
+	return ActionSchema{
+		Name:        "delete_behavior_template",
+		Description: "Delete a behavior template. Refused while behaviors using it are enabled. A copy is kept in its version history. ONLY BY USER REQUEST.",
+		Parameters:  json.RawMessage(`{"type": "string", "description": "The template name."}`),
+	}
+}
+
+func (a *DeleteBehaviorTemplateAction) Execute(ctx ActionContext, payload json.RawMessage) error {
+	var name string
+	if err := json.Unmarshal(payload, &name); err != nil {
+		var input struct {
+			Name string `json:"name"`
+		}
+		if err := json.Unmarshal(payload, &input); err != nil {
+			return fmt.Errorf("invalid payload for delete_behavior_template: %w", err)
+		}
+		name = input.Name
+	}
+	name = strings.TrimSpace(name)
+
+	if ctx.BehaviorManager != nil {
+		list, err := ctx.BehaviorManager.ListBehaviors(false)
+		if err != nil {
+			return err
+		}
+		var inUse []string
+		for _, b := range list {
+			if b.Name == name && b.Status == behaviors.StatusEnabled {
+				inUse = append(inUse, fmt.Sprintf("#%d", b.ID))
+			}
+		}
+		if len(inUse) > 0 {
+			return fmt.Errorf("template '%s' is used by enabled behaviors %s, disable or archive them first", name, strings.Join(inUse, ", "))
+		}
+	}
+
+	if err := a.Templates.DeleteTemplate(name); err != nil {
+		return err
+	}
+	if ctx.ToolOutputs != nil {
+		*ctx.ToolOutputs = append(*ctx.ToolOutputs, fmt.Sprintf("Behavior template '%s' deleted (restorable with rollback_behavior_template).", name))
+	}
+	return nil
+}
+
+// --- RollbackBehaviorTemplateAction ---
+
+type RollbackBehaviorTemplateAction struct {
+	Templates *behaviors.TemplateStore
+	Preview   PreviewFunc
+}
+
+func (a *RollbackBehaviorTemplateAction) GetSchema() ActionSchema {
+	return ActionSchema{
+		Name:        "rollback_behavior_template",
+		Description: "Restore a previous version of a behavior template (also restores deleted templates). Versions are listed by preview_behavior_template.",
+		Parameters: json.RawMessage(`{
+			"type": "object",
+			"properties": {
+				"name": {"type": "string"},
+				"version": {"type": "integer", "description": "Optional. Defaults to the latest saved version."}
+			},
+			"required": ["name"]
+		}`),
+	}
+}
+
+func (a *RollbackBehaviorTemplateAction) Execute(ctx ActionContext, payload json.RawMessage) error {
+	var input struct {
+		Name    string `json:"name"`
+		Version int    `json:"version"`
+	}
+	if err := unmarshalObjectPayload(payload, &input); err != nil {
+		return fmt.Errorf("invalid payload for rollback_behavior_template: %w", err)
+	}
+
+	if input.Version == 0 {
+		versions, err := a.Templates.Versions(input.Name)
+		if err != nil {
+			return err
+		}
+		if len(versions) == 0 {
+			return fmt.Errorf("template '%s' has no saved versions", input.Name)
+		}
+		input.Version = versions[0].Version
+	}
+
+	content, trigger, err := a.Templates.ReadVersion(input.Name, input.Version)
+	if err != nil {
+		return err
+	}
+	if a.Preview != nil {
+		if _, err := a.Preview(input.Name, content, versionTrigger(trigger), nil, ""); err != nil {
+			return fmt.Errorf("version %d does not render: %w", input.Version, err)
+		}
+	}
+	if err := a.Templates.Rollback(input.Name, input.Version); err != nil {
+		return err
+	}
+
+	if ctx.ToolOutputs != nil {
+		*ctx.ToolOutputs = append(*ctx.ToolOutputs, fmt.Sprintf("Behavior template '%s' restored to version %d.", input.Name, input.Version))
+	}
+	return nil
+}
+
+// validateTemplate checks the content and trigger and renders the template with sample data
+func validateTemplate(preview PreviewFunc, name, content string, trigger json.RawMessage) error {
+	if err := behaviors.CheckTemplate(content, trigger); err != nil {
+		return err
+	}
+	if preview == nil {
+		return nil
+	}
+	if _, err := preview(name, content, trigger, nil, ""); err != nil {
+		return fmt.Errorf("template validation failed: %w", err)
+	}
+	return nil
+}
+
+// versionTrigger is the trigger of a saved version for PreviewFunc, JSON null when it had none
+func versionTrigger(trigger string) json.RawMessage {
+	if trigger == "" {
+		return json.RawMessage("null")
+	}
+	return json.RawMessage(trigger)
+}
+
+// formatTemplateVersions lists saved versions for the LLM
+func formatTemplateVersions(versions []behaviors.TemplateVersion) string {
+	if len(versions) == 0 {
+		return "Versions: none saved.\n"
+	}
+	var sb strings.Builder
+	sb.WriteString("Versions (newest first):\n")
+	for _, v := range versions {
+		sb.WriteString(fmt.Sprintf("- v%d %s before %s (%d bytes)\n", v.Version, time.Unix(v.Timestamp, 0).Format("2006-01-02 15:04"), v.Reason, v.Size))
+	}
+	return sb.String()
+}
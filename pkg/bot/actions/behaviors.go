@@ -0,0 +1,307 @@
+package actions
+
+import (
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+	"whatsabladerunner/pkg/behaviors"
+)
+
+// --- ListBehaviorsAction ---
+
+type ListBehaviorsAction struct{}
+
+func (a *ListBehaviorsAction) GetSchema() ActionSchema {
+	return ActionSchema{
+		Name:        "list_behaviors",
+		Description: "List behavior instances with their status, schedule and comments. Results are returned to you.",
+		Parameters: json.RawMessage(`{
+			"type": "object",
+			"properties": {
+				"contact": {"type": "string", "description": "Optional. Only behaviors for this contact JID."},
+				"status": {"type": "string", "enum": ["enabled", "disabled", "archived", "all"], "description": "Optional. Defaults to all non archived."}
+			}
+		}`),
+	}
+}
+
+func (a *ListBehaviorsAction) Execute(ctx ActionContext, payload json.RawMessage) error {
+	if ctx.BehaviorManager == nil {
+		return fmt.Errorf("behavior manager not available in context")
+	}
+
+	var input struct {
+		Contact string `json:"contact"`
+		Status  string `json:"status"`
+	}
+	if err := unmarshalObjectPayload(payload, &input); err != nil {
+		return fmt.Errorf("invalid payload for list_behaviors: %w", err)
+	}
+
+	includeArchived := input.Status == "archived" || input.Status == "all"
+	list, err := ctx.BehaviorManager.ListBehaviors(includeArchived)
+	if err != nil {
+		return err
+	}
+
+	archivedIDs := map[int]bool{}
+	if includeArchived {
+		active, err := ctx.BehaviorManager.ListBehaviors(false)
+		if err != nil {
+			return err
+		}
+		for _, b := range list {
+			archivedIDs[b.ID] = true
+		}
+		for _, b := range active {
+			delete(archivedIDs, b.ID)
+		}
+	}
+
+	var lines []string
+	for _, b := range list {
+		if input.Contact != "" && !targetsContact(b, input.Contact) {
+			continue
+		}
+		status := b.Status
+		if archivedIDs[b.ID] {
+			status = "archived"
+		}
+		if input.Status != "" && input.Status != "all" && input.Status != status {
+			continue
+		}
+		line := fmt.Sprintf("- #%d %s for %s [%s] created %s", b.ID, b.Name, strings.Join(b.AllTargets(), ", "), status, time.Unix(b.Timestamp, 0).Format("2006-01-02"))
+		if len(b.Exclude) > 0 {
+			line += fmt.Sprintf(" exclude=%s", strings.Join(b.Exclude, ", "))
+		}
+		if b.Schedule != nil {
+			scheduleJSON, _ := json.Marshal(b.Schedule)
+			line += fmt.Sprintf(" schedule=%s", string(scheduleJSON))
+		}
+		if b.Comments != "" {
+			line += fmt.Sprintf(" comments=%q", b.Comments)
+		}
+		lines = append(lines, line)
+	}
+
+	output := "[list_behaviors] No behaviors found."
+	if len(lines) > 0 {
+		output = "[list_behaviors] Behaviors:\n" + strings.Join(lines, "\n")
+	}
+	if ctx.ToolOutputs != nil {
+		*ctx.ToolOutputs = append(*ctx.ToolOutputs, output)
+	}
+	return nil
+}
+
+// --- UpdateBehaviorAction ---
+
+type UpdateBehaviorAction struct{}
+
+func (a *UpdateBehaviorAction) GetSchema() ActionSchema {
+	return ActionSchema{
+		Name:        "update_behavior",
+		Description: "Update a behavior instance: comments, contact, targets, exclude, status (enabled/disabled to re-enable or pause), schedule (same format as enable_behavior, null removes it), allowed actions (empty list removes the restriction) or shadow (false takes a shadowed behavior live). Only given fields change.",
+		Parameters: json.RawMessage(`{
+			"type": "object",
+			"properties": {
+				"id": {"type": "string", "description": "The Behavior ID."},
+				"contact": {"type": "string"},
+				"targets": {"type": "array", "items": {"type": "string"}, "description": "Replaces the extra targets."},
+				"exclude": {"type": "array", "items": {"type": "string"}, "description": "Replaces the exclusion list."},
+				"comments": {"type": "string"},
+				"status": {"type": "string", "enum": ["enabled", "disabled"]},
+				"schedule": {"type": ["object", "null"]},
+				"actions": {"type": "array", "items": {"type": "string"}, "description": "Replaces the allowed actions."},
+				"shadow": {"type": "boolean"}
+			},
+			"required": ["id"]
+		}`),
+	}
+}
+
+func (a *UpdateBehaviorAction) Execute(ctx ActionContext, payload json.RawMessage) error {
+	if ctx.BehaviorManager == nil {
+		return fmt.Errorf("behavior manager not available in context")
+	}
+
+	var input struct {
+		ID       json.RawMessage `json:"id"`
+		Contact  *string         `json:"contact"`
+		Targets  *[]string       `json:"targets"`
+		Exclude  *[]string       `json:"exclude"`
+		Comments *string         `json:"comments"`
+		Status   *string         `json:"status"`
+		Schedule json.RawMessage `json:"schedule"`
+		Actions  *[]string       `json:"actions"`
+		Shadow   *bool           `json:"shadow"`
+	}
+	if err := unmarshalObjectPayload(payload, &input); err != nil {
+		return fmt.Errorf("invalid payload for update_behavior: %w", err)
+	}
+
+	id, err := parseIDValue(input.ID)
+	if err != nil {
+		return fmt.Errorf("invalid behavior ID: %w", err)
+	}
+
+	update := behaviors.BehaviorUpdate{
+		Contact:  input.Contact,
+		Targets:  input.Targets,
+		Exclude:  input.Exclude,
+		Comments: input.Comments,
+		Status:   input.Status,
+		Actions:  input.Actions,
+		Shadow:   input.Shadow,
+	}
+	if input.Actions != nil {
+		if err := ValidateActionPatterns(*input.Actions); err != nil {
+			return err
+		}
+	}
+	if input.Schedule != nil {
+		if string(input.Schedule) == "null" {
+			update.ClearSchedule = true
+		} else {
+			var schedule behaviors.Schedule
+			if err := json.Unmarshal(input.Schedule, &schedule); err != nil {
+				return fmt.Errorf("invalid schedule for update_behavior: %w", err)
+			}
+			update.Schedule = &schedule
+		}
+	}
+
+	b, err := ctx.BehaviorManager.UpdateBehavior(id, update)
+	if err != nil {
+		return fmt.Errorf("failed to update behavior: %w", err)
+	}
+
+	if ctx.ToolOutputs != nil {
+		data, _ := json.Marshal(b)
+		*ctx.ToolOutputs = append(*ctx.ToolOutputs, fmt.Sprintf("Behavior %d updated: %s", b.ID, string(data)))
+	}
+	return nil
+}
+
+// --- ArchiveBehaviorAction ---
+
+type ArchiveBehaviorAction struct{}
+
+func (a *ArchiveBehaviorAction) GetSchema() ActionSchema {
+	return ActionSchema{
+		Name:        "archive_behavior",
+		Description: "Archive a behavior by ID: disables it and removes it from the active list. Its activity log is kept.",
+		Parameters:  json.RawMessage(`{"type": "string", "description": "The Behavior ID."}`),
+	}
+}
+
+func (a *ArchiveBehaviorAction) Execute(ctx ActionContext, payload json.RawMessage) error {
+	if ctx.BehaviorManager == nil {
+		return fmt.Errorf("behavior manager not available in context")
+	}
+
+	id, err := parseIDValue(payload)
+	if err != nil {
+		return fmt.Errorf("invalid payload for archive_behavior: %w", err)
+	}
+
+	if err := ctx.BehaviorManager.ArchiveBehavior(id); err != nil {
+		return err
+	}
+
+	if ctx.ToolOutputs != nil {
+		*ctx.ToolOutputs = append(*ctx.ToolOutputs, fmt.Sprintf("Behavior %d archived.", id))
+	}
+	return nil
+}
+
+// --- BehaviorActivityAction ---
+
+type BehaviorActivityAction struct{}
+
+func (a *BehaviorActivityAction) GetSchema() ActionSchema {
+	return ActionSchema{
+		Name:        "behavior_activity",
+		Description: "Show the activity log of a behavior: each run, what triggered it and the actions it executed. Results are returned to you.",
+		Parameters: json.RawMessage(`{
+			"type": "object",
+			"properties": {
+				"id": {"type": "string", "description": "The Behavior ID."},
+				"limit": {"type": "integer", "description": "Optional. Number of most recent runs (default 10)."}
+			},
+			"required": ["id"]
+		}`),
+	}
+}
+
+func (a *BehaviorActivityAction) Execute(ctx ActionContext, payload json.RawMessage) error {
+	if ctx.BehaviorManager == nil {
+		return fmt.Errorf("behavior manager not available in context")
+	}
+
+	var input struct {
+		ID    json.RawMessage `json:"id"`
+		Limit int             `json:"limit"`
+	}
+	if err := unmarshalObjectPayload(payload, &input); err != nil {
+		// Allow the bare ID as content
+		input.ID = payload
+	}
+	id, err := parseIDValue(input.ID)
+	if err != nil {
+		return fmt.Errorf("invalid behavior ID: %w", err)
+	}
+	if input.Limit <= 0 {
+		input.Limit = 10
+	}
+
+	entries, err := ctx.BehaviorManager.GetActivity(id, input.Limit)
+	if err != nil {
+		return err
+	}
+
+	if ctx.ToolOutputs != nil {
+		*ctx.ToolOutputs = append(*ctx.ToolOutputs, fmt.Sprintf("[behavior_activity] Behavior %d:\n%s", id, behaviors.FormatActivity(entries)))
+	}
+	return nil
+}
+
+// targetsContact reports whether any behavior target mentions the contact (or is the same expression)
+func targetsContact(b behaviors.Behavior, contact string) bool {
+	for _, t := range b.AllTargets() {
+		if t == contact || behaviors.MatchJID(contact, t) {
+			return true
+		}
+	}
+	return false
+}
+
+// unmarshalObjectPayload decodes an object payload, accepting stringified JSON too
+func unmarshalObjectPayload(payload json.RawMessage, v interface{}) error {
+	if len(payload) == 0 || string(payload) == "null" {
+		return nil
+	}
+	var contentStr string
+	if err := json.Unmarshal(payload, &contentStr); err == nil {
+		if strings.TrimSpace(contentStr) == "" {
+			return nil
+		}
+		return json.Unmarshal([]byte(contentStr), v)
+	}
+	return json.Unmarshal(payload, v)
+}
+
+// parseIDValue accepts an ID given as a JSON number or string
+func parseIDValue(raw json.RawMessage) (int, error) {
+	var idStr string
+	if err := json.Unmarshal(raw, &idStr); err != nil {
+		var idInt int
+		if err := json.Unmarshal(raw, &idInt); err != nil {
+			return 0, err
+		}
+		return idInt, nil
+	}
+	return strconv.Atoi(strings.TrimPrefix(strings.TrimSpace(idStr), "#"))
+}
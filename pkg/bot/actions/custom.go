@@ -16,16 +16,27 @@ import (
 type CustomActionConfig struct {
 	Name          string          `json:"name"`
 	Description   string          `json:"description"`
-	Method        string          `json:"method"` // GET, POST, PUT, DELETE
+	Type          string          `json:"type,omitempty"` // http (default) or exec
+	Method        string          `json:"method"`         // GET, POST, PUT, DELETE
 	URL           string          `json:"url"`
 	ResponseToLLM bool            `json:"response_to_llm"`
 	Parameters    json.RawMessage `json:"parameters"` // JSON Schema for the payload
+	// Headers are sent with every request; values may contain ${secret:NAME} or ${env:NAME}
+	Headers map[string]string `json:"headers,omitempty"`
+	Auth    *CustomAuth       `json:"auth,omitempty"`
+	Signing *CustomSigning    `json:"signing,omitempty"`
+	// Response shapes the result; when set, the result always goes somewhere (see To)
+	Response *CustomResponse `json:"response,omitempty"`
+	// Exec configures exec actions (type "exec"), which ignore method, url and the HTTP options
+	Exec *CustomExec `json:"exec,omitempty"`
 }
 
 // CustomAction implements the Action interface for user-defined actions
 type CustomAction struct {
-	Config CustomActionConfig
-	Client *http.Client
+	Config     CustomActionConfig
+	Client     *http.Client
+	Secrets    ActionSecrets
+	SendMaster func(string) // For results sent to the master
 }
 
 func (a *CustomAction) GetSchema() ActionSchema {
@@ -36,15 +47,48 @@ func (a *CustomAction) GetSchema() ActionSchema {
 	}
 }
 
+// Review renders the outgoing request. HTTP actions always reach an external service;
+// local programs count as external when running for a third party conversation.
+func (a *CustomAction) Review(ctx ActionContext, payload json.RawMessage) Review {
+	if a.Config.Type == CustomKindExec {
+		command := strings.Join(a.Config.Exec.Command, " ")
+		return Review{
+			Summary:  fmt.Sprintf("run `%s` with input %s", command, string(payload)),
+			Target:   "local program " + a.Config.Exec.Command[0],
+			External: ctx.Mode != ModeCommand,
+		}
+	}
+	method := strings.ToUpper(a.Config.Method)
+	if method == "" {
+		method = "POST"
+	}
+	return Review{
+		Summary:  fmt.Sprintf("%s %s with payload %s", method, a.Config.URL, string(payload)),
+		Target:   a.Config.URL,
+		External: true,
+	}
+}
+
 func (a *CustomAction) Execute(ctx ActionContext, payload json.RawMessage) error {
+	if a.Config.Type == CustomKindExec {
+		return a.execute(ctx, payload)
+	}
+
 	// The payload is the JSON object generated by the LLM
 	var data map[string]interface{}
 	if err := json.Unmarshal(payload, &data); err != nil {
 		return fmt.Errorf("failed to parse payload for action %s: %w", a.Config.Name, err)
 	}
 
+	// Secret values used by this request, hidden from logs and the LLM.
+	// Only the configured URL is resolved, never values coming from the LLM.
+	var used []string
+	finalURL, err := a.resolveSecrets(a.Config.URL, &used)
+	if err != nil {
+		return fmt.Errorf("action %s: %w", a.Config.Name, err)
+	}
+
 	// Prepare URL with template replacement
-	finalURL := a.Config.URL
 	remainingData := make(map[string]interface{})
 	for k, v := range data {
 		placeholder := "{" + k + "}"
@@ -61,9 +105,10 @@ func (a *CustomAction) Execute(ctx ActionContext, payload json.RawMessage) error
 	}
 
 	var bodyReader io.Reader
+	var bodyBytes []byte // Kept for request signing
 	if method == "POST" || method == "PUT" || method == "PATCH" {
 		if len(remainingData) > 0 {
-			bodyBytes, err := json.Marshal(remainingData)
+			bodyBytes, err = json.Marshal(remainingData)
 			if err != nil {
 				return fmt.Errorf("failed to marshal remaining payload for action %s: %w", a.Config.Name, err)
 			}
@@ -92,17 +137,20 @@ func (a *CustomAction) Execute(ctx ActionContext, payload json.RawMessage) error
 	// Create request
 	req, err := http.NewRequest(method, finalURL, bodyReader)
 	if err != nil {
-		return fmt.Errorf("failed to create request for action %s: %w", a.Config.Name, err)
+		return fmt.Errorf("failed to create request for action %s: %s", a.Config.Name, redactSecrets(err.Error(), used))
 	}
 	if bodyReader != nil {
 		req.Header.Set("Content-Type", "application/json")
 	}
 	req.Header.Set("User-Agent", "WhatsABladeRunner/1.0")
+	if err := a.authorize(req, bodyBytes, &used); err != nil {
+		return fmt.Errorf("action %s: %w", a.Config.Name, err)
+	}
 
 	// Execute request
 	resp, err := client.Do(req)
 	if err != nil {
-		return fmt.Errorf("failed to execute action %s: %w", a.Config.Name, err)
+		return fmt.Errorf("failed to execute action %s: %s", a.Config.Name, redactSecrets(err.Error(), used))
 	}
 	defer resp.Body.Close()
 
@@ -111,25 +159,61 @@ func (a *CustomAction) Execute(ctx ActionContext, payload json.RawMessage) error
 	if err != nil {
 		return fmt.Errorf("failed to read response body for action %s: %w", a.Config.Name, err)
 	}
+	bodyText := redactSecrets(string(body), used)
 
 	// Check status code
 	if resp.StatusCode >= 400 {
-		fmt.Printf("Action %s returned status %d: %s\n", a.Config.Name, resp.StatusCode, string(body))
+		fmt.Printf("Action %s returned status %d: %s\n", a.Config.Name, resp.StatusCode, bodyText)
 	} else {
 		fmt.Printf("Action %s executed successfully (Status %d)\n", a.Config.Name, resp.StatusCode)
 	}
 
-	// If configured to return response to LLM
-	if a.Config.ResponseToLLM && ctx.ToolOutputs != nil {
-		output := fmt.Sprintf("Action '%s' response (Status %d):\n%s", a.Config.Name, resp.StatusCode, string(body))
-		*ctx.ToolOutputs = append(*ctx.ToolOutputs, output)
+	a.deliver(ctx, resp.StatusCode, resp.StatusCode >= 400, fmt.Sprintf("Status %d", resp.StatusCode), bodyText)
+	return nil
+}
+
+// deliver shapes the result and hands it to the LLM and/or the master.
+// label describes the outcome, e.g. "Status 200" or "exit code 1".
+func (a *CustomAction) deliver(ctx ActionContext, status int, failed bool, label, body string) {
+	shaping := a.Config.Response
+	toLLM, toMaster := a.Config.ResponseToLLM, false
+	maxBytes := DefaultMaxResponseBytes
+	if shaping != nil {
+		toLLM = shaping.To == "" || shaping.To == ResponseToLLM || shaping.To == ResponseToBoth
+		toMaster = shaping.To == ResponseToMaster || shaping.To == ResponseToBoth
+		if shaping.MaxBytes > 0 {
+			maxBytes = shaping.MaxBytes
+		}
+	}
+	if !toLLM && !toMaster {
+		return
 	}
 
-	return nil
+	result := body
+	if shaping != nil {
+		shaped, err := shaping.shape(status, failed, body)
+		if err != nil {
+			fmt.Printf("Action %s: %v\n", a.Config.Name, err)
+			shaped = fmt.Sprintf("Could not shape the response (%v). Raw response:\n%s", err, body)
+		}
+		result = shaped
+	}
+	result = truncate(result, maxBytes)
+
+	if toMaster && a.SendMaster != nil {
+		a.SendMaster(fmt.Sprintf("[Blady][%s] : %s", a.Config.Name, result))
+	}
+	if toLLM && ctx.ToolOutputs != nil {
+		output := fmt.Sprintf("Action '%s' response (%s):\n%s", a.Config.Name, label, result)
+		if toMaster {
+			output = fmt.Sprintf("Action '%s' response (%s) was sent to the master:\n%s", a.Config.Name, label, result)
+		}
+		*ctx.ToolOutputs = append(*ctx.ToolOutputs, output)
+	}
 }
 
 // LoadCustomActions scans the given directory for .json files and registers them
-func LoadCustomActions(dir string, registry *Registry) error {
+func LoadCustomActions(dir string, registry *Registry, sendMaster func(string)) error {
 	// Check if directory exists
 	if _, err := os.Stat(dir); os.IsNotExist(err) {
 		// optional directory, just return
@@ -141,6 +225,12 @@ func LoadCustomActions(dir string, registry *Registry) error {
 		return fmt.Errorf("failed to read custom actions directory: %w", err)
 	}
 
+	secrets, err := LoadActionSecrets(filepath.Join(dir, "secrets.env"))
+	if err != nil {
+		fmt.Printf("Warning: %v\n", err)
+		secrets = ActionSecrets{}
+	}
+
 	for _, entry := range entries {
 		if entry.IsDir() || !strings.HasSuffix(entry.Name(), ".json") {
 			continue
@@ -160,10 +250,37 @@ func LoadCustomActions(dir string, registry *Registry) error {
 		}
 
 		// Validation
-		if config.Name == "" || config.URL == "" {
-			fmt.Printf("Warning: custom action %s missing name or url\n", entry.Name())
+		switch config.Type {
+		case "", CustomKindHTTP:
+			config.Type = CustomKindHTTP
+			if config.Name == "" || config.URL == "" {
+				fmt.Printf("Warning: custom action %s missing name or url\n", entry.Name())
+				continue
+			}
+		case CustomKindExec:
+			if config.Name == "" || config.Exec == nil {
+				fmt.Printf("Warning: custom action %s missing name or exec\n", entry.Name())
+				continue
+			}
+			if err := config.Exec.validate(); err != nil {
+				fmt.Printf("Warning: custom action %s: %v\n", entry.Name(), err)
+				continue
+			}
+		default:
+			fmt.Printf("Warning: custom action %s has unknown type '%s'\n", entry.Name(), config.Type)
+			continue
+		}
+
+		if err := config.validateAuth(); err != nil {
+			fmt.Printf("Warning: custom action %s: %v\n", entry.Name(), err)
 			continue
 		}
+		if config.Response != nil {
+			if err := config.Response.validate(); err != nil {
+				fmt.Printf("Warning: custom action %s: %v\n", entry.Name(), err)
+				continue
+			}
+		}
 
 		// Default method to POST
 		if config.Method == "" {
@@ -176,12 +293,18 @@ func LoadCustomActions(dir string, registry *Registry) error {
 		}
 
 		action := &CustomAction{
-			Config: config,
-			Client: &http.Client{Timeout: 30 * time.Second},
+			Config:     config,
+			Client:     &http.Client{Timeout: 30 * time.Second},
+			Secrets:    secrets,
+			SendMaster: sendMaster,
 		}
 
 		registry.Register(action)
-		fmt.Printf("Registered custom action: %s (%s)\n", config.Name, config.Method)
+		if config.Type == CustomKindExec {
+			fmt.Printf("Registered custom action: %s (exec %s)\n", config.Name, config.Exec.Command[0])
+		} else {
+			fmt.Printf("Registered custom action: %s (%s)\n", config.Name, config.Method)
+		}
 	}
 
 	return nil
@@ -0,0 +1,245 @@
+package actions
+
+import (
+	"bufio"
+	"crypto/hmac"
+	"crypto/sha1"
+	"crypto/sha256"
+	"crypto/sha512"
+	"encoding/base64"
+	"encoding/hex"
+	"fmt"
+	"hash"
+	"net/http"
+	"net/url"
+	"os"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// CustomAuth configures the authentication of a custom action.
+// Credentials must be secret references: ${secret:NAME} or ${env:NAME}.
+type CustomAuth struct {
+	Type     string `json:"type"`               // bearer, basic or api_key
+	Token    string `json:"token,omitempty"`    // bearer token or API key
+	Username string `json:"username,omitempty"` // basic
+	Password string `json:"password,omitempty"` // basic
+	Header   string `json:"header,omitempty"`   // api_key header, defaults to X-API-Key
+	Query    string `json:"query,omitempty"`    // api_key query parameter, instead of the header
+}
+
+// CustomSigning configures HMAC signing of the request body
+type CustomSigning struct {
+	Secret          string `json:"secret"`                     // Secret reference
+	Algorithm       string `json:"algorithm,omitempty"`        // sha256 (default), sha1 or sha512
+	Header          string `json:"header,omitempty"`           // Defaults to X-Signature
+	Encoding        string `json:"encoding,omitempty"`         // hex (default) or base64
+	Prefix          string `json:"prefix,omitempty"`           // Prepended to the signature, e.g. "sha256="
+	TimestampHeader string `json:"timestamp_header,omitempty"` // If set, the unix time is sent and signed as "<timestamp>.<body>"
+}
+
+// ActionSecrets holds the named secrets of config/actions/secrets.env
+type ActionSecrets map[string]string
+
+var secretRefRe = regexp.MustCompile(`\$\{(secret|env):([A-Za-z0-9_.-]+)\}`)
+
+// LoadActionSecrets reads NAME=value lines. A missing file means no secrets.
+func LoadActionSecrets(path string) (ActionSecrets, error) {
+	secrets := ActionSecrets{}
+	f, err := os.Open(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return secrets, nil
+		}
+		return nil, fmt.Errorf("failed to read action secrets: %w", err)
+	}
+	defer f.Close()
+
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		name, value, ok := strings.Cut(line, "=")
+		if !ok {
+			continue
+		}
+		value = strings.TrimSpace(value)
+		if unquoted, err := strconv.Unquote(value); err == nil {
+			value = unquoted
+		}
+		secrets[strings.TrimSpace(name)] = value
+	}
+	return secrets, scanner.Err()
+}
+
+// isSecretRef reports whether a value is exactly one secret reference
+func isSecretRef(value string) bool {
+	loc := secretRefRe.FindStringIndex(value)
+	return loc != nil && loc[0] == 0 && loc[1] == len(value)
+}
+
+// resolveSecrets replaces the secret references of a value. Resolved values
+// are added to used so they can be redacted later.
+func (a *CustomAction) resolveSecrets(value string, used *[]string) (string, error) {
+	var missing string
+	resolved := secretRefRe.ReplaceAllStringFunc(value, func(ref string) string {
+		m := secretRefRe.FindStringSubmatch(ref)
+		var v string
+		var ok bool
+		if m[1] == "env" {
+			v, ok = os.LookupEnv(m[2])
+		} else {
+			v, ok = a.Secrets[m[2]]
+		}
+		if !ok || v == "" {
+			missing = ref
+			return ""
+		}
+		*used = append(*used, v)
+		return v
+	})
+	if missing != "" {
+		return "", fmt.Errorf("secret %s is not set", missing)
+	}
+	return resolved, nil
+}
+
+// validateAuth checks that credentials are references and not inline values
+func (c *CustomActionConfig) validateAuth() error {
+	if c.Auth != nil {
+		switch c.Auth.Type {
+		case "bearer", "api_key":
+			if !isSecretRef(c.Auth.Token) {
+				return fmt.Errorf("auth token must be a ${secret:NAME} or ${env:NAME} reference")
+			}
+		case "basic":
+			if c.Auth.Username == "" || !isSecretRef(c.Auth.Password) {
+				return fmt.Errorf("basic auth needs a username and a password reference")
+			}
+		default:
+			return fmt.Errorf("unknown auth type '%s'", c.Auth.Type)
+		}
+	}
+	if c.Signing != nil {
+		if !isSecretRef(c.Signing.Secret) {
+			return fmt.Errorf("signing secret must be a ${secret:NAME} or ${env:NAME} reference")
+		}
+		if _, err := signingHash(c.Signing.Algorithm); err != nil {
+			return err
+		}
+		if c.Signing.Encoding != "" && c.Signing.Encoding != "hex" && c.Signing.Encoding != "base64" {
+			return fmt.Errorf("unknown signing encoding '%s'", c.Signing.Encoding)
+		}
+	}
+	return nil
+}
+
+func signingHash(algorithm string) (func() hash.Hash, error) {
+	switch algorithm {
+	case "", "sha256":
+		return sha256.New, nil
+	case "sha1":
+		return sha1.New, nil
+	case "sha512":
+		return sha512.New, nil
+	}
+	return nil, fmt.Errorf("unknown signing algorithm '%s'", algorithm)
+}
+
+// authorize adds the configured headers, auth and signature to the request
+func (a *CustomAction) authorize(req *http.Request, body []byte, used *[]string) error {
+	for name, value := range a.Config.Headers {
+		resolved, err := a.resolveSecrets(value, used)
+		if err != nil {
+			return fmt.Errorf("header %s: %w", name, err)
+		}
+		req.Header.Set(name, resolved)
+	}
+
+	if auth := a.Config.Auth; auth != nil {
+		switch auth.Type {
+		case "bearer":
+			token, err := a.resolveSecrets(auth.Token, used)
+			if err != nil {
+				return err
+			}
+			req.Header.Set("Authorization", "Bearer "+token)
+		case "basic":
+			user, err := a.resolveSecrets(auth.Username, used)
+			if err != nil {
+				return err
+			}
+			password, err := a.resolveSecrets(auth.Password, used)
+			if err != nil {
+				return err
+			}
+			req.SetBasicAuth(user, password)
+		case "api_key":
+			key, err := a.resolveSecrets(auth.Token, used)
+			if err != nil {
+				return err
+			}
+			if auth.Query != "" {
+				q := req.URL.Query()
+				q.Set(auth.Query, key)
+				req.URL.RawQuery = q.Encode()
+			} else {
+				header := auth.Header
+				if header == "" {
+					header = "X-API-Key"
+				}
+				req.Header.Set(header, key)
+			}
+		}
+	}
+
+	if signing := a.Config.Signing; signing != nil {
+		secret, err := a.resolveSecrets(signing.Secret, used)
+		if err != nil {
+			return err
+		}
+		newHash, err := signingHash(signing.Algorithm)
+		if err != nil {
+			return err
+		}
+		mac := hmac.New(newHash, []byte(secret))
+		if signing.TimestampHeader != "" {
+			ts := strconv.FormatInt(time.Now().Unix(), 10)
+			req.Header.Set(signing.TimestampHeader, ts)
+			mac.Write([]byte(ts + "."))
+		}
+		mac.Write(body)
+		var signature string
+		if signing.Encoding == "base64" {
+			signature = base64.StdEncoding.EncodeToString(mac.Sum(nil))
+		} else {
+			signature = hex.EncodeToString(mac.Sum(nil))
+		}
+		header := signing.Header
+		if header == "" {
+			header = "X-Signature"
+		}
+		req.Header.Set(header, signing.Prefix+signature)
+	}
+	return nil
+}
+
+// redactSecrets hides the resolved secret values in text meant for logs or the LLM
+func redactSecrets(text string, used []string) string {
+	for _, secret := range used {
+		if len(secret) < 4 {
+			continue
+		}
+		text = strings.ReplaceAll(text, secret, "[REDACTED]")
+		for _, encoded := range []string{strings.Trim(strconv.Quote(secret), `"`), url.QueryEscape(secret)} {
+			if encoded != secret {
+				text = strings.ReplaceAll(text, encoded, "[REDACTED]")
+			}
+		}
+	}
+	return text
+}
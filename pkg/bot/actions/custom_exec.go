@@ -0,0 +1,165 @@
+package actions
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Custom action kinds
+const (
+	CustomKindHTTP = "http"
+	CustomKindExec = "exec"
+)
+
+// DefaultExecTimeout is used when an exec action sets no timeout
+const DefaultExecTimeout = 30 * time.Second
+
+// maxExecOutput bounds what is read from a program; the result is capped later by max_bytes
+const maxExecOutput = 1 << 20
+
+// CustomExec configures a custom action that runs a local program.
+// The LLM payload is written to stdin as JSON and stdout is the result.
+type CustomExec struct {
+	Command        []string          `json:"command"`                   // Executable and arguments
+	Workdir        string            `json:"workdir,omitempty"`         // Defaults to the current directory
+	Env            []string          `json:"env,omitempty"`             // Variables passed from Blady's environment (allowlist)
+	EnvSet         map[string]string `json:"env_set,omitempty"`         // Extra variables; values may contain secret references
+	TimeoutSeconds int               `json:"timeout_seconds,omitempty"` // Defaults to 30
+	Sandbox        *ExecSandbox      `json:"sandbox,omitempty"`
+}
+
+// ExecSandbox restricts an exec action
+type ExecSandbox struct {
+	CPUSeconds int  `json:"cpu_seconds,omitempty"` // RLIMIT_CPU
+	MemoryMB   int  `json:"memory_mb,omitempty"`   // RLIMIT_AS
+	Isolate    bool `json:"isolate,omitempty"`     // Linux only: new user, PID, network, IPC and UTS namespaces (no network access)
+}
+
+func (e *CustomExec) validate() error {
+	if len(e.Command) == 0 || e.Command[0] == "" {
+		return fmt.Errorf("exec action needs a command")
+	}
+	if e.TimeoutSeconds < 0 {
+		return fmt.Errorf("invalid timeout_seconds %d", e.TimeoutSeconds)
+	}
+	if e.Sandbox != nil && e.Sandbox.Isolate && !isolationSupported {
+		return fmt.Errorf("sandbox isolation is only supported on Linux")
+	}
+	return nil
+}
+
+// cappedBuffer keeps the first max bytes written and counts the rest
+type cappedBuffer struct {
+	buf     bytes.Buffer
+	max     int
+	dropped int
+}
+
+func (c *cappedBuffer) Write(p []byte) (int, error) {
+	room := c.max - c.buf.Len()
+	if room < len(p) {
+		if room > 0 {
+			c.buf.Write(p[:room])
+		}
+		c.dropped += len(p) - max(room, 0)
+		return len(p), nil
+	}
+	return c.buf.Write(p)
+}
+
+func (c *cappedBuffer) String() string {
+	if c.dropped == 0 {
+		return c.buf.String()
+	}
+	return fmt.Sprintf("%s\n...[truncated %d bytes]", c.buf.String(), c.dropped)
+}
+
+// execute runs the configured program with the payload on stdin
+func (a *CustomAction) execute(ctx ActionContext, payload json.RawMessage) error {
+	cfg := a.Config.Exec
+	timeout := DefaultExecTimeout
+	if cfg.TimeoutSeconds > 0 {
+		timeout = time.Duration(cfg.TimeoutSeconds) * time.Second
+	}
+	var used []string
+	env := []string{}
+	for _, name := range cfg.Env {
+		if v, ok := os.LookupEnv(name); ok {
+			env = append(env, name+"="+v)
+		}
+	}
+	for name, value := range cfg.EnvSet {
+		resolved, err := a.resolveSecrets(value, &used)
+		if err != nil {
+			return fmt.Errorf("action %s: env %s: %w", a.Config.Name, name, err)
+		}
+		env = append(env, name+"="+resolved)
+	}
+
+	runCtx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+
+	name, args := cfg.Command[0], cfg.Command[1:]
+	if sb := cfg.Sandbox; sb != nil && (sb.CPUSeconds > 0 || sb.MemoryMB > 0) {
+		// Apply the rlimits in a shell that then replaces itself with the program
+		var limits []string
+		if sb.CPUSeconds > 0 {
+			limits = append(limits, "ulimit -t "+strconv.Itoa(sb.CPUSeconds))
+		}
+		if sb.MemoryMB > 0 {
+			limits = append(limits, "ulimit -v "+strconv.Itoa(sb.MemoryMB*1024))
+		}
+		args = append([]string{"-c", strings.Join(limits, " && ") + ` && exec "$@"`, "sh", name}, args...)
+		name = "/bin/sh"
+	}
+
+	cmd := exec.CommandContext(runCtx, name, args...)
+	cmd.Dir = cfg.Workdir
+	cmd.Env = env
+	cmd.Stdin = bytes.NewReader(payload)
+	stdout := &cappedBuffer{max: maxExecOutput}
+	stderr := &cappedBuffer{max: 2000}
+	cmd.Stdout = stdout
+	cmd.Stderr = stderr
+	cmd.WaitDelay = 2 * time.Second
+	configureSandbox(cmd, cfg.Sandbox)
+
+	err := cmd.Run()
+	exitCode := 0
+	var exitErr *exec.ExitError
+	switch {
+	case runCtx.Err() == context.DeadlineExceeded:
+		fmt.Printf("Action %s timed out after %s\n", a.Config.Name, timeout)
+		if ctx.ToolOutputs != nil {
+			*ctx.ToolOutputs = append(*ctx.ToolOutputs, fmt.Sprintf("Action '%s' timed out after %s and was killed.", a.Config.Name, timeout))
+		}
+		return fmt.Errorf("action %s timed out", a.Config.Name)
+	case errors.As(err, &exitErr):
+		exitCode = exitErr.ExitCode()
+	case err != nil:
+		return fmt.Errorf("failed to run action %s: %s", a.Config.Name, redactSecrets(err.Error(), used))
+	}
+
+	out := redactSecrets(stdout.String(), used)
+	if exitCode != 0 {
+		errText := redactSecrets(stderr.String(), used)
+		fmt.Printf("Action %s exited with code %d: %s\n", a.Config.Name, exitCode, errText)
+		if strings.TrimSpace(out) == "" {
+			out = errText
+		} else if errText != "" {
+			out += "\n" + errText
+		}
+	} else {
+		fmt.Printf("Action %s executed successfully\n", a.Config.Name)
+	}
+	a.deliver(ctx, exitCode, exitCode != 0, "exit code "+strconv.Itoa(exitCode), out)
+	return nil
+}
@@ -0,0 +1,25 @@
+package actions
+
+import (
+	"os"
+	"os/exec"
+	"syscall"
+)
+
+const isolationSupported = true
+
+// configureSandbox runs the program in its own process group, so a timeout kills
+// its children too, and in new namespaces when isolation is on
+func configureSandbox(cmd *exec.Cmd, sb *ExecSandbox) {
+	cmd.SysProcAttr = &syscall.SysProcAttr{Setpgid: true}
+	cmd.Cancel = func() error {
+		return syscall.Kill(-cmd.Process.Pid, syscall.SIGKILL)
+	}
+	if sb == nil || !sb.Isolate {
+		return
+	}
+	cmd.SysProcAttr.Cloneflags = syscall.CLONE_NEWUSER | syscall.CLONE_NEWPID | syscall.CLONE_NEWNET |
+		syscall.CLONE_NEWIPC | syscall.CLONE_NEWUTS
+	cmd.SysProcAttr.UidMappings = []syscall.SysProcIDMap{{ContainerID: os.Getuid(), HostID: os.Getuid(), Size: 1}}
+	cmd.SysProcAttr.GidMappings = []syscall.SysProcIDMap{{ContainerID: os.Getgid(), HostID: os.Getgid(), Size: 1}}
+}
@@ -0,0 +1,10 @@
+//go:build !linux
+
+package actions
+
+import "os/exec"
+
+const isolationSupported = false
+
+// configureSandbox is a no-op outside Linux; rlimits still apply through the shell
+func configureSandbox(cmd *exec.Cmd, sb *ExecSandbox) {}
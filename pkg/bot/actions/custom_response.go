@@ -0,0 +1,243 @@
+package actions
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"text/template"
+	"unicode/utf8"
+)
+
+// DefaultMaxResponseBytes caps custom action results when no max_bytes is set
+const DefaultMaxResponseBytes = 8000
+
+// Response destinations
+const (
+	ResponseToLLM    = "llm"
+	ResponseToMaster = "master"
+	ResponseToBoth   = "both"
+)
+
+// CustomResponse shapes the HTTP response of a custom action before it reaches the LLM or the master
+type CustomResponse struct {
+	// Select maps result fields to JSONPath expressions ($.a.b[0], $.items[*].name)
+	Select map[string]string `json:"select,omitempty"`
+	// Template is a Go template over the selected fields (or the parsed body without select)
+	Template string `json:"template,omitempty"`
+	// MaxBytes truncates the result, defaults to DefaultMaxResponseBytes
+	MaxBytes int `json:"max_bytes,omitempty"`
+	// Errors maps status codes ("404"), classes ("4xx", "5xx"), exit codes of exec
+	// actions ("1") or "default" to a message template; {{.Status}} and {{.Body}} are available
+	Errors map[string]string `json:"errors,omitempty"`
+	// To is llm (default), master or both
+	To string `json:"to,omitempty"`
+}
+
+// validate compiles the templates and JSONPaths so config errors show at load time
+func (r *CustomResponse) validate() error {
+	for name, path := range r.Select {
+		if _, err := parseJSONPath(path); err != nil {
+			return fmt.Errorf("select %s: %w", name, err)
+		}
+	}
+	if r.Template != "" {
+		if _, err := template.New("response").Parse(r.Template); err != nil {
+			return fmt.Errorf("invalid response template: %w", err)
+		}
+	}
+	for code, msg := range r.Errors {
+		if _, err := template.New(code).Parse(msg); err != nil {
+			return fmt.Errorf("invalid error template %s: %w", code, err)
+		}
+	}
+	switch r.To {
+	case "", ResponseToLLM, ResponseToMaster, ResponseToBoth:
+	default:
+		return fmt.Errorf("invalid response destination '%s'", r.To)
+	}
+	return nil
+}
+
+// shape turns the status (HTTP status or exit code) and body into the result text
+func (r *CustomResponse) shape(status int, failed bool, body string) (string, error) {
+	if failed {
+		if msg, ok := r.errorFor(status); ok {
+			return renderTemplate(msg, map[string]interface{}{"Status": status, "Body": body})
+		}
+		return fmt.Sprintf("Error (status %d): %s", status, body), nil
+	}
+	if len(r.Select) == 0 && r.Template == "" {
+		return body, nil
+	}
+
+	var data interface{}
+	if err := json.Unmarshal([]byte(body), &data); err != nil {
+		return "", fmt.Errorf("response is not JSON: %w", err)
+	}
+	if len(r.Select) > 0 {
+		selected := map[string]interface{}{}
+		for name, path := range r.Select {
+			steps, _ := parseJSONPath(path)
+			selected[name] = evalJSONPath(steps, data)
+		}
+		data = selected
+	}
+	if r.Template == "" {
+		out, err := json.Marshal(data)
+		if err != nil {
+			return "", err
+		}
+		return string(out), nil
+	}
+	return renderTemplate(r.Template, data)
+}
+
+// errorFor finds the error message for a status: exact code, then class, then default
+func (r *CustomResponse) errorFor(status int) (string, bool) {
+	for _, key := range []string{strconv.Itoa(status), fmt.Sprintf("%dxx", status/100), "default"} {
+		if msg, ok := r.Errors[key]; ok {
+			return msg, true
+		}
+	}
+	return "", false
+}
+
+func renderTemplate(text string, data interface{}) (string, error) {
+	tmpl, err := template.New("response").Option("missingkey=zero").Parse(text)
+	if err != nil {
+		return "", err
+	}
+	var buf bytes.Buffer
+	if err := tmpl.Execute(&buf, data); err != nil {
+		return "", fmt.Errorf("failed to render response template: %w", err)
+	}
+	return buf.String(), nil
+}
+
+// truncate caps text at max bytes on a rune boundary, with a marker
+func truncate(text string, max int) string {
+	if max <= 0 || len(text) <= max {
+		return text
+	}
+	cut := max
+	for cut > 0 && !utf8.RuneStart(text[cut]) {
+		cut--
+	}
+	return fmt.Sprintf("%s\n...[truncated %d bytes]", text[:cut], len(text)-cut)
+}
+
+// pathStep is a JSONPath step: a key, an index or a wildcard
+type pathStep struct {
+	key      string
+	index    int
+	isIndex  bool
+	wildcard bool
+}
+
+// parseJSONPath parses the supported JSONPath subset: $, .key, ['key'], [n], [*] and .*
+func parseJSONPath(path string) ([]pathStep, error) {
+	p := strings.TrimPrefix(strings.TrimSpace(path), "$")
+	var steps []pathStep
+	for p != "" {
+		switch {
+		case strings.HasPrefix(p, "."):
+			p = p[1:]
+			end := strings.IndexAny(p, ".[")
+			if end < 0 {
+				end = len(p)
+			}
+			key := p[:end]
+			if key == "" {
+				return nil, fmt.Errorf("empty key in path '%s'", path)
+			}
+			if key == "*" {
+				steps = append(steps, pathStep{wildcard: true})
+			} else {
+				steps = append(steps, pathStep{key: key})
+			}
+			p = p[end:]
+		case strings.HasPrefix(p, "["):
+			end := strings.Index(p, "]")
+			if end < 0 {
+				return nil, fmt.Errorf("unclosed bracket in path '%s'", path)
+			}
+			inner := strings.TrimSpace(p[1:end])
+			switch {
+			case inner == "*":
+				steps = append(steps, pathStep{wildcard: true})
+			case len(inner) >= 2 && (inner[0] == '\'' || inner[0] == '"') && inner[len(inner)-1] == inner[0]:
+				steps = append(steps, pathStep{key: inner[1 : len(inner)-1]})
+			default:
+				n, err := strconv.Atoi(inner)
+				if err != nil {
+					return nil, fmt.Errorf("invalid index '%s' in path '%s'", inner, path)
+				}
+				steps = append(steps, pathStep{index: n, isIndex: true})
+			}
+			p = p[end+1:]
+		default:
+			// Allow a leading bare key: "items[0].name"
+			if len(steps) > 0 {
+				return nil, fmt.Errorf("unexpected '%s' in path '%s'", p, path)
+			}
+			p = "." + p
+		}
+	}
+	return steps, nil
+}
+
+// evalJSONPath applies the steps to a decoded JSON value. Wildcards collect a list; missing values are nil.
+func evalJSONPath(steps []pathStep, v interface{}) interface{} {
+	if len(steps) == 0 {
+		return v
+	}
+	step, rest := steps[0], steps[1:]
+	switch {
+	case step.wildcard:
+		var items []interface{}
+		switch val := v.(type) {
+		case []interface{}:
+			items = val
+		case map[string]interface{}:
+			keys := make([]string, 0, len(val))
+			for k := range val {
+				keys = append(keys, k)
+			}
+			sort.Strings(keys)
+			for _, k := range keys {
+				items = append(items, val[k])
+			}
+		default:
+			return nil
+		}
+		out := []interface{}{}
+		for _, item := range items {
+			if r := evalJSONPath(rest, item); r != nil {
+				out = append(out, r)
+			}
+		}
+		return out
+	case step.isIndex:
+		list, ok := v.([]interface{})
+		if !ok {
+			return nil
+		}
+		i := step.index
+		if i < 0 {
+			i += len(list)
+		}
+		if i < 0 || i >= len(list) {
+			return nil
+		}
+		return evalJSONPath(rest, list[i])
+	default:
+		obj, ok := v.(map[string]interface{})
+		if !ok {
+			return nil
+		}
+		return evalJSONPath(rest, obj[step.key])
+	}
+}
@@ -1,6 +1,9 @@
 package actions
 
 import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
 	"encoding/json"
 	"io"
 	"net/http"
@@ -41,7 +44,7 @@ func TestCustomAction(t *testing.T) {
 
 	// 3. Load Actions
 	registry := NewRegistry()
-	if err := LoadCustomActions(tmpDir, registry); err != nil {
+	if err := LoadCustomActions(tmpDir, registry, nil); err != nil {
 		t.Fatalf("LoadCustomActions failed: %v", err)
 	}
 
@@ -137,3 +140,165 @@ func TestCustomAction_Templating(t *testing.T) {
 		t.Errorf("Expected query param extra=val in URL %s", receivedURL)
 	}
 }
+
+func TestCustomAction_AuthSigningAndRedaction(t *testing.T) {
+	var gotAuth, gotSignature string
+	var gotBody []byte
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		gotAuth = r.Header.Get("Authorization")
+		gotSignature = r.Header.Get("X-Hub-Signature")
+		gotBody, _ = io.ReadAll(r.Body)
+		// A careless API echoing the credentials back
+		w.Write([]byte(`{"token": "` + strings.TrimPrefix(gotAuth, "Bearer ") + `"}`))
+	}))
+	defer server.Close()
+
+	tmpDir := t.TempDir()
+	secrets := "# API credentials\nCRM_TOKEN=tok-123456\nCRM_HOOK=\"hook secret\"\n"
+	if err := os.WriteFile(filepath.Join(tmpDir, "secrets.env"), []byte(secrets), 0600); err != nil {
+		t.Fatalf("failed to write secrets: %v", err)
+	}
+	configs := map[string]string{
+		"crm.json": `{"name": "crm", "url": "` + server.URL + `", "response_to_llm": true,
+			"auth": {"type": "bearer", "token": "${secret:CRM_TOKEN}"},
+			"signing": {"secret": "${secret:CRM_HOOK}", "header": "X-Hub-Signature", "prefix": "sha256="}}`,
+		"inline.json": `{"name": "inline", "url": "` + server.URL + `", "auth": {"type": "bearer", "token": "tok-123456"}}`,
+	}
+	for name, content := range configs {
+		if err := os.WriteFile(filepath.Join(tmpDir, name), []byte(content), 0644); err != nil {
+			t.Fatalf("failed to write %s: %v", name, err)
+		}
+	}
+
+	registry := NewRegistry()
+	if err := LoadCustomActions(tmpDir, registry, nil); err != nil {
+		t.Fatalf("LoadCustomActions failed: %v", err)
+	}
+	if _, ok := registry.Get("inline"); ok {
+		t.Errorf("action with an inline token should not be registered")
+	}
+	action, ok := registry.Get("crm")
+	if !ok {
+		t.Fatalf("action 'crm' not registered")
+	}
+
+	ctx := ActionContext{ToolOutputs: &[]string{}}
+	if err := action.Execute(ctx, json.RawMessage(`{"note": "hi"}`)); err != nil {
+		t.Fatalf("Execute failed: %v", err)
+	}
+
+	if gotAuth != "Bearer tok-123456" {
+		t.Errorf("Authorization = %q", gotAuth)
+	}
+	mac := hmac.New(sha256.New, []byte("hook secret"))
+	mac.Write(gotBody)
+	if want := "sha256=" + hex.EncodeToString(mac.Sum(nil)); gotSignature != want {
+		t.Errorf("signature = %q, want %q", gotSignature, want)
+	}
+	output := (*ctx.ToolOutputs)[0]
+	if strings.Contains(output, "tok-123456") || !strings.Contains(output, "[REDACTED]") {
+		t.Errorf("secret not redacted from tool output: %s", output)
+	}
+}
+
+func TestCustomAction_ResponseShaping(t *testing.T) {
+	status := http.StatusOK
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(status)
+		w.Write([]byte(`{"order": {"id": 42, "state": "shipped"}, "items": [{"name": "mate"}, {"name": "yerba"}], "padding": "` + strings.Repeat("x", 500) + `"}`))
+	}))
+	defer server.Close()
+
+	var toMaster []string
+	action := &CustomAction{
+		Config: CustomActionConfig{
+			Name:   "order_status",
+			Method: "GET",
+			URL:    server.URL,
+			Response: &CustomResponse{
+				Select:   map[string]string{"id": "$.order.id", "state": "order.state", "items": "$.items[*].name"},
+				Template: "Order {{.id}} is {{.state}} ({{len .items}} items)",
+				Errors:   map[string]string{"404": "No such order", "5xx": "Shop down ({{.Status}})"},
+				To:       ResponseToBoth,
+			},
+		},
+		SendMaster: func(msg string) { toMaster = append(toMaster, msg) },
+	}
+	if err := action.Config.Response.validate(); err != nil {
+		t.Fatalf("validate failed: %v", err)
+	}
+
+	cases := []struct {
+		status int
+		want   string
+	}{
+		{http.StatusOK, "Order 42 is shipped (2 items)"},
+		{http.StatusNotFound, "No such order"},
+		{http.StatusBadGateway, "Shop down (502)"},
+	}
+	for _, c := range cases {
+		status = c.status
+		ctx := ActionContext{ToolOutputs: &[]string{}}
+		if err := action.Execute(ctx, json.RawMessage(`{}`)); err != nil {
+			t.Fatalf("Execute failed: %v", err)
+		}
+		if !strings.HasSuffix((*ctx.ToolOutputs)[0], c.want) {
+			t.Errorf("status %d: tool output %q, want suffix %q", c.status, (*ctx.ToolOutputs)[0], c.want)
+		}
+		if last := toMaster[len(toMaster)-1]; !strings.HasSuffix(last, c.want) {
+			t.Errorf("status %d: master got %q", c.status, last)
+		}
+	}
+
+	// Unshaped responses are capped
+	status = http.StatusOK
+	action.Config.Response = &CustomResponse{MaxBytes: 100}
+	ctx := ActionContext{ToolOutputs: &[]string{}}
+	if err := action.Execute(ctx, json.RawMessage(`{}`)); err != nil {
+		t.Fatalf("Execute failed: %v", err)
+	}
+	if out := (*ctx.ToolOutputs)[0]; !strings.Contains(out, "...[truncated") || len(out) > 200 {
+		t.Errorf("response not truncated: %d bytes", len(out))
+	}
+}
+
+func TestCustomAction_Exec(t *testing.T) {
+	t.Setenv("BLADY_ALLOWED", "visible")
+	t.Setenv("BLADY_HIDDEN", "hidden")
+
+	run := func(exec *CustomExec) string {
+		t.Helper()
+		if err := exec.validate(); err != nil {
+			t.Fatalf("validate failed: %v", err)
+		}
+		action := &CustomAction{
+			Config:  CustomActionConfig{Name: "ledger", Type: CustomKindExec, ResponseToLLM: true, Exec: exec},
+			Secrets: ActionSecrets{"LEDGER_KEY": "key-98765"},
+		}
+		ctx := ActionContext{ToolOutputs: &[]string{}}
+		action.Execute(ctx, json.RawMessage(`{"amount": 10}`))
+		if len(*ctx.ToolOutputs) != 1 {
+			t.Fatalf("expected 1 tool output, got %d", len(*ctx.ToolOutputs))
+		}
+		return (*ctx.ToolOutputs)[0]
+	}
+
+	out := run(&CustomExec{
+		Command: []string{"/bin/sh", "-c", `cat; echo " $BLADY_ALLOWED-$BLADY_HIDDEN-$KEY"`},
+		Env:     []string{"BLADY_ALLOWED"},
+		EnvSet:  map[string]string{"KEY": "${secret:LEDGER_KEY}"},
+	})
+	if !strings.Contains(out, `{"amount": 10} visible--[REDACTED]`) || !strings.Contains(out, "exit code 0") {
+		t.Errorf("unexpected output: %s", out)
+	}
+
+	out = run(&CustomExec{Command: []string{"/bin/sh", "-c", "echo broken >&2; exit 3"}})
+	if !strings.Contains(out, "exit code 3") || !strings.Contains(out, "broken") {
+		t.Errorf("unexpected output for failure: %s", out)
+	}
+
+	out = run(&CustomExec{Command: []string{"/bin/sh", "-c", "sleep 5"}, TimeoutSeconds: 1})
+	if !strings.Contains(out, "timed out") {
+		t.Errorf("unexpected output for timeout: %s", out)
+	}
+}
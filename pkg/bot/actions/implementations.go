@@ -6,6 +6,7 @@ import (
 	"os"
 	"strconv"
 	"strings"
+	"whatsabladerunner/pkg/behaviors"
 	"whatsabladerunner/pkg/tasks"
 )
 
@@ -18,15 +19,42 @@ type EnableBehaviorAction struct {
 func (a *EnableBehaviorAction) GetSchema() ActionSchema {
 	return ActionSchema{
 		Name:        "enable_behavior",
-		Description: "Enable a behavior for a contact. The behavior file must exist in config/modes/behavior/ (without extension).",
+		Description: "Enable a behavior for a contact or a group of chats. The behavior file must exist in config/modes/behavior/ (without extension). Optionally limit when it is active with a schedule.",
 		Parameters: json.RawMessage(`{
 			"type": "object",
 			"properties": {
-				"contact": {"type": "string", "description": "The contact JID or number."},
+				"contact": {"type": "string", "description": "The contact JID or number, or a target expression (see targets)."},
+				"targets": {"type": "array", "items": {"type": "string"}, "description": "Optional extra targets: JIDs, 'set:<name>' (contact set from config), 'tag:<name>' (tagged contacts), 'all_groups' or 'all_chats' (every 1:1 chat)."},
+				"exclude": {"type": "array", "items": {"type": "string"}, "description": "Optional chats to never target, same syntax as targets."},
 				"behavior": {"type": "string", "description": "The name of the behavior file (e.g. 'sales_agent')."},
-				"comments": {"type": "string", "description": "Additional context or comments."}
+				"comments": {"type": "string", "description": "Additional context or comments."},
+				"actions": {"type": "array", "items": {"type": "string"}, "description": "Optional. Only these actions (names or globs like 'github_*') may be used by the behavior, within the behavior mode permissions."},
+				"shadow": {"type": "boolean", "description": "Optional. Dry run: messages and other side effects are shown to the master instead of performed. Flip it off with update_behavior to go live."},
+				"schedule": {
+					"type": "object",
+					"description": "Optional. When the behavior is active. Omit for always active.",
+					"properties": {
+						"timezone": {"type": "string", "description": "IANA timezone (e.g. 'America/Argentina/Buenos_Aires'). Defaults to local."},
+						"active_hours": {"type": "array", "description": "Active only inside these weekly windows.", "items": {"$ref": "#/$defs/window"}},
+						"inactive_hours": {"type": "array", "description": "Never active inside these weekly windows (e.g. office hours).", "items": {"$ref": "#/$defs/window"}},
+						"holidays": {"type": "array", "description": "Dates (YYYY-MM-DD) active all day regardless of hours.", "items": {"type": "string"}},
+						"start_date": {"type": "string", "description": "YYYY-MM-DD or YYYY-MM-DDTHH:MM"},
+						"end_date": {"type": "string", "description": "YYYY-MM-DD (inclusive) or YYYY-MM-DDTHH:MM. The behavior is disabled automatically after it."}
+					}
+				}
 			},
-			"required": ["contact", "behavior"]
+			"required": ["behavior"],
+			"$defs": {
+				"window": {
+					"type": "object",
+					"properties": {
+						"days": {"type": "array", "items": {"type": "string", "enum": ["mon", "tue", "wed", "thu", "fri", "sat", "sun"]}},
+						"start": {"type": "string", "description": "HH:MM"},
+						"end": {"type": "string", "description": "HH:MM"}
+					},
+					"required": ["start", "end"]
+				}
+			}
 		}`),
 	}
 }
@@ -37,9 +65,14 @@ func (a *EnableBehaviorAction) Execute(ctx ActionContext, payload json.RawMessag
 	}
 
 	var input struct {
-		Contact  string `json:"contact"`
-		Behavior string `json:"behavior"`
-		Comments string `json:"comments"`
+		Contact  string              `json:"contact"`
+		Targets  []string            `json:"targets"`
+		Exclude  []string            `json:"exclude"`
+		Behavior string              `json:"behavior"`
+		Comments string              `json:"comments"`
+		Schedule *behaviors.Schedule `json:"schedule"`
+		Actions  []string            `json:"actions"`
+		Shadow   bool                `json:"shadow"`
 	}
 
 	// Handle stringified JSON
@@ -54,13 +87,40 @@ func (a *EnableBehaviorAction) Execute(ctx ActionContext, payload json.RawMessag
 		}
 	}
 
-	b, err := ctx.BehaviorManager.EnableBehavior(input.Contact, input.Behavior, input.Comments)
+	if err := ValidateActionPatterns(input.Actions); err != nil {
+		return err
+	}
+
+	b, err := ctx.BehaviorManager.EnableBehavior(behaviors.Behavior{
+		Contact:  input.Contact,
+		Targets:  input.Targets,
+		Exclude:  input.Exclude,
+		Name:     input.Behavior,
+		Comments: input.Comments,
+		Schedule: input.Schedule,
+		Actions:  input.Actions,
+		Shadow:   input.Shadow,
+	})
 	if err != nil {
 		return fmt.Errorf("failed to enable behavior: %w", err)
 	}
 
 	if ctx.ToolOutputs != nil {
-		*ctx.ToolOutputs = append(*ctx.ToolOutputs, fmt.Sprintf("Behavior %d ('%s') enabled for %s.", b.ID, b.Name, b.Contact))
+		output := fmt.Sprintf("Behavior %d ('%s') enabled for %s.", b.ID, b.Name, strings.Join(b.AllTargets(), ", "))
+		if len(b.Exclude) > 0 {
+			output += fmt.Sprintf(" Excluding: %s.", strings.Join(b.Exclude, ", "))
+		}
+		if b.Schedule != nil {
+			scheduleJSON, _ := json.Marshal(b.Schedule)
+			output += fmt.Sprintf(" Schedule: %s", string(scheduleJSON))
+		}
+		if len(b.Actions) > 0 {
+			output += fmt.Sprintf(" Allowed actions: %s.", strings.Join(b.Actions, ", "))
+		}
+		if b.Shadow {
+			output += " Shadow mode: side effects are shown to the master, not performed."
+		}
+		*ctx.ToolOutputs = append(*ctx.ToolOutputs, output)
 	}
 	return nil
 }
@@ -72,7 +132,7 @@ type DisableBehaviorAction struct{}
 func (a *DisableBehaviorAction) GetSchema() ActionSchema {
 	return ActionSchema{
 		Name:        "disable_behavior",
-		Description: "Disable (remove) a behavior by ID.",
+		Description: "Disable a behavior by ID. It is kept (with its activity log) and can be re-enabled with update_behavior.",
 		Parameters:  json.RawMessage(`{"type": "string", "description": "The Behavior ID."}`),
 	}
 }
@@ -112,6 +172,11 @@ type MemoryUpdateAction struct {
 	MemoriesPath string
 }
 
+// Review renders the new memory. Rewrites from task/behavior mode are external input.
+func (a *MemoryUpdateAction) Review(ctx ActionContext, payload json.RawMessage) Review {
+	return Review{Summary: "Rewrite global memory to:\n" + PayloadText(payload), Target: "memory", External: ctx.Mode != ModeCommand}
+}
+
 func (a *MemoryUpdateAction) GetSchema() ActionSchema {
 	return ActionSchema{
 		Name:        "memory_update",
@@ -142,6 +207,11 @@ type MemoryAppendAction struct {
 	MemoriesPath string
 }
 
+// Review renders the appended text. Appends from task/behavior mode are external input.
+func (a *MemoryAppendAction) Review(ctx ActionContext, payload json.RawMessage) Review {
+	return Review{Summary: "Append to global memory:\n" + PayloadText(payload), Target: "memory", External: ctx.Mode != ModeCommand}
+}
+
 func (a *MemoryAppendAction) GetSchema() ActionSchema {
 	return ActionSchema{
 		Name:        "memory_append",
@@ -185,81 +255,84 @@ func (a *MemoryAppendAction) Execute(ctx ActionContext, payload json.RawMessage)
 // --- ResponseAction ---
 
 type ResponseAction struct {
-	SendFunc       func(string)
-	CheckMessage   func(proposedMsg string, context []string) (bool, string, error)
-	SendMasterFunc func(string)
-	OnWatcherBlock func(blockedMsg string, targetChatJID string, sendFunc func(string))
+	SendFunc func(string)
+}
+
+// responsePayload is the object form of the response payload
+type responsePayload struct {
+	Text    string `json:"text"`
+	ReplyTo string `json:"reply_to"` // ID of the quoted message, as shown in the context lines ("#ID")
+}
+
+// parseResponse accepts a plain string or {"text": ..., "reply_to": ...}
+func parseResponse(payload json.RawMessage) (responsePayload, error) {
+	var p responsePayload
+	if err := json.Unmarshal(payload, &p.Text); err == nil {
+		return p, nil
+	}
+	if err := json.Unmarshal(payload, &p); err != nil {
+		return p, err
+	}
+	p.ReplyTo = strings.TrimPrefix(strings.TrimSpace(p.ReplyTo), "#")
+	return p, nil
 }
 
 func (a *ResponseAction) GetSchema() ActionSchema {
 	return ActionSchema{
 		Name:        "response",
-		Description: "A message sent to the 3rd party (as the Master).",
-		Parameters:  json.RawMessage(`{"type": "string", "description": "The message text."}`),
+		Description: "A message sent to the 3rd party (as the Master). To reply to a specific earlier message (quoting it), use an object with the ID shown after '#' in the context lines.",
+		Parameters: json.RawMessage(`{
+			"anyOf": [
+				{"type": "string", "description": "The message text."},
+				{
+					"type": "object",
+					"properties": {
+						"text": {"type": "string", "description": "The message text."},
+						"reply_to": {"type": "string", "description": "ID of the message to quote, e.g. 3EB0C7A1F2 for a line '[2024-01-01 12:00:00 #3EB0C7A1F2] User: ...'."}
+					},
+					"required": ["text"]
+				}
+			]
+		}`),
 	}
 }
 
-func (a *ResponseAction) Execute(ctx ActionContext, payload json.RawMessage) error {
-	var contentStr string
-	if err := json.Unmarshal(payload, &contentStr); err != nil {
-		return fmt.Errorf("invalid payload for response: %w", err)
+// Review renders the message for the watcher. Only messages to a contact are external.
+func (a *ResponseAction) Review(ctx ActionContext, payload json.RawMessage) Review {
+	review := Review{Summary: PayloadText(payload), Target: "master"}
+	if p, err := parseResponse(payload); err == nil {
+		review.Summary = p.Text
+		if p.ReplyTo != "" {
+			review.Summary += fmt.Sprintf(" (reply to #%s)", p.ReplyTo)
+		}
 	}
-
-	// Helper to send feedback to master
-	logToMaster := func(msg string) {
-		if a.SendMasterFunc != nil {
-			if ctx.Task != nil {
-				a.SendMasterFunc(fmt.Sprintf("[Blady][Task %d] : %s", ctx.Task.ID, msg))
-			} else {
-				a.SendMasterFunc(fmt.Sprintf("[Blady] : %s", msg))
-			}
+	if ctx.SendToContact != nil {
+		review.External = true
+		review.Target = "contact"
+		if ctx.Task != nil {
+			review.Target = ctx.Task.ChatID
 		}
 	}
+	return review
+}
 
-	if ctx.Task != nil {
-		// Task Mode: Check with Watcher
-		if a.CheckMessage != nil {
-			proceed, reason, err := a.CheckMessage(contentStr, ctx.Context)
-			if err != nil {
-				logToMaster(fmt.Sprintf("Error in Watcher check: %v", err))
-				// Fail safe? Or continue? Bot code continued with warning.
-				// But block if error prevents verification? Safe to block.
-				return fmt.Errorf("watcher check error: %w", err)
-			}
-
-			if !proceed {
-				fmt.Printf("Watcher BLOCKED message: %s. Reason: %s\n", contentStr, reason)
-				logToMaster(fmt.Sprintf("[Watcher] : Blocked: \"%s\". Reason: %s ('LET IT BE' cancels block)", contentStr, reason))
-
-				if a.OnWatcherBlock != nil && ctx.SendToContact != nil {
-					a.OnWatcherBlock(contentStr, ctx.Task.ChatID, ctx.SendToContact)
-				}
-				return nil // Blocked, so we don't send
-			}
-		}
+func (a *ResponseAction) Execute(ctx ActionContext, payload json.RawMessage) error {
+	p, err := parseResponse(payload)
+	if err != nil {
+		return fmt.Errorf("invalid payload for response: %w", err)
+	}
 
-		// Send to contact
-		if ctx.SendToContact != nil {
-			ctx.SendToContact(contentStr)
+	if ctx.SendToContact != nil {
+		// Task or behavior mode: send to the contact (watcher review happens before Execute)
+		if p.ReplyTo != "" && ctx.ReplyToContact != nil {
+			ctx.ReplyToContact(p.Text, p.ReplyTo)
+		} else {
+			ctx.SendToContact(p.Text)
 		}
-
-		// Transition task to running if pending
-		// We need access to TaskManager to set status?
-		// The original code did: b.TaskManager.SetTaskRunning(task.ID)
-		// We don't have TaskManager here.
-		// Maybe ActionContext should have a callback "SetTaskRunning"?
-		// Or we pass TaskManager to ResponseAction.
-		// ResponseAction doesn't seemingly need TaskManager usually, but side-effect of sending in task mode requires it.
-		// ACTUALLY, I missed dependencies. I should probably inject a wrapper or interface.
-		// For now, I'll ignore the status update side-effect here? No, that's important.
-		// I will handle this by emitting an event or modifying Task object?
-		// Task object is a pointer but modifying it doesn't save it.
-		// I will rely on the caller to handle status updates? No, action is encapsulation.
-		// I'll add TaskManager to ResponseAction dependencies.
 	} else {
 		// Command Mode (Response to Master/Self)
 		if a.SendFunc != nil {
-			a.SendFunc("[Blady] : " + contentStr)
+			a.SendFunc("[Blady] : " + p.Text)
 		}
 	}
 	return nil
@@ -303,6 +376,19 @@ type CreateTaskAction struct {
 	SendFunc    func(string) // for feedback in command mode
 }
 
+// Review renders the task: it will talk to the contact on the master's behalf
+func (a *CreateTaskAction) Review(ctx ActionContext, payload json.RawMessage) Review {
+	var input tasks.CreateTaskContent
+	if err := unmarshalObjectPayload(payload, &input); err != nil {
+		return Review{Summary: PayloadText(payload), External: true}
+	}
+	return Review{
+		Summary:  fmt.Sprintf("Create task for %s.\nObjective: %s\nOrders: %s", input.Contact, input.Objective, input.OriginalOrders),
+		Target:   input.Contact,
+		External: true,
+	}
+}
+
 func (a *CreateTaskAction) GetSchema() ActionSchema {
 	return ActionSchema{
 		Name:        "create_task",
@@ -313,7 +399,11 @@ func (a *CreateTaskAction) GetSchema() ActionSchema {
 				"objective": {"type": "string"},
 				"contact": {"type": "string", "description": "The contact number (e.g. 12345@whats.me)"},
 				"original_orders": {"type": "string"},
-				"schedule_datetime": {"type": "string", "description": "ISO 8601 format without timezone (e.g. 2024-12-31T23:59), optional"}
+				"schedule_datetime": {"type": "string", "description": "ISO 8601 format without timezone (e.g. 2024-12-31T23:59), optional"},
+				"watcher_policy": {"type": "string", "enum": ["retry"], "description": "Optional. 'retry' makes watcher blocks always retry with feedback and never be overruled."},
+				"language": {"type": "string", "description": "Optional. Language to talk in (e.g. English). Defaults to the contact's language."},
+				"actions": {"type": "array", "items": {"type": "string"}, "description": "Optional. Only these actions (names or globs like 'github_*') may be used by the task, within the task mode permissions."},
+				"shadow": {"type": "boolean", "description": "Optional. Dry run: messages and other side effects are shown to the master instead of performed."}
 			},
 			"required": ["objective", "contact", "original_orders"]
 		}`),
@@ -343,6 +433,13 @@ func (a *CreateTaskAction) Execute(ctx ActionContext, payload json.RawMessage) e
 		return fmt.Errorf("%s", msg)
 	}
 
+	if input.WatcherPolicy != "" && input.WatcherPolicy != tasks.WatcherRetry {
+		return fmt.Errorf("invalid watcher_policy '%s'", input.WatcherPolicy)
+	}
+	if err := ValidateActionPatterns(input.Actions); err != nil {
+		return err
+	}
+
 	task, err := a.TaskManager.CreateTask(input.Objective, input.Contact, input.OriginalOrders, input.ScheduleDatetime)
 	if err != nil {
 		msg := fmt.Sprintf("Error creating task: %v", err)
@@ -352,6 +449,16 @@ func (a *CreateTaskAction) Execute(ctx ActionContext, payload json.RawMessage) e
 		return err
 	}
 
+	if input.WatcherPolicy != "" || input.Language != "" || len(input.Actions) > 0 || input.Shadow {
+		task.WatcherPolicy = input.WatcherPolicy
+		task.Language = input.Language
+		task.Actions = input.Actions
+		task.Shadow = input.Shadow
+		if err := a.TaskManager.SaveTask(task); err != nil {
+			return err
+		}
+	}
+
 	if a.SendFunc != nil {
 		taskJSON, _ := json.MarshalIndent(task, "", "  ")
 		a.SendFunc(fmt.Sprintf("[Blady] : Tarea creada:\n```json\n%s\n```", string(taskJSON)))
@@ -382,6 +489,7 @@ const (
 	TaskConfirm TaskActionType = "confirm_task"
 	TaskPause   TaskActionType = "pause_task"
 	TaskResume  TaskActionType = "resume_task"
+	TaskFinish  TaskActionType = "finish_task"
 )
 
 type TaskManagementAction struct {
@@ -402,6 +510,8 @@ func (a *TaskManagementAction) GetSchema() ActionSchema {
 		desc = "Pause a task. Content is ID."
 	case TaskResume:
 		desc = "Resume a task. Content is ID."
+	case TaskFinish:
+		desc = "Mark a task as finished once its objective is met. Content is ID."
 	}
 
 	// Just accept ID as string or int, schema says string/int but we usually get string representation
@@ -427,6 +537,9 @@ func (a *TaskManagementAction) Execute(ctx ActionContext, payload json.RawMessag
 	if err != nil {
 		return fmt.Errorf("invalid ID for %s: %w", a.Type, err)
 	}
+	if ctx.Mode == ModeTask && ctx.Task != nil && id != ctx.Task.ID {
+		return fmt.Errorf("%s: a task can only change itself (task %d)", a.Type, ctx.Task.ID)
+	}
 
 	switch a.Type {
 	case TaskDelete:
@@ -441,6 +554,8 @@ func (a *TaskManagementAction) Execute(ctx ActionContext, payload json.RawMessag
 		}
 	case TaskPause:
 		return a.TaskManager.PauseTask(id)
+	case TaskFinish:
+		return a.TaskManager.FinishTask(id)
 	case TaskResume:
 		if err := a.TaskManager.ResumeTask(id); err != nil {
 			return err
@@ -483,6 +598,19 @@ func (a *SendMediaAction) GetSchema() ActionSchema {
 	}
 }
 
+// Review renders the media being sent. Media to the master is internal.
+func (a *SendMediaAction) Review(ctx ActionContext, payload json.RawMessage) Review {
+	review := Review{Summary: "Send media ID " + PayloadText(payload), Target: "master"}
+	if !a.ToMaster && ctx.Task != nil {
+		review.Target = ctx.Task.ChatID
+		review.External = true
+	} else if !a.ToMaster && ctx.Mode != ModeCommand {
+		review.Target = "contact"
+		review.External = true
+	}
+	return review
+}
+
 func (a *SendMediaAction) Execute(ctx ActionContext, payload json.RawMessage) error {
 	var idStr string
 	if err := json.Unmarshal(payload, &idStr); err != nil {
@@ -542,6 +670,15 @@ func (a *ButtonResponseAction) GetSchema() ActionSchema {
 	}
 }
 
+// Review renders the button click, always sent to a third party chat
+func (a *ButtonResponseAction) Review(ctx ActionContext, payload json.RawMessage) Review {
+	review := Review{Summary: "Click button: " + PayloadText(payload), Target: "contact", External: true}
+	if ctx.Task != nil {
+		review.Target = ctx.Task.ChatID
+	}
+	return review
+}
+
 func (a *ButtonResponseAction) Execute(ctx ActionContext, payload json.RawMessage) error {
 	var btnResp struct {
 		DisplayText string `json:"displayText"`
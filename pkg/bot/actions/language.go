@@ -0,0 +1,72 @@
+package actions
+
+import (
+	"encoding/json"
+	"fmt"
+	"whatsabladerunner/pkg/language"
+	"whatsabladerunner/pkg/tasks"
+)
+
+// SetLanguageAction sets the language the bot talks in with a contact or in a task
+type SetLanguageAction struct {
+	Overrides   *language.Overrides
+	TaskManager *tasks.TaskManager
+	SendFunc    func(string)
+}
+
+func (a *SetLanguageAction) GetSchema() ActionSchema {
+	return ActionSchema{
+		Name:        "set_language",
+		Description: "Set the language to talk in with a contact or within a task, overriding the detected one. An empty language goes back to automatic.",
+		Parameters: json.RawMessage(`{
+			"type": "object",
+			"properties": {
+				"contact": {"type": "string", "description": "Contact JID or number. Use this or task_id."},
+				"task_id": {"type": "integer", "description": "Task ID. Use this or contact."},
+				"language": {"type": "string", "description": "Language name in English (e.g. English, Spanish, Portuguese), or empty to remove the override"}
+			},
+			"required": ["language"]
+		}`),
+	}
+}
+
+func (a *SetLanguageAction) Execute(ctx ActionContext, payload json.RawMessage) error {
+	var input struct {
+		Contact  string `json:"contact"`
+		TaskID   int    `json:"task_id"`
+		Language string `json:"language"`
+	}
+	if err := unmarshalObjectPayload(payload, &input); err != nil {
+		return fmt.Errorf("invalid payload for set_language: %w", err)
+	}
+
+	var target string
+	switch {
+	case input.TaskID != 0:
+		task, err := a.TaskManager.LoadTask(input.TaskID)
+		if err != nil {
+			return err
+		}
+		task.Language = input.Language
+		if err := a.TaskManager.SaveTask(task); err != nil {
+			return err
+		}
+		target = fmt.Sprintf("task %d", task.ID)
+	case input.Contact != "":
+		if err := a.Overrides.Set(input.Contact, input.Language); err != nil {
+			return err
+		}
+		target = input.Contact
+	default:
+		return fmt.Errorf("set_language needs a contact or a task_id")
+	}
+
+	if a.SendFunc != nil {
+		if input.Language == "" {
+			a.SendFunc(fmt.Sprintf("[Blady] : Language for %s back to automatic.", target))
+		} else {
+			a.SendFunc(fmt.Sprintf("[Blady] : Language for %s set to %s.", target, input.Language))
+		}
+	}
+	return nil
+}
@@ -0,0 +1,249 @@
+package actions
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+)
+
+// Messenger performs the message operations other than sending new text:
+// reactions, read receipts, and edits/deletions of our own messages.
+// Message IDs are the ones shown after '#' in the context lines.
+type Messenger interface {
+	React(chatJID, messageID, emoji string) error
+	MarkRead(chatJID string, messageIDs []string) error // No IDs marks the latest incoming messages
+	Edit(chatJID, messageID, text string) error
+	Delete(chatJID, messageID string) error
+}
+
+// messageOp is the payload shared by the message actions
+type messageOp struct {
+	Chat       string   `json:"chat"` // Command mode only; tasks and behaviors act on their own chat
+	MessageID  string   `json:"message_id"`
+	MessageIDs []string `json:"message_ids"`
+	Emoji      string   `json:"emoji"`
+	Text       string   `json:"text"`
+}
+
+// parseMessageOp decodes the payload (an object, or empty) and normalizes the "#ID" references
+func parseMessageOp(payload json.RawMessage) (messageOp, error) {
+	var op messageOp
+	if len(payload) > 0 && string(payload) != "null" && string(payload) != `""` {
+		if err := json.Unmarshal(payload, &op); err != nil {
+			return op, err
+		}
+	}
+	op.MessageID = strings.TrimPrefix(strings.TrimSpace(op.MessageID), "#")
+	for i, id := range op.MessageIDs {
+		op.MessageIDs[i] = strings.TrimPrefix(strings.TrimSpace(id), "#")
+	}
+	return op, nil
+}
+
+// operationChat resolves the chat an operation applies to: the task chat, the
+// behavior chat, or in command mode the chat given in the payload
+func operationChat(ctx ActionContext, op messageOp) string {
+	if ctx.Task != nil {
+		if ctx.Task.ChatID != "" {
+			return ctx.Task.ChatID
+		}
+		return ctx.Task.Contact
+	}
+	if ctx.ChatJID != "" {
+		return ctx.ChatJID
+	}
+	return op.Chat
+}
+
+// messageAction is the common part of the message actions
+type messageAction struct {
+	Messenger func() Messenger
+}
+
+// run resolves the chat and the messenger and reports failures to the LLM
+func (a *messageAction) run(name string, ctx ActionContext, payload json.RawMessage, needID bool, do func(m Messenger, chat string, op messageOp) error) error {
+	op, err := parseMessageOp(payload)
+	if err != nil {
+		return fmt.Errorf("invalid payload for %s: %w", name, err)
+	}
+	chat := operationChat(ctx, op)
+	switch {
+	case chat == "":
+		err = fmt.Errorf("no chat to act on (pass \"chat\" in command mode)")
+	case needID && op.MessageID == "":
+		err = fmt.Errorf("message_id is required")
+	case a.Messenger == nil || a.Messenger() == nil:
+		err = fmt.Errorf("messaging is not available")
+	default:
+		err = do(a.Messenger(), chat, op)
+	}
+	if err != nil && ctx.ToolOutputs != nil {
+		*ctx.ToolOutputs = append(*ctx.ToolOutputs, fmt.Sprintf("[%s] Failed: %v", name, err))
+	}
+	return err
+}
+
+// review renders an operation on the chat of the run
+func (a *messageAction) review(ctx ActionContext, payload json.RawMessage, summary func(op messageOp) string) Review {
+	op, _ := parseMessageOp(payload)
+	review := Review{Summary: summary(op), Target: operationChat(ctx, op), External: true}
+	if review.Target == "" {
+		review.Target = "contact"
+	}
+	return review
+}
+
+// --- ReactAction ---
+
+type ReactAction struct {
+	messageAction
+}
+
+func NewReactAction(messenger func() Messenger) *ReactAction {
+	return &ReactAction{messageAction{Messenger: messenger}}
+}
+
+func (a *ReactAction) GetSchema() ActionSchema {
+	return ActionSchema{
+		Name:        "react",
+		Description: "React with an emoji to a message of the conversation. An empty emoji removes the reaction.",
+		Parameters: json.RawMessage(`{
+			"type": "object",
+			"properties": {
+				"message_id": {"type": "string", "description": "ID of the message, shown after '#' in the context lines."},
+				"emoji": {"type": "string", "description": "A single emoji, e.g. 👍"},
+				"chat": {"type": "string", "description": "Chat JID (command mode only)."}
+			},
+			"required": ["message_id", "emoji"]
+		}`),
+	}
+}
+
+func (a *ReactAction) Review(ctx ActionContext, payload json.RawMessage) Review {
+	return a.review(ctx, payload, func(op messageOp) string {
+		return fmt.Sprintf("React %s to #%s", op.Emoji, op.MessageID)
+	})
+}
+
+func (a *ReactAction) Execute(ctx ActionContext, payload json.RawMessage) error {
+	return a.run("react", ctx, payload, true, func(m Messenger, chat string, op messageOp) error {
+		return m.React(chat, op.MessageID, strings.TrimSpace(op.Emoji))
+	})
+}
+
+// --- MarkReadAction ---
+
+type MarkReadAction struct {
+	messageAction
+}
+
+func NewMarkReadAction(messenger func() Messenger) *MarkReadAction {
+	return &MarkReadAction{messageAction{Messenger: messenger}}
+}
+
+func (a *MarkReadAction) GetSchema() ActionSchema {
+	return ActionSchema{
+		Name:        "mark_read",
+		Description: "Mark messages of the conversation as read (blue ticks), after a short human-like delay. Without IDs, marks the latest incoming messages.",
+		Parameters: json.RawMessage(`{
+			"type": "object",
+			"properties": {
+				"message_ids": {"type": "array", "items": {"type": "string"}, "description": "IDs of the messages, shown after '#' in the context lines."},
+				"chat": {"type": "string", "description": "Chat JID (command mode only)."}
+			}
+		}`),
+	}
+}
+
+func (a *MarkReadAction) Review(ctx ActionContext, payload json.RawMessage) Review {
+	return a.review(ctx, payload, func(op messageOp) string {
+		if len(op.MessageIDs) == 0 {
+			return "Mark the latest messages as read"
+		}
+		return "Mark as read: #" + strings.Join(op.MessageIDs, ", #")
+	})
+}
+
+func (a *MarkReadAction) Execute(ctx ActionContext, payload json.RawMessage) error {
+	return a.run("mark_read", ctx, payload, false, func(m Messenger, chat string, op messageOp) error {
+		return m.MarkRead(chat, op.MessageIDs)
+	})
+}
+
+// --- EditMessageAction ---
+
+type EditMessageAction struct {
+	messageAction
+}
+
+func NewEditMessageAction(messenger func() Messenger) *EditMessageAction {
+	return &EditMessageAction{messageAction{Messenger: messenger}}
+}
+
+func (a *EditMessageAction) GetSchema() ActionSchema {
+	return ActionSchema{
+		Name:        "edit_message",
+		Description: "Edit one of your own recent messages (lines marked 'Me', up to 20 minutes old).",
+		Parameters: json.RawMessage(`{
+			"type": "object",
+			"properties": {
+				"message_id": {"type": "string", "description": "ID of your message, shown after '#' in the context lines."},
+				"text": {"type": "string", "description": "The new text."},
+				"chat": {"type": "string", "description": "Chat JID (command mode only)."}
+			},
+			"required": ["message_id", "text"]
+		}`),
+	}
+}
+
+func (a *EditMessageAction) Review(ctx ActionContext, payload json.RawMessage) Review {
+	return a.review(ctx, payload, func(op messageOp) string {
+		return fmt.Sprintf("Edit #%s to: %s", op.MessageID, op.Text)
+	})
+}
+
+func (a *EditMessageAction) Execute(ctx ActionContext, payload json.RawMessage) error {
+	return a.run("edit_message", ctx, payload, true, func(m Messenger, chat string, op messageOp) error {
+		if strings.TrimSpace(op.Text) == "" {
+			return fmt.Errorf("text is required (use delete_message to remove a message)")
+		}
+		return m.Edit(chat, op.MessageID, op.Text)
+	})
+}
+
+// --- DeleteMessageAction ---
+
+type DeleteMessageAction struct {
+	messageAction
+}
+
+func NewDeleteMessageAction(messenger func() Messenger) *DeleteMessageAction {
+	return &DeleteMessageAction{messageAction{Messenger: messenger}}
+}
+
+func (a *DeleteMessageAction) GetSchema() ActionSchema {
+	return ActionSchema{
+		Name:        "delete_message",
+		Description: "Delete one of your own recent messages for everyone (lines marked 'Me', up to 2 days old).",
+		Parameters: json.RawMessage(`{
+			"type": "object",
+			"properties": {
+				"message_id": {"type": "string", "description": "ID of your message, shown after '#' in the context lines."},
+				"chat": {"type": "string", "description": "Chat JID (command mode only)."}
+			},
+			"required": ["message_id"]
+		}`),
+	}
+}
+
+func (a *DeleteMessageAction) Review(ctx ActionContext, payload json.RawMessage) Review {
+	return a.review(ctx, payload, func(op messageOp) string {
+		return "Delete for everyone #" + op.MessageID
+	})
+}
+
+func (a *DeleteMessageAction) Execute(ctx ActionContext, payload json.RawMessage) error {
+	return a.run("delete_message", ctx, payload, true, func(m Messenger, chat string, op messageOp) error {
+		return m.Delete(chat, op.MessageID)
+	})
+}
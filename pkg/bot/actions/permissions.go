@@ -0,0 +1,148 @@
+package actions
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path"
+	"slices"
+)
+
+// ModePermissions lists the actions a mode may use. Entries are action names or
+// glob patterns ("github_*"); an empty allow list allows every action.
+type ModePermissions struct {
+	Allow []string `json:"allow,omitempty"`
+	Deny  []string `json:"deny,omitempty"` // Checked after allow
+}
+
+// Permissions holds the per-mode action permissions, stored in config/permissions.json.
+// Tasks and behaviors can narrow them further with their own allowlist (Task.Actions, Behavior.Actions).
+type Permissions struct {
+	Modes map[string]ModePermissions `json:"modes"`
+}
+
+// managementActions are the actions only the master (command mode) may use
+var managementActions = []string{
+	"search_contacts", "list_behaviors", "update_behavior", "archive_behavior", "behavior_activity",
+	"create_behavior_template", "edit_behavior_template", "preview_behavior_template", "delete_behavior_template", "rollback_behavior_template",
+	"set_language", "audit",
+}
+
+// conversationActions are the messaging and media actions tasks and behaviors talk with
+var conversationActions = []string{
+	"response", "message_master", "send_media", "send_media_to_master", "button_response",
+	"react", "mark_read", "edit_message", "delete_message",
+}
+
+// ownTaskActions let a task pause or finish itself; they only accept the task's own ID
+var ownTaskActions = []string{"pause_task", "finish_task"}
+
+// agentDenied are kept out of task and behavior mode even when config/permissions.json
+// allows a glob covering them; naming one in the mode allow list opts in
+var agentDenied = append([]string{
+	"create_task", "memory_update", "memory_append", "search_history",
+	"enable_behavior", "disable_behavior", "confirm_task", "resume_task", "delete_task",
+}, managementActions...)
+
+// DefaultPermissions lets command mode use everything but message_master. Task and
+// behavior mode are default-deny: they get the conversation actions (plus polls and
+// pausing or finishing themselves for tasks) and nothing else, so memory writes,
+// create_task, the task and behavior toggles, management and custom, exec and MCP
+// actions must be allowed in config/permissions.json. search_history allowed for tasks
+// is scoped to the task chat.
+func DefaultPermissions() *Permissions {
+	taskAllow := append(append([]string{"send_poll", "poll_results"}, ownTaskActions...), conversationActions...)
+	return &Permissions{
+		Modes: map[string]ModePermissions{
+			ModeCommand:  {Deny: []string{"message_master"}},
+			ModeTask:     {Allow: taskAllow, Deny: agentDenied},
+			ModeBehavior: {Allow: conversationActions, Deny: append(append([]string{}, ownTaskActions...), agentDenied...)},
+		},
+	}
+}
+
+// LoadPermissions reads the permissions file. A missing file means the defaults.
+// A mode in the file adds its allow list to a default allow list (or restricts a mode
+// that allowed everything) and its deny list to the default denies; a default deny is
+// only dropped when the mode allow list names that action.
+func LoadPermissions(path string) (*Permissions, error) {
+	permissions := DefaultPermissions()
+	data, err := os.ReadFile(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return permissions, nil
+		}
+		return nil, fmt.Errorf("failed to read permissions: %w", err)
+	}
+
+	var loaded Permissions
+	if err := json.Unmarshal(data, &loaded); err != nil {
+		return nil, fmt.Errorf("failed to parse permissions: %w", err)
+	}
+	for mode, p := range loaded.Modes {
+		if err := ValidateActionPatterns(p.Allow); err != nil {
+			return nil, fmt.Errorf("mode %s: %w", mode, err)
+		}
+		if err := ValidateActionPatterns(p.Deny); err != nil {
+			return nil, fmt.Errorf("mode %s: %w", mode, err)
+		}
+		permissions.Modes[mode] = mergeModePermissions(permissions.Modes[mode], p)
+	}
+	return permissions, nil
+}
+
+// mergeModePermissions combines the default permissions of a mode with the file entry
+func mergeModePermissions(def, file ModePermissions) ModePermissions {
+	merged := ModePermissions{Allow: file.Allow}
+	if len(def.Allow) > 0 {
+		merged.Allow = append(append([]string{}, def.Allow...), file.Allow...)
+	}
+	for _, action := range def.Deny {
+		if !slices.Contains(file.Allow, action) {
+			merged.Deny = append(merged.Deny, action)
+		}
+	}
+	merged.Deny = append(merged.Deny, file.Deny...)
+	return merged
+}
+
+// ValidateActionPatterns checks the glob syntax of an allow or deny list
+func ValidateActionPatterns(patterns []string) error {
+	for _, p := range patterns {
+		if _, err := path.Match(p, ""); err != nil {
+			return fmt.Errorf("invalid action pattern '%s'", p)
+		}
+	}
+	return nil
+}
+
+// matchesAny reports whether the action matches one of the patterns
+func matchesAny(patterns []string, action string) bool {
+	for _, p := range patterns {
+		if ok, _ := path.Match(p, action); ok {
+			return true
+		}
+	}
+	return false
+}
+
+// Allowed reports whether the action may run in the mode. Each non-empty narrow list
+// (the task or behavior allowlists) must allow it too. Unknown modes allow nothing.
+func (p *Permissions) Allowed(mode, action string, narrow ...[]string) bool {
+	mp, ok := p.Modes[mode]
+	if !ok {
+		return false
+	}
+	if len(mp.Allow) > 0 && !matchesAny(mp.Allow, action) {
+		return false
+	}
+	if matchesAny(mp.Deny, action) {
+		return false
+	}
+	for _, list := range narrow {
+		if len(list) > 0 && !matchesAny(list, action) {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,134 @@
+package actions
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+)
+
+// Polls sends WhatsApp polls and summarizes their votes
+type Polls interface {
+	SendPoll(chatJID, question string, options []string, multi bool) (string, error) // Returns the poll ID
+	PollResults(chatJID, pollID string, limit int) ([]string, error)                 // Empty chat means every chat
+}
+
+// --- SendPollAction ---
+
+type SendPollAction struct {
+	Polls func() Polls
+}
+
+type sendPollPayload struct {
+	Question string   `json:"question"`
+	Options  []string `json:"options"`
+	Multi    bool     `json:"multi"`
+	Chat     string   `json:"chat"` // Command mode only
+}
+
+func (a *SendPollAction) GetSchema() ActionSchema {
+	return ActionSchema{
+		Name:        "send_poll",
+		Description: "Send a WhatsApp poll to the conversation. Votes show up in the conversation as '(voted ... in poll #ID ...)' lines with the current tally.",
+		Parameters: json.RawMessage(`{
+			"type": "object",
+			"properties": {
+				"question": {"type": "string"},
+				"options": {"type": "array", "items": {"type": "string"}, "description": "2 to 12 options."},
+				"multi": {"type": "boolean", "description": "Allow selecting several options (default false)."},
+				"chat": {"type": "string", "description": "Chat JID (command mode only)."}
+			},
+			"required": ["question", "options"]
+		}`),
+	}
+}
+
+// Review renders the poll, always sent to a third party chat
+func (a *SendPollAction) Review(ctx ActionContext, payload json.RawMessage) Review {
+	var p sendPollPayload
+	json.Unmarshal(payload, &p)
+	review := Review{Summary: fmt.Sprintf("Poll: %s [%s]", p.Question, strings.Join(p.Options, " / ")), Target: operationChat(ctx, messageOp{Chat: p.Chat}), External: true}
+	if review.Target == "" {
+		review.Target = "contact"
+	}
+	return review
+}
+
+func (a *SendPollAction) Execute(ctx ActionContext, payload json.RawMessage) error {
+	var p sendPollPayload
+	if err := json.Unmarshal(payload, &p); err != nil {
+		return fmt.Errorf("invalid payload for send_poll: %w", err)
+	}
+	chat := operationChat(ctx, messageOp{Chat: p.Chat})
+
+	var err error
+	var pollID string
+	switch {
+	case chat == "":
+		err = fmt.Errorf("no chat to send the poll to (pass \"chat\" in command mode)")
+	case strings.TrimSpace(p.Question) == "" || len(p.Options) < 2 || len(p.Options) > 12:
+		err = fmt.Errorf("a poll needs a question and 2 to 12 options")
+	case a.Polls == nil || a.Polls() == nil:
+		err = fmt.Errorf("polls are not available")
+	default:
+		pollID, err = a.Polls().SendPoll(chat, p.Question, p.Options, p.Multi)
+	}
+	if err != nil {
+		if ctx.ToolOutputs != nil {
+			*ctx.ToolOutputs = append(*ctx.ToolOutputs, fmt.Sprintf("[send_poll] Failed: %v", err))
+		}
+		return err
+	}
+	fmt.Printf("Poll #%s sent to %s\n", pollID, chat)
+	if ctx.ToolOutputs != nil {
+		*ctx.ToolOutputs = append(*ctx.ToolOutputs, fmt.Sprintf("[send_poll] Sent poll #%s to %s: %s (check the votes with poll_results)", pollID, chat, p.Question))
+	}
+	return nil
+}
+
+// --- PollResultsAction ---
+
+type PollResultsAction struct {
+	Polls func() Polls
+}
+
+func (a *PollResultsAction) GetSchema() ActionSchema {
+	return ActionSchema{
+		Name:        "poll_results",
+		Description: "Get the vote tally of a poll, or of the latest polls. In task and behavior mode only the polls of the conversation are visible.",
+		Parameters: json.RawMessage(`{
+			"type": "object",
+			"properties": {
+				"poll_id": {"type": "string", "description": "ID of the poll message (optional)."},
+				"chat": {"type": "string", "description": "Chat JID to list the polls of (command mode only, optional)."}
+			}
+		}`),
+	}
+}
+
+func (a *PollResultsAction) Execute(ctx ActionContext, payload json.RawMessage) error {
+	var input struct {
+		PollID string `json:"poll_id"`
+		Chat   string `json:"chat"`
+	}
+	if len(payload) > 0 && payload[0] == '{' {
+		if err := json.Unmarshal(payload, &input); err != nil {
+			return fmt.Errorf("invalid payload for poll_results: %w", err)
+		}
+	}
+	if a.Polls == nil || a.Polls() == nil {
+		return fmt.Errorf("polls are not available")
+	}
+
+	chat := operationChat(ctx, messageOp{Chat: input.Chat})
+	summaries, err := a.Polls().PollResults(chat, strings.TrimPrefix(strings.TrimSpace(input.PollID), "#"), 5)
+	output := "[poll_results]\n" + strings.Join(summaries, "\n")
+	if err != nil {
+		output = fmt.Sprintf("[poll_results] Failed: %v", err)
+	} else if len(summaries) == 0 {
+		output = "[poll_results] No polls found."
+	}
+	if ctx.ToolOutputs != nil {
+		*ctx.ToolOutputs = append(*ctx.ToolOutputs, output)
+	}
+	return err
+}
@@ -0,0 +1,94 @@
+package actions
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+	"whatsabladerunner/pkg/audit"
+	"whatsabladerunner/pkg/history"
+)
+
+// SearchHistoryAction searches the stored conversations. Outside command mode the
+// search is scoped to the chat of the task or behavior.
+type SearchHistoryAction struct {
+	SearchFunc func(q history.SearchQuery) ([]history.SearchResult, error)
+}
+
+func (a *SearchHistoryAction) GetSchema() ActionSchema {
+	return ActionSchema{
+		Name:        "search_history",
+		Description: "Search older messages beyond the conversation context (e.g. an address sent last month). Returns snippets with message IDs.",
+		Parameters: json.RawMessage(`{
+			"type": "object",
+			"properties": {
+				"query": {"type": "string", "description": "Keywords, all must appear."},
+				"chat": {"type": "string", "description": "Chat JID or phone number (command mode only)."},
+				"sender": {"type": "string", "description": "Sender JID or phone number, or 'me' for your own messages."},
+				"since": {"type": "string", "description": "A duration back from now (24h, 30d) or a date (YYYY-MM-DD)."},
+				"until": {"type": "string", "description": "Same format as since."},
+				"limit": {"type": "integer", "description": "Maximum results (default 20, max 50)."}
+			}
+		}`),
+	}
+}
+
+func (a *SearchHistoryAction) Execute(ctx ActionContext, payload json.RawMessage) error {
+	var input struct {
+		Query  string `json:"query"`
+		Chat   string `json:"chat"`
+		Sender string `json:"sender"`
+		Since  string `json:"since"`
+		Until  string `json:"until"`
+		Limit  int    `json:"limit"`
+	}
+	if err := json.Unmarshal(payload, &input); err != nil {
+		// A bare string is the keywords
+		if err := json.Unmarshal(payload, &input.Query); err != nil {
+			return fmt.Errorf("invalid payload for search_history: %w", err)
+		}
+	}
+	if a.SearchFunc == nil {
+		return fmt.Errorf("history search is not available")
+	}
+
+	q := history.SearchQuery{
+		Keywords: input.Query,
+		ChatJID:  operationChat(ctx, messageOp{Chat: input.Chat}),
+		Sender:   input.Sender,
+		Limit:    input.Limit,
+	}
+	if q.Limit > 50 {
+		q.Limit = 50
+	}
+	now := time.Now()
+	var err error
+	if input.Since != "" {
+		q.Since, err = audit.ParseTime(input.Since, now)
+	}
+	if err == nil && input.Until != "" {
+		q.Until, err = audit.ParseTime(input.Until, now)
+	}
+
+	var results []history.SearchResult
+	if err == nil {
+		results, err = a.SearchFunc(q)
+	}
+
+	var sb strings.Builder
+	switch {
+	case err != nil:
+		fmt.Fprintf(&sb, "[search_history] Failed: %v", err)
+	case len(results) == 0:
+		fmt.Fprintf(&sb, "[search_history] No messages found for '%s'.", input.Query)
+	default:
+		fmt.Fprintf(&sb, "[search_history] %d messages for '%s' (newest first):", len(results), input.Query)
+		for _, r := range results {
+			sb.WriteString("\n" + r.Format())
+		}
+	}
+	if ctx.ToolOutputs != nil {
+		*ctx.ToolOutputs = append(*ctx.ToolOutputs, sb.String())
+	}
+	return err
+}
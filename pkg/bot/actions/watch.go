@@ -0,0 +1,124 @@
+package actions
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+)
+
+// Processing modes, set in ActionContext.Mode
+const (
+	ModeCommand  = "command"
+	ModeTask     = "task"
+	ModeBehavior = "behavior"
+)
+
+// WatchPolicy controls when the watcher reviews an action before it runs
+type WatchPolicy string
+
+const (
+	WatchAlways   WatchPolicy = "always"   // Review every time
+	WatchNever    WatchPolicy = "never"    // Never review
+	WatchExternal WatchPolicy = "external" // Review when the action is external (see Review.External)
+)
+
+// Review is the rendering of an action shown to the watcher
+type Review struct {
+	Action  string // Action type
+	Summary string // What the action is about to do (message text, request, ...)
+	Target  string // Chat JID, "master", "memory" or the external service
+	// External is true when the action reaches someone other than the master,
+	// or runs on behalf of a third party conversation (task/behavior mode)
+	External bool
+}
+
+// Reviewable is implemented by actions that can describe themselves for the watcher.
+// Actions that don't implement it are not reviewed unless a policy says "always".
+type Reviewable interface {
+	Review(ctx ActionContext, payload json.RawMessage) Review
+}
+
+// WatchPolicies holds the per-action watcher policies, stored in config/watcher/policy.json
+type WatchPolicies struct {
+	Default WatchPolicy            `json:"default"` // For reviewable actions without an entry
+	Actions map[string]WatchPolicy `json:"actions"`
+}
+
+// DefaultWatchPolicies reviews reviewable actions when they are external
+func DefaultWatchPolicies() *WatchPolicies {
+	return &WatchPolicies{
+		Default: WatchExternal,
+		Actions: map[string]WatchPolicy{
+			"message_master":       WatchNever,
+			"send_media_to_master": WatchNever,
+		},
+	}
+}
+
+// LoadWatchPolicies reads the policy file. A missing file means the defaults.
+func LoadWatchPolicies(path string) (*WatchPolicies, error) {
+	policies := DefaultWatchPolicies()
+	data, err := os.ReadFile(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return policies, nil
+		}
+		return nil, fmt.Errorf("failed to read watcher policy: %w", err)
+	}
+
+	var loaded WatchPolicies
+	if err := json.Unmarshal(data, &loaded); err != nil {
+		return nil, fmt.Errorf("failed to parse watcher policy: %w", err)
+	}
+	if loaded.Default != "" {
+		policies.Default = loaded.Default
+	}
+	for name, p := range loaded.Actions {
+		policies.Actions[name] = p
+	}
+	for name, p := range policies.Actions {
+		if p != WatchAlways && p != WatchNever && p != WatchExternal {
+			return nil, fmt.Errorf("invalid watcher policy '%s' for action %s", p, name)
+		}
+	}
+	return policies, nil
+}
+
+// ReviewFor returns the watcher rendering of an action and whether it must be reviewed
+func (p *WatchPolicies) ReviewFor(act Action, ctx ActionContext, payload json.RawMessage) (Review, bool) {
+	name := act.GetSchema().Name
+	reviewable, isReviewable := act.(Reviewable)
+
+	policy, ok := p.Actions[name]
+	if !ok {
+		if !isReviewable {
+			return Review{}, false
+		}
+		policy = p.Default
+	}
+	if policy == WatchNever {
+		return Review{}, false
+	}
+
+	var review Review
+	if isReviewable {
+		review = reviewable.Review(ctx, payload)
+	} else {
+		review = Review{Summary: PayloadText(payload), External: ctx.Mode != ModeCommand}
+	}
+	review.Action = name
+
+	if policy == WatchExternal && !review.External {
+		return review, false
+	}
+	return review, true
+}
+
+// PayloadText returns a string payload as is, anything else as raw JSON
+func PayloadText(payload json.RawMessage) string {
+	var s string
+	if err := json.Unmarshal(payload, &s); err == nil {
+		return s
+	}
+	return string(payload)
+}
@@ -0,0 +1,81 @@
+package bot
+
+import (
+	"encoding/json"
+	"fmt"
+	"path/filepath"
+	"strconv"
+	"sync"
+	"time"
+	"whatsabladerunner/pkg/audit"
+	"whatsabladerunner/pkg/bot/actions"
+)
+
+// AuditFile is the action audit database, inside the config directory
+const AuditFile = "audit.db"
+
+// The audit log is shared by every Bot of the process
+var (
+	auditOnce sync.Once
+	auditLog  *audit.Log
+)
+
+// sharedAudit opens the audit log the first time it's called
+func sharedAudit(configDir string) *audit.Log {
+	auditOnce.Do(func() {
+		log, err := audit.Open(filepath.Join(configDir, AuditFile))
+		if err != nil {
+			fmt.Printf("Error opening audit log, actions won't be recorded: %v\n", err)
+			return
+		}
+		auditLog = log
+	})
+	return auditLog
+}
+
+// newTraceID identifies a run in the audit log and the LLM logs
+func newTraceID() string {
+	return strconv.FormatInt(time.Now().UnixNano(), 10)
+}
+
+// newAuditEntry starts the record of a dispatched action. The payload is stored with
+// the watcher redact rules applied; executeAction replaces it once the rules ran.
+func (b *Bot) newAuditEntry(ctx actions.ActionContext, raw RawAction) *audit.Entry {
+	entry := &audit.Entry{
+		TraceID:     ctx.TraceID,
+		Mode:        ctx.Mode,
+		BehaviorIDs: ctx.Behaviors,
+		ChatJID:     ctx.ChatJID,
+		Action:      raw.Type,
+		Payload:     b.auditPayload(raw.Content, ctx),
+		Verdict:     audit.VerdictNotReviewed,
+	}
+	if ctx.Task != nil {
+		entry.TaskID = ctx.Task.ID
+		entry.ChatJID = taskChat(ctx.Task)
+	}
+	return entry
+}
+
+// auditPayload applies the watcher redact rules to a payload for the audit log
+func (b *Bot) auditPayload(payload json.RawMessage, ctx actions.ActionContext) string {
+	if b.WatchRules == nil {
+		return string(payload)
+	}
+	return string(b.WatchRules.RedactPayload(payload, watchScope(actions.Review{}, ctx)))
+}
+
+// recordAction completes the record with the outcome and stores it
+func (b *Bot) recordAction(entry *audit.Entry, toolOutputs []string, err error) {
+	if b.Audit == nil {
+		return
+	}
+	if err != nil {
+		entry.Status = audit.StatusFailed
+		entry.Error = err.Error()
+	}
+	entry.ToolOutputs = toolOutputs
+	if rerr := b.Audit.Record(*entry); rerr != nil {
+		fmt.Printf("Warning: %v\n", rerr)
+	}
+}
@@ -5,12 +5,18 @@ import (
 	"fmt"
 	"os"
 	"path/filepath"
+	"strconv"
 	"strings"
+	"whatsabladerunner/pkg/audit"
 	"whatsabladerunner/pkg/behaviors"
 	"whatsabladerunner/pkg/bot/actions"
+	"whatsabladerunner/pkg/history"
+	"whatsabladerunner/pkg/language"
 	"whatsabladerunner/pkg/llm"
 	"whatsabladerunner/pkg/prompt"
 	"whatsabladerunner/pkg/tasks"
+	"whatsabladerunner/pkg/watcher"
+	"whatsabladerunner/pkg/withheld"
 )
 
 type Bot struct {
@@ -18,6 +24,7 @@ type Bot struct {
 	PromptManager   *prompt.PromptManager
 	TaskManager     *tasks.TaskManager
 	BehaviorManager *behaviors.BehaviorManager
+	Templates       *behaviors.TemplateStore
 	ActionRegistry  *actions.Registry
 	ConfigDir       string
 
@@ -29,13 +36,41 @@ type Bot struct {
 	StartTaskCallback      func(*tasks.Task)                   // Called when a task is confirmed to start it
 	ResumeTaskCallback     func(*tasks.Task)                   // Called when a task is resumed
 
-	// OnWatcherBlock is called when watcher blocks a message, allowing the caller to store it for potential override
-	OnWatcherBlock func(blockedMsg string, targetChatJID string, sendFunc func(string))
+	// Withheld stores actions blocked by the watcher until the master decides
+	Withheld *withheld.Queue
+	// ChatSender returns a function sending text to a chat, used to release or retry withheld actions
+	ChatSender func(chatJID string) func(string)
+	// Messenger reacts to, marks as read, edits and deletes messages; nil disables those actions
+	Messenger actions.Messenger
+	// Polls sends polls and summarizes their votes; nil disables the poll actions
+	Polls actions.Polls
+	// ChatReplier returns a function sending text to a chat quoting one of its stored messages
+	ChatReplier func(chatJID string) func(text, messageID string)
 
 	// CurrentTaskID is set during ProcessTask to enable proper message tagging
 	CurrentTaskID int
 
 	SearchContactsFunc func(query string) string
+	// SearchHistoryFunc searches the stored messages; nil disables search_history
+	SearchHistoryFunc func(q history.SearchQuery) ([]history.SearchResult, error)
+
+	// WatchPolicies decides which actions the watcher reviews (config/watcher/policy.json)
+	WatchPolicies *actions.WatchPolicies
+	// WatchRules are the deterministic watcher rules checked before the LLM watcher
+	WatchRules *watcher.RuleSet
+	// WatchStats tallies checks and blocks per watcher rule
+	WatchStats *watcher.Stats
+	// Permissions decides which actions each mode may use (config/permissions.json)
+	Permissions *actions.Permissions
+	// Shadow reports whether every task and behavior runs in shadow mode; nil means off
+	Shadow func() bool
+	// Audit records every dispatched action (config/audit.db); nil disables it
+	Audit *audit.Log
+
+	// Language returns the configured prompt language; nil means language.Default
+	Language func() string
+	// Languages holds the per-contact language overrides (config/languages.json)
+	Languages *language.Overrides
 }
 
 func NewBot(client llm.Client, configDir string, sendFunc func(string), sendMasterFunc func(string), contacts string, reporter tasks.Reporter) *Bot {
@@ -44,6 +79,9 @@ func NewBot(client llm.Client, configDir string, sendFunc func(string), sendMast
 		PromptManager:   prompt.NewPromptManager(configDir),
 		TaskManager:     tasks.NewTaskManager(filepath.Join(configDir, "tasks")),
 		BehaviorManager: behaviors.NewBehaviorManager(filepath.Join(configDir, "behaviors")),
+		Templates:       behaviors.NewTemplateStore(filepath.Join(configDir, "modes", "behavior")),
+		Withheld:        sharedWithheld(configDir),
+		Languages:       language.NewOverrides(filepath.Join(configDir, "languages.json")),
 		ActionRegistry:  actions.NewRegistry(),
 		ConfigDir:       configDir,
 		SendFunc:        sendFunc,
@@ -52,6 +90,30 @@ func NewBot(client llm.Client, configDir string, sendFunc func(string), sendMast
 	}
 	b.TaskManager.Reporter = reporter
 	b.TaskManager.SendFunc = sendMasterFunc
+	b.BehaviorManager.ContactSetsPath = filepath.Join(configDir, "contact_sets.json")
+
+	policies, err := actions.LoadWatchPolicies(filepath.Join(configDir, "watcher", "policy.json"))
+	if err != nil {
+		fmt.Printf("Error loading watcher policy, using defaults: %v\n", err)
+		policies = actions.DefaultWatchPolicies()
+	}
+	b.WatchPolicies = policies
+
+	rules, err := watcher.LoadRuleSet(filepath.Join(configDir, "watcher"))
+	if err != nil {
+		fmt.Printf("Error loading watcher rules, using defaults: %v\n", err)
+		rules = &watcher.RuleSet{Rules: watcher.DefaultRules(), LLMReview: watcher.LLMReviewAlways, LLMMode: watcher.LLMModeBatch, OnError: watcher.FailClosed}
+	}
+	b.WatchRules = rules
+	b.WatchStats = sharedWatchStats(configDir)
+
+	permissions, err := actions.LoadPermissions(filepath.Join(configDir, "permissions.json"))
+	if err != nil {
+		fmt.Printf("Error loading permissions, using defaults: %v\n", err)
+		permissions = actions.DefaultPermissions()
+	}
+	b.Permissions = permissions
+	b.Audit = sharedAudit(configDir)
 
 	b.registerActions()
 	return b
@@ -61,6 +123,17 @@ func (b *Bot) registerActions() {
 	// Behaviors
 	b.ActionRegistry.Register(&actions.EnableBehaviorAction{})
 	b.ActionRegistry.Register(&actions.DisableBehaviorAction{})
+	b.ActionRegistry.Register(&actions.UpdateBehaviorAction{})
+	b.ActionRegistry.Register(&actions.ArchiveBehaviorAction{})
+	b.ActionRegistry.Register(&actions.ListBehaviorsAction{})
+	b.ActionRegistry.Register(&actions.BehaviorActivityAction{})
+
+	// Behavior templates
+	b.ActionRegistry.Register(&actions.CreateBehaviorTemplateAction{Templates: b.Templates, Preview: b.PreviewBehaviorTemplate})
+	b.ActionRegistry.Register(&actions.EditBehaviorTemplateAction{Templates: b.Templates, Preview: b.PreviewBehaviorTemplate})
+	b.ActionRegistry.Register(&actions.PreviewBehaviorTemplateAction{Templates: b.Templates, Preview: b.PreviewBehaviorTemplate})
+	b.ActionRegistry.Register(&actions.DeleteBehaviorTemplateAction{Templates: b.Templates})
+	b.ActionRegistry.Register(&actions.RollbackBehaviorTemplateAction{Templates: b.Templates, Preview: b.PreviewBehaviorTemplate})
 
 	// Memory Update & Append
 	b.ActionRegistry.Register(&actions.MemoryUpdateAction{
@@ -72,14 +145,7 @@ func (b *Bot) registerActions() {
 
 	// Response
 	b.ActionRegistry.Register(&actions.ResponseAction{
-		SendFunc:       b.SendFunc,
-		CheckMessage:   b.CheckMessage,
-		SendMasterFunc: b.SendMasterFunc,
-		OnWatcherBlock: func(blockedMsg string, targetChatJID string, sendFunc func(string)) {
-			if b.OnWatcherBlock != nil {
-				b.OnWatcherBlock(blockedMsg, targetChatJID, sendFunc)
-			}
-		},
+		SendFunc: b.SendFunc,
 	})
 
 	// Message Master
@@ -100,6 +166,7 @@ func (b *Bot) registerActions() {
 		actions.TaskConfirm,
 		actions.TaskPause,
 		actions.TaskResume,
+		actions.TaskFinish,
 	}
 	for _, t := range taskActions {
 		b.ActionRegistry.Register(&actions.TaskManagementAction{
@@ -118,6 +185,18 @@ func (b *Bot) registerActions() {
 		})
 	}
 
+	// Audit log
+	if b.Audit != nil {
+		b.ActionRegistry.Register(&actions.AuditAction{Log: b.Audit})
+	}
+
+	// Language
+	b.ActionRegistry.Register(&actions.SetLanguageAction{
+		Overrides:   b.Languages,
+		TaskManager: b.TaskManager,
+		SendFunc:    b.SendFunc,
+	})
+
 	// Send Media
 	b.ActionRegistry.Register(&actions.SendMediaAction{
 		SendMediaFunc: b.SendMediaFunc,
@@ -128,6 +207,18 @@ func (b *Bot) registerActions() {
 		ToMaster:      true,
 	})
 
+	// Reactions, read receipts, edits and deletions (Messenger is set after NewBot)
+	messenger := func() actions.Messenger { return b.Messenger }
+	b.ActionRegistry.Register(actions.NewReactAction(messenger))
+	b.ActionRegistry.Register(actions.NewMarkReadAction(messenger))
+	b.ActionRegistry.Register(actions.NewEditMessageAction(messenger))
+	b.ActionRegistry.Register(actions.NewDeleteMessageAction(messenger))
+
+	// Polls
+	polls := func() actions.Polls { return b.Polls }
+	b.ActionRegistry.Register(&actions.SendPollAction{Polls: polls})
+	b.ActionRegistry.Register(&actions.PollResultsAction{Polls: polls})
+
 	// Button Response
 	b.ActionRegistry.Register(&actions.ButtonResponseAction{
 		SendButtonResponseFunc: b.SendButtonResponseFunc,
@@ -141,11 +232,24 @@ func (b *Bot) registerActions() {
 		})
 	}
 
+	// Search History (SearchHistoryFunc is set after NewBot)
+	b.ActionRegistry.Register(&actions.SearchHistoryAction{
+		SearchFunc: func(q history.SearchQuery) ([]history.SearchResult, error) {
+			if b.SearchHistoryFunc == nil {
+				return nil, fmt.Errorf("history search is not available")
+			}
+			return b.SearchHistoryFunc(q)
+		},
+	})
+
 	// Custom Actions
 	actionsDir := filepath.Join(b.ConfigDir, "actions")
-	if err := actions.LoadCustomActions(actionsDir, b.ActionRegistry); err != nil {
+	if err := actions.LoadCustomActions(actionsDir, b.ActionRegistry, b.SendMasterFunc); err != nil {
 		fmt.Printf("Error loading custom actions: %v\n", err)
 	}
+
+	// MCP server tools
+	b.registerMCPActions()
 }
 
 // RawAction is used for initial parsing to handle flexible content types
@@ -170,60 +274,217 @@ type BotResponse struct {
 }
 
 type WatcherResponse struct {
+	Action   string        `json:"action"` // Single verdict (legacy format), applies to every rule
+	Reason   string        `json:"reason"`
+	Verdicts []RuleVerdict `json:"verdicts"`
+}
+
+// RuleVerdict is the watcher verdict for one rule file
+type RuleVerdict struct {
+	Rule   string `json:"rule"`
 	Action string `json:"action"`
 	Reason string `json:"reason"`
 }
 
+// CheckMessage asks the watcher to review a message to a contact
 func (b *Bot) CheckMessage(proposedMsg string, context []string) (bool, string, error) {
+	review := actions.Review{Action: "response", Summary: proposedMsg, Target: "contact", External: true}
+	return b.CheckAction(review, actions.ActionContext{Context: context})
+}
+
+// CheckAction asks the LLM watcher to review an action rendering against the
+// rule files in scope. Each rule gets its own verdict; block reasons name the rule.
+func (b *Bot) CheckAction(review actions.Review, ctx actions.ActionContext) (bool, string, error) {
 	watcherData := prompt.WatcherData{
-		ProposedMessage: proposedMsg,
-		Context:         strings.Join(context, "\n"),
+		ProposedMessage: review.Summary,
+		Action:          review.Action,
+		Target:          review.Target,
+		Context:         strings.Join(ctx.Context, "\n"),
+	}
+	if ctx.Mode != actions.ModeCommand && review.External {
+		watcherData.Language = b.languageFor(ctx.Task, watchScope(review, ctx).Contact, ctx.Context)
 	}
 
-	watcherPrompt, err := b.PromptManager.LoadWatcherPrompt(watcherData)
+	rules, err := b.PromptManager.LoadWatcherRules(watcherData)
 	if err != nil {
-		return false, "", fmt.Errorf("failed to load watcher prompt: %w", err)
+		return false, "", fmt.Errorf("failed to load watcher rules: %w", err)
+	}
+	rules = b.watcherRulesInScope(rules, review, ctx)
+	if len(rules) == 0 {
+		return true, "", nil
 	}
 
-	sysPrompt, err := b.PromptManager.LoadSystemPrompt("Spanish")
+	sysPrompt, err := b.PromptManager.LoadSystemPrompt(b.defaultLanguage())
 	if err != nil {
 		return false, "", fmt.Errorf("failed to load system prompt: %w", err)
 	}
 
+	verdicts := map[string]RuleVerdict{}
+	if b.WatchRules != nil && b.WatchRules.LLMMode == watcher.LLMModeParallel && len(rules) > 1 {
+		type result struct {
+			verdicts map[string]RuleVerdict
+			err      error
+		}
+		results := make(chan result, len(rules))
+		for _, rule := range rules {
+			go func(rule prompt.WatcherRule) {
+				v, err := b.askWatcher(sysPrompt, watcherData, []prompt.WatcherRule{rule}, ctx.TraceID)
+				results <- result{v, err}
+			}(rule)
+		}
+		for range rules {
+			r := <-results
+			if r.err != nil {
+				err = r.err
+				continue
+			}
+			for id, v := range r.verdicts {
+				verdicts[id] = v
+			}
+		}
+		if err != nil {
+			return false, "", err
+		}
+	} else {
+		verdicts, err = b.askWatcher(sysPrompt, watcherData, rules, ctx.TraceID)
+		if err != nil {
+			return false, "", err
+		}
+	}
+
+	var reasons []string
+	for _, rule := range rules {
+		v := verdicts[rule.ID]
+		blocked := v.Action == "block"
+		if b.WatchStats != nil {
+			if err := b.WatchStats.Record(watcher.KindLLM, rule.ID, blocked, v.Reason); err != nil {
+				fmt.Printf("Warning: %v\n", err)
+			}
+		}
+		if blocked {
+			reasons = append(reasons, fmt.Sprintf("[%s] %s", rule.ID, v.Reason))
+		}
+	}
+	if len(reasons) > 0 {
+		return false, strings.Join(reasons, "; "), nil
+	}
+	return true, "", nil
+}
+
+// defaultLanguage returns the configured prompt language
+func (b *Bot) defaultLanguage() string {
+	if b.Language != nil {
+		if lang := b.Language(); lang != "" {
+			return lang
+		}
+	}
+	return language.Default
+}
+
+// languageFor resolves the language of a chat: task override, contact override,
+// language detected in the contact's recent messages, then the configured one
+func (b *Bot) languageFor(task *tasks.Task, chatJID string, context []string) string {
+	if task != nil && task.Language != "" {
+		return task.Language
+	}
+	if b.Languages != nil {
+		if lang := b.Languages.For(chatJID); lang != "" {
+			return lang
+		}
+	}
+	if lang := language.Detect(context); lang != "" {
+		return lang
+	}
+	return b.defaultLanguage()
+}
+
+// askWatcher runs one watcher call for the given rules and returns a verdict per rule
+func (b *Bot) askWatcher(sysPrompt string, data prompt.WatcherData, rules []prompt.WatcherRule, trace string) (map[string]RuleVerdict, error) {
+	watcherPrompt, err := b.PromptManager.LoadWatcherRulesPrompt(data, rules)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load watcher prompt: %w", err)
+	}
+
 	msgs := []llm.Message{
 		{Role: "system", Content: sysPrompt},
 		{Role: "user", Content: watcherPrompt},
 	}
 
-	///fmt.Printf("DEBUG: Sending to Watcher:\n--- System Prompt ---\n%s\n--- Watcher Prompt ---\n%s\n---------------------\n", sysPrompt, watcherPrompt)
-
-	respMsg, err := b.Client.Chat(msgs, map[string]interface{}{"log_tag": "watcher"})
+	respMsg, err := b.Client.Chat(msgs, map[string]interface{}{"log_tag": "watcher", "trace_id": trace})
 	if err != nil {
-		return false, "", fmt.Errorf("watcher ollama chat failed: %w", err)
+		return nil, fmt.Errorf("watcher ollama chat failed: %w", err)
 	}
 
 	content := cleanJSON(respMsg.Content)
 	var watcherResp WatcherResponse
 	if err := json.Unmarshal([]byte(content), &watcherResp); err != nil {
-		return false, "", fmt.Errorf("failed to parse watcher response json: %w", err)
+		return nil, fmt.Errorf("failed to parse watcher response json: %w", err)
+	}
+
+	verdicts := map[string]RuleVerdict{}
+	for _, v := range watcherResp.Verdicts {
+		verdicts[v.Rule] = v
+	}
+	for _, rule := range rules {
+		if _, ok := verdicts[rule.ID]; ok {
+			continue
+		}
+		if watcherResp.Action == "" {
+			return nil, fmt.Errorf("watcher gave no verdict for rule %s", rule.ID)
+		}
+		verdicts[rule.ID] = RuleVerdict{Rule: rule.ID, Action: watcherResp.Action, Reason: watcherResp.Reason}
 	}
+	return verdicts, nil
+}
 
-	if watcherResp.Action == "block" {
-		return false, watcherResp.Reason, nil
+// watcherRulesInScope keeps the rule files whose scope matches the action
+func (b *Bot) watcherRulesInScope(rules []prompt.WatcherRule, review actions.Review, ctx actions.ActionContext) []prompt.WatcherRule {
+	scope := watchScope(review, ctx)
+	var behaviorKeys []string
+	for _, id := range ctx.Behaviors {
+		behaviorKeys = append(behaviorKeys, strconv.Itoa(id))
+		if b.BehaviorManager != nil {
+			if behavior, err := b.BehaviorManager.LoadBehavior(id); err == nil {
+				behaviorKeys = append(behaviorKeys, behavior.Name)
+			}
+		}
 	}
 
-	return true, "", nil
+	var inScope []prompt.WatcherRule
+	for _, rule := range rules {
+		if len(rule.Contacts) > 0 && !anyMatch(rule.Contacts, func(c string) bool {
+			return scope.Contact != "" && behaviors.MatchJID(c, scope.Contact)
+		}) {
+			continue
+		}
+		if len(rule.Tasks) > 0 && !anyMatch(rule.Tasks, func(id int) bool { return id == scope.TaskID }) {
+			continue
+		}
+		if len(rule.Behaviors) > 0 && !anyMatch(rule.Behaviors, func(key string) bool {
+			return anyMatch(behaviorKeys, func(k string) bool { return strings.TrimPrefix(key, "#") == k })
+		}) {
+			continue
+		}
+		if len(rule.Actions) > 0 && !anyMatch(rule.Actions, func(a string) bool { return a == review.Action }) {
+			continue
+		}
+		inScope = append(inScope, rule)
+	}
+	return inScope
 }
 
-func (b *Bot) getAvailableActionsJSON(mode string) string {
-	var exclude []string
-	if mode == "command" {
-		exclude = []string{"message_master"}
-	} else {
-		// Only command mode should have search_contacts
-		exclude = []string{"search_contacts"}
+func anyMatch[T any](items []T, match func(T) bool) bool {
+	for _, item := range items {
+		if match(item) {
+			return true
+		}
 	}
-	schemas := b.ActionRegistry.GetSchemasFiltered(exclude)
+	return false
+}
+
+// getAvailableActionsJSON lists the schemas of the permitted actions for the prompt
+func (b *Bot) getAvailableActionsJSON(allowed func(string) bool) string {
+	schemas := b.ActionRegistry.GetSchemasWhere(allowed)
 	data, err := json.MarshalIndent(schemas, "", "  ")
 	if err != nil {
 		return "[]"
@@ -232,24 +493,51 @@ func (b *Bot) getAvailableActionsJSON(mode string) string {
 }
 
 func (b *Bot) getAvailableBehaviors() string {
-	dir := filepath.Join(b.ConfigDir, "modes", "behavior")
-	entries, err := os.ReadDir(dir)
+	templates, err := b.Templates.ListTemplates()
 	if err != nil {
 		return ""
 	}
+	return strings.Join(templates, ", ")
+}
 
-	var templates []string
-	for _, entry := range entries {
-		if !entry.IsDir() && strings.HasSuffix(entry.Name(), ".txt") && !strings.HasPrefix(entry.Name(), "__") {
-			templates = append(templates, strings.TrimSuffix(entry.Name(), ".txt"))
+// FilterBehaviors keeps only the behaviors whose template trigger matches the message.
+// Behaviors without a trigger file (or with an unreadable one) always pass.
+func (b *Bot) FilterBehaviors(active []behaviors.Behavior, in behaviors.TriggerInput) []behaviors.Behavior {
+	dir := filepath.Join(b.ConfigDir, "modes", "behavior")
+	var matched []behaviors.Behavior
+	for _, behavior := range active {
+		trigger, err := behaviors.LoadTrigger(dir, behavior.Name)
+		if err != nil {
+			fmt.Printf("Warning: %v (behavior %d runs unfiltered)\n", err, behavior.ID)
+			matched = append(matched, behavior)
+			continue
 		}
+		if ok, reason := trigger.Match(in); !ok {
+			fmt.Printf("[Behavior] Skipping behavior %d (%s): %s\n", behavior.ID, behavior.Name, reason)
+			continue
+		}
+		matched = append(matched, behavior)
 	}
-	return strings.Join(templates, ", ")
+	return matched
+}
+
+// BehaviorsWantMedia reports whether any behavior trigger explicitly asks for the media type
+func (b *Bot) BehaviorsWantMedia(active []behaviors.Behavior, mediaType string) bool {
+	dir := filepath.Join(b.ConfigDir, "modes", "behavior")
+	for _, behavior := range active {
+		trigger, err := behaviors.LoadTrigger(dir, behavior.Name)
+		if err == nil && trigger != nil && trigger.WantsMedia(mediaType) {
+			return true
+		}
+	}
+	return false
 }
 
 func (b *Bot) Process(mode string, msg string, context []string) (*BotResponse, error) {
+	trace := newTraceID()
+
 	// 1. Load System Prompt
-	sysPrompt, err := b.PromptManager.LoadSystemPrompt("Spanish")
+	sysPrompt, err := b.PromptManager.LoadSystemPrompt(b.defaultLanguage())
 	if err != nil {
 		return nil, fmt.Errorf("failed to load system prompt: %w", err)
 	}
@@ -287,13 +575,14 @@ func (b *Bot) Process(mode string, msg string, context []string) (*BotResponse,
 		}
 	}
 
+	allowed := b.allowedActions(mode, nil, nil)
 	modeData := prompt.ModeData{
 		Memories:         string(memoriesContent),
 		Tasks:            string(tasksJSON),
 		Contacts:         b.Contacts,
 		Context:          strings.Join(context, "\n"),
 		Message:          msg,
-		AvailableActions: b.getAvailableActionsJSON(mode),
+		AvailableActions: b.getAvailableActionsJSON(allowed),
 		Behaviors:        behaviorsList,
 		ActiveBehaviors:  activeBehaviorsJSON,
 	}
@@ -310,7 +599,7 @@ func (b *Bot) Process(mode string, msg string, context []string) (*BotResponse,
 	//fmt.Printf("DEBUG: Sending to Ollama:\n--- System Prompt ---\n%s\n--- Mode Prompt ---\n%s\n---------------------\n", sysPrompt, modePrompt)
 
 	// Note: Client uses default options (Temperature 0.13, etc)
-	respMsg, err := b.Client.Chat(msgs, map[string]interface{}{"log_tag": "mode-" + mode})
+	respMsg, err := b.Client.Chat(msgs, map[string]interface{}{"log_tag": "mode-" + mode, "trace_id": trace})
 	if err != nil {
 		return nil, fmt.Errorf("ollama chat failed: %w", err)
 	}
@@ -353,7 +642,7 @@ func (b *Bot) Process(mode string, msg string, context []string) (*BotResponse,
 			msgs[1].Content = modePrompt
 
 			// Re-run Chat
-			respMsg, err = b.Client.Chat(msgs, map[string]interface{}{"log_tag": "mode-" + mode})
+			respMsg, err = b.Client.Chat(msgs, map[string]interface{}{"log_tag": "mode-" + mode, "trace_id": trace})
 			if err != nil {
 				return nil, fmt.Errorf("ollama chat failed in recursion: %w", err)
 			}
@@ -376,14 +665,23 @@ func (b *Bot) Process(mode string, msg string, context []string) (*BotResponse,
 			}
 
 			ctx := actions.ActionContext{
+				Mode:            actions.ModeCommand,
+				Message:         msg,
 				Context:         context,
 				BehaviorManager: b.BehaviorManager,
 				ToolOutputs:     &toolOutputs,
+				TraceID:         trace,
 			}
 
-			if err := act.Execute(ctx, rawAction.Content); err != nil {
+			entry, before := b.newAuditEntry(ctx, rawAction), len(toolOutputs)
+			var err error
+			if !allowed(rawAction.Type) {
+				b.denyAction(rawAction.Type, mode, ctx)
+				entry.Status = audit.StatusDenied
+			} else if err = b.executeAction(act, ctx, rawAction.Content, entry); err != nil {
 				fmt.Printf("Error executing action %s: %v\n", rawAction.Type, err)
 			}
+			b.recordAction(entry, toolOutputs[before:], err)
 
 			// Parse content to string
 			var contentStr string
@@ -414,8 +712,10 @@ func (b *Bot) Process(mode string, msg string, context []string) (*BotResponse,
 // ProcessTask processes a message in task mode for a specific task
 // It sets CurrentTask in the mode data and transitions task to running on first response
 func (b *Bot) ProcessTask(task *tasks.Task, msg string, context []string, sendToContact func(string)) (*BotResponse, error) {
+	trace := newTraceID()
+
 	// 1. Load System Prompt
-	sysPrompt, err := b.PromptManager.LoadSystemPrompt("Spanish")
+	sysPrompt, err := b.PromptManager.LoadSystemPrompt(b.languageFor(task, taskChat(task), context))
 	if err != nil {
 		return nil, fmt.Errorf("failed to load system prompt: %w", err)
 	}
@@ -435,6 +735,8 @@ func (b *Bot) ProcessTask(task *tasks.Task, msg string, context []string, sendTo
 	}
 
 	// 5. Load Mode Prompt (task mode)
+	allowed := b.allowedActions(actions.ModeTask, task, nil)
+	shadow := b.shadowed(task, nil)
 	// Send empty tasks and contacts to focus on current task
 	modeData := prompt.ModeData{
 		Memories:         string(memoriesContent),
@@ -443,7 +745,7 @@ func (b *Bot) ProcessTask(task *tasks.Task, msg string, context []string, sendTo
 		Context:          strings.Join(context, "\n"),
 		Message:          msg,
 		CurrentTask:      string(currentTaskJSON),
-		AvailableActions: b.getAvailableActionsJSON("task"),
+		AvailableActions: b.getAvailableActionsJSON(allowed),
 		Behaviors:        "",
 		ActiveBehaviors:  "[]",
 	}
@@ -459,7 +761,7 @@ func (b *Bot) ProcessTask(task *tasks.Task, msg string, context []string, sendTo
 
 	//fmt.Printf("DEBUG: Sending to Ollama (Task Mode):\n--- System Prompt ---\n%s\n--- Mode Prompt ---\n%s\n---------------------\n", sysPrompt, modePrompt)
 
-	respMsg, err := b.Client.Chat(msgs, map[string]interface{}{"log_tag": "task"})
+	respMsg, err := b.Client.Chat(msgs, map[string]interface{}{"log_tag": "task", "trace_id": trace})
 	if err != nil {
 		return nil, fmt.Errorf("ollama chat failed: %w", err)
 	}
@@ -499,7 +801,7 @@ func (b *Bot) ProcessTask(task *tasks.Task, msg string, context []string, sendTo
 			}
 			msgs[1].Content = modePrompt
 
-			respMsg, err = b.Client.Chat(msgs, map[string]interface{}{"log_tag": "task"})
+			respMsg, err = b.Client.Chat(msgs, map[string]interface{}{"log_tag": "task", "trace_id": trace})
 			if err != nil {
 				return nil, fmt.Errorf("ollama chat failed in recursion: %w", err)
 			}
@@ -522,16 +824,27 @@ func (b *Bot) ProcessTask(task *tasks.Task, msg string, context []string, sendTo
 			}
 
 			ctx := actions.ActionContext{
+				Mode:            actions.ModeTask,
+				Message:         msg,
 				Context:         context,
 				Task:            task,
 				BehaviorManager: b.BehaviorManager,
 				SendToContact:   sendToContact,
+				ReplyToContact:  b.replier(taskChat(task)),
 				ToolOutputs:     &toolOutputs,
+				Shadow:          shadow,
+				TraceID:         trace,
 			}
 
-			if err := act.Execute(ctx, rawAction.Content); err != nil {
+			entry, before := b.newAuditEntry(ctx, rawAction), len(toolOutputs)
+			var err error
+			if !allowed(rawAction.Type) {
+				b.denyAction(rawAction.Type, actions.ModeTask, ctx)
+				entry.Status = audit.StatusDenied
+			} else if err = b.executeAction(act, ctx, rawAction.Content, entry); err != nil {
 				fmt.Printf("Error executing action %s: %v\n", rawAction.Type, err)
 			}
+			b.recordAction(entry, toolOutputs[before:], err)
 
 			var contentStr string
 			if rawAction.Content != nil {
@@ -556,10 +869,22 @@ func (b *Bot) ProcessTask(task *tasks.Task, msg string, context []string, sendTo
 	return botResp, nil
 }
 
-// ProcessBehaviors processes a message with active behaviors enabled
-func (b *Bot) ProcessBehaviors(activeBehaviors []behaviors.Behavior, msg string, context []string, sendToContact func(string)) (*BotResponse, error) {
+// ProcessBehaviors processes a message with active behaviors enabled.
+// Every run is recorded in the activity log of each behavior involved.
+func (b *Bot) ProcessBehaviors(chatJID string, activeBehaviors []behaviors.Behavior, msg string, context []string, sendToContact func(string)) (resp *BotResponse, runErr error) {
+	runID := newTraceID()
+	var executed []behaviors.ActivityAction
+	defer func() {
+		b.logBehaviorRun(chatJID, activeBehaviors, runID, msg, executed, runErr)
+	}()
+
+	behaviorIDs := make([]int, len(activeBehaviors))
+	for i, behavior := range activeBehaviors {
+		behaviorIDs[i] = behavior.ID
+	}
+
 	// 1. Load System Prompt
-	sysPrompt, err := b.PromptManager.LoadSystemPrompt("Spanish")
+	sysPrompt, err := b.PromptManager.LoadSystemPrompt(b.languageFor(nil, chatJID, context))
 	if err != nil {
 		return nil, fmt.Errorf("failed to load system prompt: %w", err)
 	}
@@ -581,12 +906,12 @@ func (b *Bot) ProcessBehaviors(activeBehaviors []behaviors.Behavior, msg string,
 			fmt.Printf("Warning: failed to read behavior file %s: %v\n", behavior.Name, err)
 			continue
 		}
-		behaviorsContent.WriteString(fmt.Sprintf("\n--- Behavior: %s (Comments: %s) ---\n", behavior.Name, behavior.Comments))
-		behaviorsContent.WriteString(string(content))
-		behaviorsContent.WriteString("\n-------------------------------------\n")
+		behaviorsContent.WriteString(formatBehaviorBlock(behavior.Name, behavior.Comments, string(content)))
 	}
 
 	// 4. Load Behavior Prompt
+	allowed := b.allowedActions(actions.ModeBehavior, nil, activeBehaviors)
+	shadow := b.shadowed(nil, activeBehaviors)
 	behaviorData := prompt.BehaviorData{
 		ModeData: prompt.ModeData{
 			Memories:         string(memoriesContent),
@@ -594,7 +919,7 @@ func (b *Bot) ProcessBehaviors(activeBehaviors []behaviors.Behavior, msg string,
 			Contacts:         "[]",
 			Context:          strings.Join(context, "\n"),
 			Message:          msg,
-			AvailableActions: b.getAvailableActionsJSON("behavior"),
+			AvailableActions: b.getAvailableActionsJSON(allowed),
 			Behaviors:        "",
 			ActiveBehaviors:  "[]",
 		},
@@ -611,7 +936,7 @@ func (b *Bot) ProcessBehaviors(activeBehaviors []behaviors.Behavior, msg string,
 		{Role: "user", Content: behaviorPrompt},
 	}
 
-	respMsg, err := b.Client.Chat(msgs, map[string]interface{}{"log_tag": "behavior"})
+	respMsg, err := b.Client.Chat(msgs, map[string]interface{}{"log_tag": "behavior", "trace_id": runID})
 	if err != nil {
 		return nil, fmt.Errorf("ollama chat failed: %w", err)
 	}
@@ -649,7 +974,7 @@ func (b *Bot) ProcessBehaviors(activeBehaviors []behaviors.Behavior, msg string,
 			}
 			msgs[1].Content = behaviorPrompt
 
-			respMsg, err = b.Client.Chat(msgs, map[string]interface{}{"log_tag": "behavior"})
+			respMsg, err = b.Client.Chat(msgs, map[string]interface{}{"log_tag": "behavior", "trace_id": runID})
 			if err != nil {
 				return nil, fmt.Errorf("ollama chat failed in recursion: %w", err)
 			}
@@ -672,15 +997,31 @@ func (b *Bot) ProcessBehaviors(activeBehaviors []behaviors.Behavior, msg string,
 
 			// Note: Task is nil for behaviors
 			ctx := actions.ActionContext{
+				Mode:            actions.ModeBehavior,
+				Message:         msg,
+				ChatJID:         chatJID,
+				Behaviors:       behaviorIDs,
 				Context:         context,
 				BehaviorManager: b.BehaviorManager,
 				SendToContact:   sendToContact,
+				ReplyToContact:  b.replier(chatJID),
 				ToolOutputs:     &toolOutputs,
+				Shadow:          shadow,
+				TraceID:         runID,
 			}
 
-			if err := act.Execute(ctx, rawAction.Content); err != nil {
+			var execErr string
+			entry, before := b.newAuditEntry(ctx, rawAction), len(toolOutputs)
+			var err error
+			if !allowed(rawAction.Type) {
+				b.denyAction(rawAction.Type, actions.ModeBehavior, ctx)
+				entry.Status = audit.StatusDenied
+				execErr = "not permitted"
+			} else if err = b.executeAction(act, ctx, rawAction.Content, entry); err != nil {
 				fmt.Printf("Error executing action %s: %v\n", rawAction.Type, err)
+				execErr = err.Error()
 			}
+			b.recordAction(entry, toolOutputs[before:], err)
 
 			var contentStr string
 			if rawAction.Content != nil {
@@ -689,6 +1030,7 @@ func (b *Bot) ProcessBehaviors(activeBehaviors []behaviors.Behavior, msg string,
 				}
 			}
 			botResp.Actions = append(botResp.Actions, Action{Type: rawAction.Type, Content: contentStr})
+			executed = append(executed, behaviors.ActivityAction{Type: rawAction.Type, Content: contentStr, Error: execErr, Shadowed: entry.Status == audit.StatusShadowed})
 		}
 
 		if len(toolOutputs) > 0 {
@@ -702,6 +1044,82 @@ func (b *Bot) ProcessBehaviors(activeBehaviors []behaviors.Behavior, msg string,
 
 	return botResp, nil
 }
+
+// formatBehaviorBlock renders a behavior template as it is embedded in the behavior prompt
+func formatBehaviorBlock(name, comments, content string) string {
+	return fmt.Sprintf("\n--- Behavior: %s (Comments: %s) ---\n%s\n-------------------------------------\n", name, comments, content)
+}
+
+// PreviewBehaviorTemplate renders the full behavior prompt for a template with sample data.
+// It fails if the trigger doesn't compile (a nil trigger checks the saved one), the schedule
+// doesn't parse, the prompt can't be rendered or the template text doesn't reach the prompt.
+func (b *Bot) PreviewBehaviorTemplate(name, content string, trigger json.RawMessage, schedule *behaviors.Schedule, message string) (string, error) {
+	if trigger == nil && b.Templates != nil && b.Templates.Exists(name) {
+		_, saved, err := b.Templates.ReadTemplate(name)
+		if err != nil {
+			return "", err
+		}
+		trigger = json.RawMessage(saved)
+	}
+	if err := behaviors.CheckTemplate(content, trigger); err != nil {
+		return "", err
+	}
+	if err := schedule.Validate(); err != nil {
+		return "", fmt.Errorf("invalid schedule: %w", err)
+	}
+	if message == "" {
+		message = "Hola! ¿Podemos vernos el martes a las 18:00?"
+	}
+	behaviorData := prompt.BehaviorData{
+		ModeData: prompt.ModeData{
+			Memories:         "(memories)",
+			Tasks:            "[]",
+			Contacts:         "[]",
+			Context:          "[sample] Contact: Hola\n[sample] Me: Hola, ¿en qué te ayudo?",
+			Message:          message,
+			AvailableActions: b.getAvailableActionsJSON(b.allowedActions(actions.ModeBehavior, nil, nil)),
+			ActiveBehaviors:  "[]",
+		},
+		EnabledBehaviors: formatBehaviorBlock(name, "preview", content),
+	}
+	rendered, err := b.PromptManager.LoadBehaviorPrompt(behaviorData)
+	if err != nil {
+		return "", err
+	}
+	if !strings.Contains(rendered, content) {
+		return "", fmt.Errorf("template text is missing from the rendered behavior prompt (check __base.txt uses {{.EnabledBehaviors}})")
+	}
+	return rendered, nil
+}
+
+// logBehaviorRun appends the run to the activity log of every behavior involved
+func (b *Bot) logBehaviorRun(chatJID string, activeBehaviors []behaviors.Behavior, runID, trigger string, executed []behaviors.ActivityAction, runErr error) {
+	if b.BehaviorManager == nil {
+		return
+	}
+	shadow := b.shadowed(nil, activeBehaviors)
+	for _, behavior := range activeBehaviors {
+		entry := behaviors.ActivityEntry{
+			RunID:   runID,
+			Contact: chatJID,
+			Trigger: trigger,
+			Actions: executed,
+			Shadow:  shadow,
+		}
+		for _, other := range activeBehaviors {
+			if other.ID != behavior.ID {
+				entry.CoActive = append(entry.CoActive, other.ID)
+			}
+		}
+		if runErr != nil {
+			entry.Error = runErr.Error()
+		}
+		if err := b.BehaviorManager.LogActivity(behavior.ID, entry); err != nil {
+			fmt.Printf("Warning: failed to log activity for behavior %d: %v\n", behavior.ID, err)
+		}
+	}
+}
+
 func cleanJSON(content string) string {
 	content = strings.TrimSpace(content)
 	// Find the start of the JSON object
@@ -0,0 +1,52 @@
+package bot
+
+import (
+	"fmt"
+	"path/filepath"
+	"sync"
+	"whatsabladerunner/pkg/mcp"
+)
+
+// The MCP servers are shared by every Bot of the process
+var (
+	mcpOnce    sync.Once
+	mcpManager *mcp.Manager
+)
+
+// sharedMCP starts the servers of config/mcp.json the first time it's called
+func sharedMCP(configDir string) *mcp.Manager {
+	mcpOnce.Do(func() {
+		cfg, err := mcp.LoadConfig(filepath.Join(configDir, "mcp.json"))
+		if err != nil {
+			fmt.Printf("Error loading MCP config: %v\n", err)
+			return
+		}
+		if len(cfg.Servers) > 0 {
+			mcpManager = mcp.Start(cfg)
+		}
+	})
+	return mcpManager
+}
+
+// registerMCPActions registers the allowed tools of the MCP servers as actions
+func (b *Bot) registerMCPActions() {
+	m := sharedMCP(b.ConfigDir)
+	if m == nil {
+		return
+	}
+	for _, act := range m.Actions() {
+		name := act.GetSchema().Name
+		if _, exists := b.ActionRegistry.Get(name); exists {
+			fmt.Printf("Warning: MCP tool %s clashes with an existing action, skipped\n", name)
+			continue
+		}
+		b.ActionRegistry.Register(act)
+	}
+}
+
+// CloseMCP stops the MCP servers, on shutdown
+func CloseMCP() {
+	if mcpManager != nil {
+		mcpManager.Close()
+	}
+}
@@ -0,0 +1,41 @@
+package bot
+
+import (
+	"fmt"
+	"whatsabladerunner/pkg/behaviors"
+	"whatsabladerunner/pkg/bot/actions"
+	"whatsabladerunner/pkg/tasks"
+)
+
+// allowedActions returns the permission check of a run: the mode permissions,
+// narrowed by the task allowlist and the allowlist of every active behavior
+func (b *Bot) allowedActions(mode string, task *tasks.Task, active []behaviors.Behavior) func(string) bool {
+	permissions := b.Permissions
+	if permissions == nil {
+		permissions = actions.DefaultPermissions()
+	}
+	var narrow [][]string
+	if task != nil {
+		narrow = append(narrow, task.Actions)
+	}
+	for _, behavior := range active {
+		narrow = append(narrow, behavior.Actions)
+	}
+	return func(action string) bool {
+		return permissions.Allowed(mode, action, narrow...)
+	}
+}
+
+// denyAction reports an action the run isn't permitted to use: the LLM gets it
+// back as a tool result and the master is told
+func (b *Bot) denyAction(action, mode string, ctx actions.ActionContext) {
+	fmt.Printf("Permissions DENIED %s in %s mode\n", action, mode)
+	if ctx.ToolOutputs != nil {
+		*ctx.ToolOutputs = append(*ctx.ToolOutputs, fmt.Sprintf("Action '%s' is not permitted here and was not executed. Use only the available actions.", action))
+	}
+	where := mode + " mode"
+	if ctx.ChatJID != "" {
+		where += " for " + ctx.ChatJID
+	}
+	b.notifyWatcher(ctx, fmt.Sprintf("[Permissions] : Denied %s in %s.", action, where))
+}
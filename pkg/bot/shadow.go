@@ -0,0 +1,78 @@
+package bot
+
+import (
+	"encoding/json"
+	"fmt"
+	"whatsabladerunner/pkg/audit"
+	"whatsabladerunner/pkg/behaviors"
+	"whatsabladerunner/pkg/bot/actions"
+	"whatsabladerunner/pkg/tasks"
+)
+
+// shadowed reports whether a task or behavior run is a dry run: shadow mode is on
+// globally, for the task, or for any of the active behaviors (their prompts are combined)
+func (b *Bot) shadowed(task *tasks.Task, active []behaviors.Behavior) bool {
+	if b.Shadow != nil && b.Shadow() {
+		return true
+	}
+	if task != nil && task.Shadow {
+		return true
+	}
+	for _, behavior := range active {
+		if behavior.Shadow {
+			return true
+		}
+	}
+	return false
+}
+
+// readOnlyActions only return results to the LLM, so they run in shadow mode too
+var readOnlyActions = map[string]bool{
+	"list_behaviors": true, "behavior_activity": true, "preview_behavior_template": true,
+	"search_contacts": true, "search_history": true, "poll_results": true, "audit": true,
+}
+
+// runAction executes an approved action. In shadow mode, actions with an effect
+// outside the master's self-chat (messages, media, memory writes, custom and MCP
+// calls) and actions that change the bot state (behavior and task toggles, templates,
+// language, messages to the master) are mirrored to the master instead.
+func (b *Bot) runAction(act actions.Action, ctx actions.ActionContext, payload json.RawMessage, entry *audit.Entry) error {
+	entry.Status = audit.StatusExecuted
+	if !ctx.Shadow {
+		return act.Execute(ctx, payload)
+	}
+	name := act.GetSchema().Name
+	var review actions.Review
+	if reviewable, ok := act.(actions.Reviewable); ok {
+		review = reviewable.Review(ctx, payload)
+		if review.Target == "master" {
+			return act.Execute(ctx, payload)
+		}
+	} else if readOnlyActions[name] {
+		return act.Execute(ctx, payload)
+	} else {
+		review = actions.Review{Summary: actions.PayloadText(payload), Target: "bot"}
+	}
+	entry.Status = audit.StatusShadowed
+
+	target := review.Target
+	if target == "contact" && ctx.ChatJID != "" {
+		target = ctx.ChatJID
+	}
+	fmt.Printf("Shadow: captured %s to %s: %s\n", name, target, review.Summary)
+	if name == "response" {
+		b.notifyShadow(ctx, fmt.Sprintf("would have sent to %s: %s", target, review.Summary))
+	} else {
+		b.notifyShadow(ctx, fmt.Sprintf("would have run %s (%s): %s", name, target, review.Summary))
+	}
+	return nil
+}
+
+// notifyShadow mirrors a captured effect to the master, tagged with its source
+func (b *Bot) notifyShadow(ctx actions.ActionContext, msg string) {
+	tag := "[Shadow]"
+	for _, id := range ctx.Behaviors {
+		tag += fmt.Sprintf("[Behavior %d]", id)
+	}
+	b.notifyWatcher(ctx, tag+" : "+msg)
+}
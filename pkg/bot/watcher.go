@@ -0,0 +1,263 @@
+package bot
+
+import (
+	"encoding/json"
+	"fmt"
+	"path/filepath"
+	"strings"
+	"sync"
+	"whatsabladerunner/pkg/audit"
+	"whatsabladerunner/pkg/bot/actions"
+	"whatsabladerunner/pkg/watcher"
+)
+
+// watcherHealth tracks LLM watcher outages across bots, so the master gets one
+// notice when it goes down and one when it's back
+var watcherHealth struct {
+	sync.Mutex
+	down bool
+}
+
+// The watcher stats are shared by every Bot of the process, so tallies aren't lost
+// between concurrent read-modify-write cycles of the stats file
+var (
+	watchStatsOnce sync.Once
+	watchStats     *watcher.Stats
+)
+
+// sharedWatchStats creates the watcher stats the first time it's called
+func sharedWatchStats(configDir string) *watcher.Stats {
+	watchStatsOnce.Do(func() {
+		watchStats = watcher.NewStats(filepath.Join(configDir, "watcher", "stats.json"))
+	})
+	return watchStats
+}
+
+// executeAction runs an action through the watcher middleware: when the policy
+// requires it, the action rendering is reviewed first and blocked actions are
+// withheld until the master releases, retries or discards them. Actions the policy
+// doesn't review still go through the block and redact rules when they leave the
+// account. The watcher verdict and the outcome are noted in the audit entry.
+func (b *Bot) executeAction(act actions.Action, ctx actions.ActionContext, payload json.RawMessage, entry *audit.Entry) error {
+	entry.Verdict = audit.VerdictNotReviewed
+	if b.WatchPolicies == nil {
+		return b.checkUnreviewed(act, ctx, payload, entry)
+	}
+	review, needed := b.WatchPolicies.ReviewFor(act, ctx, payload)
+	if !needed {
+		return b.checkUnreviewed(act, ctx, payload, entry)
+	}
+
+	// Deterministic rules first: they block, redact or allow without a model call
+	askLLM := true
+	escalated := false
+	if b.WatchRules != nil {
+		result := b.WatchRules.Evaluate(review.Summary, watchScope(review, ctx))
+		b.recordRuleMatches(result)
+		switch result.Verdict {
+		case watcher.VerdictBlock:
+			b.blockByRules(review, result, ctx, payload, entry)
+			return nil
+		case watcher.VerdictAllow:
+			askLLM = false
+		case watcher.VerdictRedact:
+			askLLM = b.WatchRules.LLMReview == watcher.LLMReviewAlways
+		case watcher.VerdictEscalate:
+			askLLM = true
+			escalated = true
+		default:
+			askLLM = b.WatchRules.LLMReview == watcher.LLMReviewAlways
+		}
+		if len(result.RulesFor(watcher.VerdictRedact)) > 0 {
+			payload = b.WatchRules.RedactPayload(payload, watchScope(review, ctx))
+			review.Summary = result.Redacted
+		}
+		entry.Payload = string(payload)
+	}
+	if !askLLM {
+		entry.Verdict = audit.VerdictRulesAllow
+		return b.runAction(act, ctx, payload, entry)
+	}
+
+	proceed, reason, err := b.CheckAction(review, ctx)
+	if err != nil {
+		return b.onWatcherError(act, review, ctx, payload, escalated, err, entry)
+	}
+	b.watcherUp()
+	if proceed {
+		entry.Verdict = audit.VerdictAllow
+		return b.runAction(act, ctx, payload, entry)
+	}
+
+	fmt.Printf("Watcher BLOCKED %s: %s. Reason: %s\n", review.Action, review.Summary, reason)
+	entry.Verdict, entry.Status, entry.Payload = audit.VerdictBlock, audit.StatusBlocked, review.Summary
+	b.onWatcherBlock(review, reason, ctx, payload)
+	return nil
+}
+
+// checkUnreviewed runs an action the policy doesn't review after checking its payload
+// against the deterministic rules: block and redact apply, escalations follow the policy.
+// Actions that stay with the master (see leavesAccount) run as they are.
+func (b *Bot) checkUnreviewed(act actions.Action, ctx actions.ActionContext, payload json.RawMessage, entry *audit.Entry) error {
+	if b.WatchRules == nil {
+		return b.runAction(act, ctx, payload, entry)
+	}
+	review, external := leavesAccount(act, ctx, payload)
+	if !external {
+		return b.runAction(act, ctx, payload, entry)
+	}
+	review.Summary = actions.PayloadText(payload)
+	result := b.WatchRules.Evaluate(review.Summary, watchScope(review, ctx))
+	b.recordRuleMatches(result)
+	if result.Verdict == watcher.VerdictBlock {
+		b.blockByRules(review, result, ctx, payload, entry)
+		return nil
+	}
+	if len(result.RulesFor(watcher.VerdictRedact)) > 0 {
+		payload = b.WatchRules.RedactPayload(payload, watchScope(review, ctx))
+	}
+	entry.Payload = string(payload)
+	return b.runAction(act, ctx, payload, entry)
+}
+
+// leavesAccount renders an action and reports whether it reaches someone other than the
+// master. Messages to the master or the self-chat and the master's own commands that stay
+// in the account (memory notes, new tasks, whose messages are checked as they are sent)
+// don't.
+func leavesAccount(act actions.Action, ctx actions.ActionContext, payload json.RawMessage) (actions.Review, bool) {
+	review := actions.Review{Summary: actions.PayloadText(payload), External: ctx.Mode != actions.ModeCommand}
+	if reviewable, ok := act.(actions.Reviewable); ok {
+		review = reviewable.Review(ctx, payload)
+	}
+	review.Action = act.GetSchema().Name
+	if !review.External || review.Target == "master" || review.Action == "message_master" {
+		return review, false
+	}
+	return review, ctx.Mode != actions.ModeCommand || review.Action != "create_task"
+}
+
+// blockByRules withholds an action blocked by the deterministic rules
+func (b *Bot) blockByRules(review actions.Review, result watcher.Result, ctx actions.ActionContext, payload json.RawMessage, entry *audit.Entry) {
+	reason := fmt.Sprintf("matched watcher rule %s", strings.Join(result.RulesFor(watcher.VerdictBlock), ", "))
+	fmt.Printf("Watcher rules BLOCKED %s: %s\n", review.Action, reason)
+	review.Summary = redactMatches(result, watcher.VerdictBlock)
+	entry.Verdict, entry.Status, entry.Payload = audit.VerdictRulesBlock, audit.StatusBlocked, review.Summary
+	b.onWatcherBlock(review, reason, ctx, payload)
+}
+
+// onWatcherError applies the failure policy of the action scope when the LLM watcher
+// can't give a verdict (model down, unparseable answer, ...)
+func (b *Bot) onWatcherError(act actions.Action, review actions.Review, ctx actions.ActionContext, payload json.RawMessage, escalated bool, err error, entry *audit.Entry) error {
+	policy := watcher.FailClosed
+	if b.WatchRules != nil {
+		policy = b.WatchRules.FailurePolicyFor(watchScope(review, ctx))
+	}
+	fmt.Printf("Watcher unavailable for %s (%s): %v\n", review.Action, policy, err)
+	b.watcherDown(policy, err)
+
+	switch {
+	case policy == watcher.FailOpen:
+		fmt.Printf("Watcher fail-open: running %s unreviewed: %s\n", review.Action, review.Summary)
+		entry.Verdict = audit.VerdictFailOpen
+		return b.runAction(act, ctx, payload, entry)
+	case policy == watcher.RulesOnly && !escalated:
+		fmt.Printf("Watcher rules-only: running %s checked by the deterministic rules only: %s\n", review.Action, review.Summary)
+		entry.Verdict = audit.VerdictRulesOnly
+		return b.runAction(act, ctx, payload, entry)
+	}
+	// Can't verify, so hold it. Retry-policy tasks are withheld too: retrying can't help.
+	entry.Verdict, entry.Status, entry.Payload = audit.VerdictFailClosed, audit.StatusBlocked, review.Summary
+	b.withhold(review, fmt.Sprintf("watcher unavailable (%v)", err), ctx, payload)
+	return nil
+}
+
+// watcherDown tells the master the first time the LLM watcher fails
+func (b *Bot) watcherDown(policy string, err error) {
+	watcherHealth.Lock()
+	defer watcherHealth.Unlock()
+	if watcherHealth.down || b.SendMasterFunc == nil {
+		return
+	}
+	watcherHealth.down = true
+	var what string
+	switch policy {
+	case watcher.FailOpen:
+		what = "Actions are running unreviewed (fail_open)."
+	case watcher.RulesOnly:
+		what = "Actions are only checked by the deterministic rules; escalated ones are held (rules_only)."
+	default:
+		what = "Actions needing review are being held, see 'withheld' (fail_closed)."
+	}
+	b.SendMasterFunc(fmt.Sprintf("[Blady][Watcher] : The watcher is unavailable: %v. %s", err, what))
+}
+
+// watcherUp tells the master when the LLM watcher answers again after an outage
+func (b *Bot) watcherUp() {
+	watcherHealth.Lock()
+	defer watcherHealth.Unlock()
+	if !watcherHealth.down {
+		return
+	}
+	watcherHealth.down = false
+	if b.SendMasterFunc != nil {
+		b.SendMasterFunc("[Blady][Watcher] : The watcher is back, actions are being reviewed again.")
+	}
+}
+
+// watchScope returns the rule scope of an action: its target chat, task and mode
+func watchScope(review actions.Review, ctx actions.ActionContext) watcher.Scope {
+	scope := watcher.Scope{Contact: ctx.ChatJID, Mode: ctx.Mode}
+	if ctx.Task != nil {
+		scope.TaskID = ctx.Task.ID
+		scope.Contact = taskChat(ctx.Task)
+	}
+	if scope.Contact == "" {
+		scope.Contact = review.Target
+	}
+	return scope
+}
+
+// redactMatches hides the matches with the given verdict, so notices don't repeat the leaked data
+func redactMatches(result watcher.Result, verdict string) string {
+	text := result.Redacted
+	for _, m := range result.Matches {
+		if m.Verdict == verdict {
+			text = strings.ReplaceAll(text, m.Text, "[REDACTED:"+m.Rule+"]")
+		}
+	}
+	return text
+}
+
+// recordRuleMatches counts the deterministic rules that matched in the watcher stats
+func (b *Bot) recordRuleMatches(result watcher.Result) {
+	if b.WatchStats == nil {
+		return
+	}
+	seen := map[string]bool{}
+	for _, m := range result.Matches {
+		if seen[m.Rule] {
+			continue
+		}
+		seen[m.Rule] = true
+		blocked := m.Verdict == watcher.VerdictBlock
+		reason := ""
+		if blocked {
+			reason = "matched " + m.Rule
+		}
+		if err := b.WatchStats.Record(watcher.KindDetector, m.Rule, blocked, reason); err != nil {
+			fmt.Printf("Warning: %v\n", err)
+		}
+	}
+}
+
+// notifyWatcher sends a watcher notice to the master, tagged with the task if any
+func (b *Bot) notifyWatcher(ctx actions.ActionContext, msg string) {
+	if b.SendMasterFunc == nil {
+		return
+	}
+	if ctx.Task != nil {
+		b.SendMasterFunc(fmt.Sprintf("[Blady][Task %d] : %s", ctx.Task.ID, msg))
+	} else {
+		b.SendMasterFunc(fmt.Sprintf("[Blady] : %s", msg))
+	}
+}
@@ -0,0 +1,329 @@
+package bot
+
+import (
+	"encoding/json"
+	"fmt"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+	"whatsabladerunner/pkg/audit"
+	"whatsabladerunner/pkg/behaviors"
+	"whatsabladerunner/pkg/bot/actions"
+	"whatsabladerunner/pkg/tasks"
+	"whatsabladerunner/pkg/withheld"
+)
+
+var withheldCommandRe = regexp.MustCompile(`(?i)^\s*(release|discard|retry)\s+#?(\d+)\s*$`)
+
+// The withheld queue is shared by every Bot of the process, so IDs are allocated under one lock
+var (
+	withheldOnce  sync.Once
+	withheldQueue *withheld.Queue
+)
+
+// sharedWithheld creates the withheld queue the first time it's called
+func sharedWithheld(configDir string) *withheld.Queue {
+	withheldOnce.Do(func() {
+		withheldQueue = withheld.NewQueue(filepath.Join(configDir, "withheld"))
+	})
+	return withheldQueue
+}
+
+// onWatcherBlock handles a blocked action. Tasks with the retry policy get the
+// watcher reason fed back into the current turn; everything else is withheld.
+func (b *Bot) onWatcherBlock(review actions.Review, reason string, ctx actions.ActionContext, payload json.RawMessage) {
+	if ctx.Task != nil && ctx.Task.WatcherPolicy == tasks.WatcherRetry {
+		b.notifyWatcher(ctx, fmt.Sprintf("[Watcher] : Blocked %s: \"%s\". Reason: %s. Retrying with feedback.", review.Action, review.Summary, reason))
+		if ctx.ToolOutputs != nil {
+			*ctx.ToolOutputs = append(*ctx.ToolOutputs, watcherFeedback(review.Action, review.Summary, reason))
+		}
+		return
+	}
+	b.withhold(review, reason, ctx, payload)
+}
+
+// withhold stores a blocked action in the queue and tells the master how to resolve it
+func (b *Bot) withhold(review actions.Review, reason string, ctx actions.ActionContext, payload json.RawMessage) {
+	if ctx.Shadow {
+		// Nothing to release later: a dry run only reports the block
+		b.notifyShadow(ctx, fmt.Sprintf("the watcher would have blocked %s: \"%s\". Reason: %s", review.Action, review.Summary, reason))
+		return
+	}
+	if b.Withheld == nil {
+		b.notifyWatcher(ctx, fmt.Sprintf("[Watcher] : Blocked %s: \"%s\". Reason: %s", review.Action, review.Summary, reason))
+		return
+	}
+
+	// Only the masked text is kept on disk: a released action runs with the redactions
+	message, context := ctx.Message, ctx.Context
+	if b.WatchRules != nil {
+		scope := watchScope(review, ctx)
+		payload = b.WatchRules.RedactPayload(payload, scope)
+		message = b.WatchRules.Evaluate(message, scope).Redacted
+		context = make([]string, len(ctx.Context))
+		for i, line := range ctx.Context {
+			context[i] = b.WatchRules.Evaluate(line, scope).Redacted
+		}
+	}
+
+	item := withheld.Item{
+		Action:      review.Action,
+		Payload:     payload,
+		Summary:     review.Summary,
+		Reason:      reason,
+		Mode:        ctx.Mode,
+		BehaviorIDs: ctx.Behaviors,
+		ChatJID:     ctx.ChatJID,
+		Message:     message,
+		Context:     context,
+	}
+	if ctx.Task != nil {
+		item.TaskID = ctx.Task.ID
+		item.ChatJID = taskChat(ctx.Task)
+	}
+	stored, err := b.Withheld.Add(item)
+	if err != nil {
+		fmt.Printf("Failed to store withheld action: %v\n", err)
+		b.notifyWatcher(ctx, fmt.Sprintf("[Watcher] : Blocked %s: \"%s\". Reason: %s (could not be withheld: %v)", review.Action, review.Summary, reason, err))
+		return
+	}
+
+	what := review.Action
+	if what == "response" {
+		what = "message"
+	}
+	b.notifyWatcher(ctx, fmt.Sprintf("[Watcher] : Blocked %s #%d: \"%s\". Reason: %s\nReply 'release #%d', 'retry #%d' or 'discard #%d' ('LET IT BE' releases the latest).",
+		what, stored.ID, review.Summary, reason, stored.ID, stored.ID, stored.ID))
+}
+
+// watcherFeedback is the guidance given to the LLM when a blocked action is retried
+func watcherFeedback(action, summary, reason string) string {
+	return fmt.Sprintf("[Watcher] Your action `%s` (\"%s\") was blocked and NOT executed. Reason: %s. Redo it taking this into account.", action, summary, reason)
+}
+
+// taskChat returns the chat a task talks in
+func taskChat(task *tasks.Task) string {
+	if task.ChatID != "" {
+		return task.ChatID
+	}
+	return task.Contact
+}
+
+// replier returns the function quoting messages of the chat, or nil if replies aren't wired
+func (b *Bot) replier(chatJID string) func(text, messageID string) {
+	if b.ChatReplier == nil || chatJID == "" {
+		return nil
+	}
+	return b.ChatReplier(chatJID)
+}
+
+// HandleWatcherCommand handles the self-chat watcher commands: "release #N",
+// "discard #N", "retry #N", "LET IT BE", "withheld" and "watcher stats [reset]".
+// It returns the reply for the master and whether the text was such a command.
+func (b *Bot) HandleWatcherCommand(text string) (string, bool) {
+	text = strings.TrimSpace(text)
+	if b.WatchStats != nil {
+		switch strings.ToLower(text) {
+		case "watcher stats":
+			stats, err := b.WatchStats.Format()
+			if err != nil {
+				return fmt.Sprintf("[Blady][Watcher] : Error: %v", err), true
+			}
+			return "[Blady][Watcher] : " + stats, true
+		case "watcher stats reset":
+			return b.replyFor("Stats cleared.", b.WatchStats.Reset()), true
+		}
+	}
+	if b.Withheld == nil {
+		return "", false
+	}
+
+	switch {
+	case text == "LET IT BE":
+		pending, err := b.Withheld.Pending()
+		if err != nil {
+			return fmt.Sprintf("[Blady][Watcher] : Error: %v", err), true
+		}
+		if len(pending) == 0 {
+			return "[Blady][Watcher] : No blocked message to release.", true
+		}
+		return b.replyFor(b.ReleaseWithheld(pending[len(pending)-1].ID)), true
+	case strings.EqualFold(text, "withheld"):
+		return b.ListWithheld(), true
+	}
+
+	m := withheldCommandRe.FindStringSubmatch(text)
+	if m == nil {
+		return "", false
+	}
+	id, _ := strconv.Atoi(m[2])
+	switch strings.ToLower(m[1]) {
+	case "release":
+		return b.replyFor(b.ReleaseWithheld(id)), true
+	case "discard":
+		return b.replyFor(b.DiscardWithheld(id)), true
+	default:
+		return b.replyFor(b.RetryWithheld(id)), true
+	}
+}
+
+func (b *Bot) replyFor(msg string, err error) string {
+	if err != nil {
+		return fmt.Sprintf("[Blady][Watcher] : Error: %v", err)
+	}
+	return "[Blady][Watcher] : " + msg
+}
+
+// ListWithheld renders the pending withheld actions for the master
+func (b *Bot) ListWithheld() string {
+	pending, err := b.Withheld.Pending()
+	if err != nil {
+		return fmt.Sprintf("[Blady][Watcher] : Error: %v", err)
+	}
+	if len(pending) == 0 {
+		return "[Blady][Watcher] : No withheld actions."
+	}
+	var sb strings.Builder
+	sb.WriteString("[Blady][Watcher] : Withheld actions:")
+	for _, it := range pending {
+		sb.WriteString(fmt.Sprintf("\n#%d %s from %s to %s (%s, expires %s): \"%s\" - %s",
+			it.ID, it.Action, it.Source(), it.ChatJID, time.Unix(it.CreatedAt, 0).Format("01-02 15:04"),
+			time.Unix(it.ExpiresAt, 0).Format("01-02 15:04"), it.Summary, it.Reason))
+	}
+	return sb.String()
+}
+
+// loadTaskFor returns the task of a withheld item, refusing overrules for retry-only tasks
+func (b *Bot) loadTaskFor(item *withheld.Item, overrule bool) (*tasks.Task, error) {
+	if item.TaskID == 0 {
+		return nil, nil
+	}
+	task, err := b.TaskManager.LoadTask(item.TaskID)
+	if err != nil {
+		return nil, err
+	}
+	if overrule && task.WatcherPolicy == tasks.WatcherRetry {
+		return nil, fmt.Errorf("task %d never overrules the watcher, use 'retry #%d'", task.ID, item.ID)
+	}
+	return task, nil
+}
+
+// ReleaseWithheld overrules the watcher and executes the withheld action as it was proposed
+func (b *Bot) ReleaseWithheld(id int) (string, error) {
+	item, err := b.Withheld.Get(id)
+	if err != nil {
+		return "", err
+	}
+	if item.Status != withheld.StatusPending {
+		return "", fmt.Errorf("withheld item #%d is already %s", id, item.Status)
+	}
+	task, err := b.loadTaskFor(item, true)
+	if err != nil {
+		return "", err
+	}
+	act, ok := b.ActionRegistry.Get(item.Action)
+	if !ok {
+		return "", fmt.Errorf("action '%s' is no longer available", item.Action)
+	}
+	if _, err := b.Withheld.Resolve(id, withheld.StatusReleased); err != nil {
+		return "", err
+	}
+
+	ctx := actions.ActionContext{
+		Mode:            item.Mode,
+		Message:         item.Message,
+		ChatJID:         item.ChatJID,
+		Behaviors:       item.BehaviorIDs,
+		Context:         item.Context,
+		Task:            task,
+		BehaviorManager: b.BehaviorManager,
+	}
+	if item.Mode != actions.ModeCommand && item.ChatJID != "" && b.ChatSender != nil {
+		ctx.SendToContact = b.ChatSender(item.ChatJID)
+		ctx.ReplyToContact = b.replier(item.ChatJID)
+	}
+	entry := b.newAuditEntry(ctx, RawAction{Type: item.Action, Content: item.Payload})
+	entry.Verdict, entry.Status = audit.VerdictReleased, audit.StatusExecuted
+	err = act.Execute(ctx, item.Payload)
+	b.recordAction(entry, nil, err)
+	if err != nil {
+		return "", fmt.Errorf("released #%d but %s failed: %w", id, item.Action, err)
+	}
+	if item.Action == "response" {
+		return "Message sent.", nil
+	}
+	return fmt.Sprintf("Released #%d (%s).", id, item.Action), nil
+}
+
+// DiscardWithheld accepts the watcher block and drops the action
+func (b *Bot) DiscardWithheld(id int) (string, error) {
+	if _, err := b.Withheld.Resolve(id, withheld.StatusDiscarded); err != nil {
+		return "", err
+	}
+	return fmt.Sprintf("Discarded #%d.", id), nil
+}
+
+// RetryWithheld re-runs the originating turn with the watcher reason as guidance.
+// The turn runs in the background; its actions go through the watcher again.
+func (b *Bot) RetryWithheld(id int) (string, error) {
+	item, err := b.Withheld.Get(id)
+	if err != nil {
+		return "", err
+	}
+	if item.Status != withheld.StatusPending {
+		return "", fmt.Errorf("withheld item #%d is already %s", id, item.Status)
+	}
+	task, err := b.loadTaskFor(item, false)
+	if err != nil {
+		return "", err
+	}
+
+	var active []behaviors.Behavior
+	if item.Mode == actions.ModeBehavior {
+		for _, bid := range item.BehaviorIDs {
+			behavior, err := b.BehaviorManager.LoadBehavior(bid)
+			if err != nil || behavior.Status != behaviors.StatusEnabled {
+				continue
+			}
+			active = append(active, *behavior)
+		}
+		if len(active) == 0 {
+			return "", fmt.Errorf("behaviors of #%d are no longer enabled", id)
+		}
+	}
+	if item.Mode != actions.ModeCommand && (item.ChatJID == "" || b.ChatSender == nil) {
+		return "", fmt.Errorf("can't reach the chat of #%d", id)
+	}
+
+	if _, err := b.Withheld.Resolve(id, withheld.StatusRetried); err != nil {
+		return "", err
+	}
+
+	msg := item.Message
+	if msg != "" {
+		msg += "\n\n"
+	}
+	msg += "[System: Tool Results]\n" + watcherFeedback(item.Action, item.Summary, item.Reason)
+
+	go func() {
+		var err error
+		switch item.Mode {
+		case actions.ModeTask:
+			_, err = b.ProcessTask(task, msg, item.Context, b.ChatSender(item.ChatJID))
+		case actions.ModeBehavior:
+			_, err = b.ProcessBehaviors(item.ChatJID, active, msg, item.Context, b.ChatSender(item.ChatJID))
+		default:
+			_, err = b.Process(actions.ModeCommand, msg, item.Context)
+		}
+		if err != nil {
+			fmt.Printf("Retry of withheld #%d failed: %v\n", id, err)
+			if b.SendMasterFunc != nil {
+				b.SendMasterFunc(fmt.Sprintf("[Blady][Watcher] : Retry of #%d failed: %v", id, err))
+			}
+		}
+	}()
+	return fmt.Sprintf("Retrying #%d from %s with the watcher feedback.", id, item.Source()), nil
+}
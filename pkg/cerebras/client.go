@@ -163,7 +163,7 @@ func (c *Client) Chat(messages []llm.Message, options map[string]interface{}) (*
 				Role:    chatResp.Choices[0].Message.Role,
 				Content: chatResp.Choices[0].Message.Content,
 			}
-			logTag, _ := options["log_tag"].(string)
+			logTag := llm.LogTag(options)
 			llm.LogLLM("cerebras", logTag, messages, res)
 			return res, nil
 		}
@@ -194,7 +194,7 @@ func (c *Client) Chat(messages []llm.Message, options map[string]interface{}) (*
 	if c.ErrorHandler != nil {
 		c.ErrorHandler(finalErr)
 	}
-	logTag, _ := options["log_tag"].(string)
+	logTag := llm.LogTag(options)
 	llm.LogLLM("cerebras", logTag, messages, nil)
 	return nil, finalErr
 }
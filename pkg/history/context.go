@@ -0,0 +1,181 @@
+package history
+
+import (
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// Directory resolves the people behind the JIDs of the context lines
+type Directory interface {
+	Name(jid string) string // Push, saved or business name, "" if unknown
+	IsMe(jid string) bool   // Whether the JID is the master's own account
+}
+
+// Meta is what a message carries besides its text
+type Meta struct {
+	Type     string                 // "text", "image", "location", "poll"... (whatsapp.Normalize), "" is text
+	Payload  map[string]interface{} // Structured fields of the type, e.g. latitude and longitude
+	QuotedID string                 // ID of the message this one replies to
+	Mentions []string               // Mentioned JIDs
+}
+
+// contextMessage is a stored message with the message it quotes, if any
+type contextMessage struct {
+	StoredMessage
+	Mentions      []string
+	QuotedID      string
+	Quoted        *StoredMessage // nil when the quoted message isn't stored
+	QuotedDeleted bool
+	EditedAt      sql.NullTime
+	DeletedAt     sql.NullTime
+}
+
+// changedAt is the latest of the message, its last edit and its deletion
+func (m contextMessage) changedAt() time.Time {
+	latest := m.Timestamp
+	for _, t := range []sql.NullTime{m.EditedAt, m.DeletedAt} {
+		if t.Valid && t.Time.After(latest) {
+			latest = t.Time
+		}
+	}
+	return latest
+}
+
+// contextQuery selects messages with their quoted message, for a WHERE clause and its tail (ORDER BY, LIMIT)
+const contextQuery = `SELECT m.message_id, m.chat_jid, m.sender_jid, m.content, m.timestamp, m.is_from_me, m.quoted_id, m.mentions,
+	m.edited_at, m.deleted_at, q.sender_jid, q.content, q.is_from_me, q.deleted_at IS NOT NULL
+	FROM messages m LEFT JOIN messages q ON q.chat_jid = m.chat_jid AND q.message_id = m.quoted_id AND m.quoted_id != ''
+	WHERE `
+
+func (h *HistoryStore) queryContext(where string, args ...interface{}) ([]contextMessage, error) {
+	rows, err := h.db.Query(contextQuery+where, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var messages []contextMessage
+	for rows.Next() {
+		var m contextMessage
+		var mentions string
+		var qSender, qContent *string
+		var qFromMe, qDeleted *bool
+		if err := rows.Scan(&m.MessageID, &m.ChatJID, &m.SenderJID, &m.Content, &m.Timestamp, &m.IsFromMe, &m.QuotedID, &mentions,
+			&m.EditedAt, &m.DeletedAt, &qSender, &qContent, &qFromMe, &qDeleted); err != nil {
+			return nil, err
+		}
+		if mentions != "" {
+			json.Unmarshal([]byte(mentions), &m.Mentions)
+		}
+		if qContent != nil {
+			m.Quoted = &StoredMessage{MessageID: m.QuotedID, ChatJID: m.ChatJID, SenderJID: *qSender, Content: *qContent, IsFromMe: *qFromMe}
+			m.QuotedDeleted = *qDeleted
+		}
+		messages = append(messages, m)
+	}
+	return messages, rows.Err()
+}
+
+// senderName names the author of a message: "Me", the display name, or a fallback
+// ("User" in direct chats, the phone number in groups where several people talk)
+func (h *HistoryStore) senderName(chatJID, senderJID string, isFromMe bool) string {
+	if isFromMe {
+		return "Me"
+	}
+	if h.Directory != nil {
+		if name := h.Directory.Name(senderJID); name != "" {
+			return name
+		}
+	}
+	if strings.HasSuffix(chatJID, "@g.us") && senderJID != "" && senderJID != chatJID {
+		return "+" + jidUser(senderJID)
+	}
+	return "User"
+}
+
+// jidUser returns the user part of a JID ("5491100000000" for "5491100000000:3@s.whatsapp.net")
+func jidUser(jid string) string {
+	user, _, _ := strings.Cut(jid, "@")
+	user, _, _ = strings.Cut(user, ":")
+	return user
+}
+
+// withMentions replaces the "@number" mentions in the text with names, "@Me" for the master
+func (h *HistoryStore) withMentions(content string, mentions []string) string {
+	for _, jid := range mentions {
+		name := ""
+		if h.Directory != nil && h.Directory.IsMe(jid) {
+			name = "Me"
+		} else if h.Directory != nil {
+			name = h.Directory.Name(jid)
+		}
+		if name != "" {
+			content = strings.ReplaceAll(content, "@"+jidUser(jid), "@"+name)
+		}
+	}
+	return content
+}
+
+// formatLine renders a message as a context line. The message ID lets the LLM quote it
+// when replying. Format: `[2023-01-01 12:00:00 #3EB0C7] Juan: (replying to #3EB0A1 Me: "hi") Message`.
+// Edited messages show their latest text after "(edited)"; deleted ones only "(deleted)".
+func (h *HistoryStore) formatLine(m contextMessage) string {
+	stamp := m.Timestamp.Format("2006-01-02 15:04:05")
+	if m.MessageID != "" {
+		stamp += " #" + m.MessageID
+	}
+	content := h.withMentions(m.Content, m.Mentions)
+	if m.EditedAt.Valid {
+		content = "(edited) " + content
+	}
+	if m.QuotedID != "" {
+		reply := "(replying to #" + m.QuotedID
+		if m.Quoted != nil {
+			quoted := fmt.Sprintf("%q", truncate(strings.ReplaceAll(m.Quoted.Content, "\n", " "), 80))
+			if m.QuotedDeleted {
+				quoted = "(deleted)"
+			}
+			reply += fmt.Sprintf(" %s: %s", h.senderName(m.ChatJID, m.Quoted.SenderJID, m.Quoted.IsFromMe), quoted)
+		}
+		content = reply + ") " + content
+	}
+	if m.DeletedAt.Valid {
+		content = "(deleted)"
+	}
+	return fmt.Sprintf("[%s] %s: %s", stamp, h.senderName(m.ChatJID, m.SenderJID, m.IsFromMe), content)
+}
+
+// truncate cuts a text to n runes
+func truncate(text string, n int) string {
+	if r := []rune(text); len(r) > n {
+		return string(r[:n]) + "..."
+	}
+	return text
+}
+
+// formatLines renders messages in chronological order, reversing them when newest first
+func (h *HistoryStore) formatLines(messages []contextMessage, newestFirst bool) []string {
+	lines := make([]string, 0, len(messages))
+	for i := range messages {
+		if newestFirst {
+			lines = append(lines, h.formatLine(messages[len(messages)-1-i]))
+		} else {
+			lines = append(lines, h.formatLine(messages[i]))
+		}
+	}
+	return lines
+}
+
+// maxUnix returns the latest timestamp, edit or deletion of the messages, or since
+// when there are none newer
+func maxUnix(messages []contextMessage, since int64) int64 {
+	for _, m := range messages {
+		if changed := m.changedAt().Unix(); changed > since {
+			since = changed
+		}
+	}
+	return since
+}
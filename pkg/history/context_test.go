@@ -0,0 +1,99 @@
+package history
+
+import (
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+type fakeDirectory map[string]string
+
+func (d fakeDirectory) Name(jid string) string { return d[jid] }
+func (d fakeDirectory) IsMe(jid string) bool   { return jid == "5491100000009@s.whatsapp.net" }
+
+func TestContextLines(t *testing.T) {
+	h, err := New(filepath.Join(t.TempDir(), "history.db"))
+	if err != nil {
+		t.Fatalf("New failed: %v", err)
+	}
+	h.Directory = fakeDirectory{"5491100000001@s.whatsapp.net": "Juan"}
+
+	group := "120363000000000001@g.us"
+	now := time.Now()
+	h.SaveMessage("A1", group, "Me", "Who's coming on Friday?", now, true)
+	h.SaveMessageMeta("A2", group, "5491100000001@s.whatsapp.net", "Me! @5491100000009 count me in", now.Add(time.Second), false,
+		Meta{QuotedID: "A1", Mentions: []string{"5491100000009@s.whatsapp.net"}})
+	h.SaveMessageMeta("A3", group, "5491100000002@s.whatsapp.net", "Me too", now.Add(2*time.Second), false, Meta{QuotedID: "GONE"})
+
+	lines, err := h.GetRecentMessages(group, 10)
+	if err != nil || len(lines) != 3 {
+		t.Fatalf("GetRecentMessages = %v, %v", lines, err)
+	}
+	want := []string{
+		`#A1] Me: Who's coming on Friday?`,
+		`#A2] Juan: (replying to #A1 Me: "Who's coming on Friday?") Me! @Me count me in`,
+		`#A3] +5491100000002: (replying to #GONE) Me too`,
+	}
+	for i, w := range want {
+		if !strings.HasSuffix(lines[i], w) {
+			t.Errorf("line %d = %q, want suffix %q", i, lines[i], w)
+		}
+	}
+
+	// Every mode reads the same lines
+	since, _, err := h.GetMessagesSince(group, now.Add(-time.Minute).Unix())
+	if err != nil || strings.Join(since, "\n") != strings.Join(lines, "\n") {
+		t.Errorf("GetMessagesSince = %v, %v", since, err)
+	}
+}
+
+func TestEditsAndRevocations(t *testing.T) {
+	h, err := New(filepath.Join(t.TempDir(), "history.db"))
+	if err != nil {
+		t.Fatalf("New failed: %v", err)
+	}
+	juan := "5491100000001@s.whatsapp.net"
+	start := time.Now().Add(-time.Hour)
+	h.SaveMessage("A1", juan, juan, "It costs $100", start, false)
+	h.SaveMessageMeta("A2", juan, "Me", "Deal", start.Add(time.Second), true, Meta{QuotedID: "A1"})
+	h.SaveMessage("A3", juan, juan, "See you", start.Add(2*time.Second), false)
+
+	// The task already processed everything
+	_, cursor, _ := h.GetMessagesSince(juan, start.Add(-time.Minute).Unix())
+
+	now := time.Now()
+	if result, err := h.EditMessage(juan, "A1", "It costs $150", now); result != ChangeApplied || err != nil {
+		t.Fatalf("EditMessage = %v, %v", result, err)
+	}
+	if result, _ := h.RevokeMessage(juan, "A1", now.Add(time.Second)); result != ChangeApplied {
+		t.Fatalf("expected the revocation to apply, got %v", result)
+	}
+	if result, _ := h.EditMessage(juan, "A1", "too late", now); result != ChangeDeleted {
+		t.Errorf("deleted messages can't be edited, got %v", result)
+	}
+	if result, _ := h.RevokeMessage(juan, "missing", now); result != ChangeMissing {
+		t.Errorf("expected ChangeMissing for an unknown message, got %v", result)
+	}
+	h.EditMessage(juan, "A3", "See you tomorrow", now)
+
+	changed, next, err := h.GetMessagesSince(juan, cursor)
+	if err != nil || len(changed) != 2 {
+		t.Fatalf("expected the two changed messages, got %v, %v", changed, err)
+	}
+	if !strings.HasSuffix(changed[0], "#A1] User: (deleted)") || !strings.HasSuffix(changed[1], "#A3] User: (edited) See you tomorrow") {
+		t.Errorf("unexpected lines %q", changed)
+	}
+	if again, _, _ := h.GetMessagesSince(juan, next); len(again) != 0 {
+		t.Errorf("expected nothing new after the cursor moved, got %q", again)
+	}
+
+	lines, _ := h.GetRecentMessages(juan, 10)
+	if !strings.HasSuffix(lines[1], `Me: (replying to #A1 User: (deleted)) Deal`) {
+		t.Errorf("expected the quote of a deleted message to be hidden, got %q", lines[1])
+	}
+	versions, _ := h.Versions(juan, "A1")
+	if len(versions) != 2 || versions[0].Content != "It costs $100" || versions[1].Content != "It costs $150" {
+		t.Errorf("unexpected versions %+v", versions)
+	}
+}
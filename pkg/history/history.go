@@ -2,6 +2,7 @@ package history
 
 import (
 	"database/sql"
+	"encoding/json"
 	"fmt"
 	"time"
 
@@ -9,7 +10,12 @@ import (
 )
 
 type HistoryStore struct {
-	db *sql.DB
+	db  *sql.DB
+	fts bool // Full-text index available (see search.go)
+
+	// Directory names the senders and mentions of the context lines. Without it
+	// contacts show up as "User", or by number in groups.
+	Directory Directory
 }
 
 func New(dbPath string) (*HistoryStore, error) {
@@ -45,17 +51,68 @@ func New(dbPath string) (*HistoryStore, error) {
 	);
 	CREATE INDEX IF NOT EXISTS idx_media_chat_jid ON media(chat_jid);
 	`
-	_, err = db.Exec(query)
+	_, err = db.Exec(query + pollsSchema + versionsSchema)
 	if err != nil {
 		return nil, fmt.Errorf("failed to create tables: %w", err)
 	}
 
-	return &HistoryStore{db: db}, nil
+	// Columns added after the first release
+	for _, column := range []struct{ name, definition string }{
+		{"quoted_id", "TEXT NOT NULL DEFAULT ''"},
+		{"mentions", "TEXT NOT NULL DEFAULT ''"},
+		{"msg_type", "TEXT NOT NULL DEFAULT 'text'"},
+		{"payload", "TEXT NOT NULL DEFAULT ''"},
+		{"edited_at", "DATETIME"},
+		{"deleted_at", "DATETIME"},
+	} {
+		if err := addColumn(db, "messages", column.name, column.definition); err != nil {
+			return nil, err
+		}
+	}
+
+	h := &HistoryStore{db: db}
+	h.initFTS()
+	return h, nil
+}
+
+// addColumn adds a column to an existing table unless it is already there
+func addColumn(db *sql.DB, table, column, definition string) error {
+	var count int
+	if err := db.QueryRow(`SELECT COUNT(*) FROM pragma_table_info(?) WHERE name = ?`, table, column).Scan(&count); err != nil {
+		return fmt.Errorf("failed to inspect table %s: %w", table, err)
+	}
+	if count > 0 {
+		return nil
+	}
+	if _, err := db.Exec(fmt.Sprintf("ALTER TABLE %s ADD COLUMN %s %s", table, column, definition)); err != nil {
+		return fmt.Errorf("failed to add column %s.%s: %w", table, column, err)
+	}
+	return nil
 }
 
 func (h *HistoryStore) SaveMessage(messageID, chatJID, senderJID, content string, timestamp time.Time, isFromMe bool) error {
-	query := `INSERT OR IGNORE INTO messages (message_id, chat_jid, sender_jid, content, timestamp, is_from_me) VALUES (?, ?, ?, ?, ?, ?)`
-	_, err := h.db.Exec(query, messageID, chatJID, senderJID, content, timestamp, isFromMe)
+	return h.SaveMessageMeta(messageID, chatJID, senderJID, content, timestamp, isFromMe, Meta{})
+}
+
+// SaveMessageMeta saves a message along with its type, payload, the message it replies to and its mentions
+func (h *HistoryStore) SaveMessageMeta(messageID, chatJID, senderJID, content string, timestamp time.Time, isFromMe bool, meta Meta) error {
+	mentions, payload := "", ""
+	if len(meta.Mentions) > 0 {
+		data, _ := json.Marshal(meta.Mentions)
+		mentions = string(data)
+	}
+	if len(meta.Payload) > 0 {
+		data, err := json.Marshal(meta.Payload)
+		if err != nil {
+			return fmt.Errorf("failed to encode message payload: %w", err)
+		}
+		payload = string(data)
+	}
+	if meta.Type == "" {
+		meta.Type = "text"
+	}
+	query := `INSERT OR IGNORE INTO messages (message_id, chat_jid, sender_jid, content, timestamp, is_from_me, quoted_id, mentions, msg_type, payload) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`
+	_, err := h.db.Exec(query, messageID, chatJID, senderJID, content, timestamp, isFromMe, meta.QuotedID, mentions, meta.Type, payload)
 	if err != nil {
 		return fmt.Errorf("failed to save message: %w", err)
 	}
@@ -103,151 +160,89 @@ func (h *HistoryStore) GetMedia(id int64) (*MediaInfo, error) {
 	return &info, nil
 }
 
-func (h *HistoryStore) GetRecentMessages(chatJID string, limit int) ([]string, error) {
-	query := `
-	SELECT sender_jid, content, timestamp, is_from_me 
-	FROM messages 
-	WHERE chat_jid = ? 
-	ORDER BY timestamp DESC 
-	LIMIT ?`
+// StoredMessage is a text message as stored in the history
+type StoredMessage struct {
+	MessageID string
+	ChatJID   string
+	SenderJID string // "Me" for our own messages
+	Content   string
+	Timestamp time.Time
+	IsFromMe  bool
+	Type      string // See Meta.Type
+	Payload   string // JSON of Meta.Payload, "" if none
+}
+
+// messageColumns are the columns scanned by scanMessage
+const messageColumns = `message_id, chat_jid, sender_jid, content, timestamp, is_from_me, msg_type, payload`
+
+func scanMessage(row interface{ Scan(...interface{}) error }) (StoredMessage, error) {
+	var m StoredMessage
+	err := row.Scan(&m.MessageID, &m.ChatJID, &m.SenderJID, &m.Content, &m.Timestamp, &m.IsFromMe, &m.Type, &m.Payload)
+	return m, err
+}
+
+// GetMessage returns a message of a chat by its ID, or nil if it isn't stored
+func (h *HistoryStore) GetMessage(chatJID, messageID string) (*StoredMessage, error) {
+	query := `SELECT ` + messageColumns + ` FROM messages WHERE chat_jid = ? AND message_id = ?`
+	m, err := scanMessage(h.db.QueryRow(query, chatJID, messageID))
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("failed to get message: %w", err)
+	}
+	return &m, nil
+}
 
+// GetIncoming returns the latest messages of a chat sent by others, newest first
+func (h *HistoryStore) GetIncoming(chatJID string, limit int) ([]StoredMessage, error) {
+	query := `SELECT ` + messageColumns + ` FROM messages
+	WHERE chat_jid = ? AND is_from_me = 0 AND deleted_at IS NULL ORDER BY timestamp DESC LIMIT ?`
 	rows, err := h.db.Query(query, chatJID, limit)
 	if err != nil {
-		return nil, fmt.Errorf("failed to query recent messages: %w", err)
+		return nil, fmt.Errorf("failed to query incoming messages: %w", err)
 	}
 	defer rows.Close()
 
-	var messages []string
-	var rawMessages []string
+	var messages []StoredMessage
 	for rows.Next() {
-		var senderJID, content string
-		var timestamp time.Time
-		var isFromMe bool
-		if err := rows.Scan(&senderJID, &content, &timestamp, &isFromMe); err != nil {
+		m, err := scanMessage(rows)
+		if err != nil {
 			return nil, err
 		}
-
-		prefix := "User"
-		if isFromMe {
-			prefix = "Me"
-		} else {
-			prefix = "User"
-		}
-
-		// Format: "[2023-01-01 12:00:00] User: Message"
-		formatted := fmt.Sprintf("[%s] %s: %s", timestamp.Format("2006-01-02 15:04:05"), prefix, content)
-		rawMessages = append(rawMessages, formatted)
+		messages = append(messages, m)
 	}
+	return messages, rows.Err()
+}
 
-	// Reverse to get chronological order (Oldest -> Newest)
-	for i := len(rawMessages) - 1; i >= 0; i-- {
-		messages = append(messages, rawMessages[i])
+func (h *HistoryStore) GetRecentMessages(chatJID string, limit int) ([]string, error) {
+	messages, err := h.queryContext(`m.chat_jid = ? ORDER BY m.timestamp DESC LIMIT ?`, chatJID, limit)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query recent messages: %w", err)
 	}
-
-	return messages, nil
+	// Reverse to get chronological order (Oldest -> Newest)
+	return h.formatLines(messages, true), nil
 }
 
 // GetMessagesSince returns messages after the given unix timestamp
 // Returns formatted messages list, the timestamp of the last message (maxUnix), and error
 func (h *HistoryStore) GetMessagesSince(chatJID string, sinceUnix int64) ([]string, int64, error) {
-	// If sinceUnix is 0, getting ALL messages might be too much, maybe default to last 10?
-	// But the user request implies grabbing "new" messages.
-	// If it's a new task, maybe start fresh?
-	// Let's assume if 0 (never processed), we get recent ones to kickstart, OR we get all since 0.
-	// User said: "When the task starts it may encounter no new messages... and that's ok"
-	// But for the very FIRST run, we want the prompt/trigger message.
-	// The trigger message is already saved.
-	// If we return nothing for timestamp 0, the task sees nothing.
-	// Strategy: If sinceUnix is 0, we treat it as "GetRecent(10)" to establish context,
-	// and set timestamp to the latest.
-
+	// The first run of a task has no cursor: take the last 10 messages (including the
+	// trigger message, already saved) to establish context, and the latest timestamp.
 	if sinceUnix == 0 {
-		// Just reuse GetRecentMessages but we need the max timestamp too
-		// Implementing a variation here
-		query := `
-		SELECT sender_jid, content, timestamp, is_from_me 
-		FROM messages 
-		WHERE chat_jid = ? 
-		ORDER BY timestamp DESC 
-		LIMIT 10`
-
-		rows, err := h.db.Query(query, chatJID)
+		messages, err := h.queryContext(`m.chat_jid = ? ORDER BY m.timestamp DESC LIMIT 10`, chatJID)
 		if err != nil {
 			return nil, 0, fmt.Errorf("failed to query initial messages: %w", err)
 		}
-		defer rows.Close()
-
-		var rawMessages []string
-		var maxUnix int64 = 0
-
-		for rows.Next() {
-			var sender string
-			var content string
-			var ts time.Time
-			var isFromMe bool
-			if err := rows.Scan(&sender, &content, &ts, &isFromMe); err != nil {
-				return nil, 0, err
-			}
-
-			if ts.Unix() > maxUnix {
-				maxUnix = ts.Unix()
-			}
-
-			prefix := "User"
-			if isFromMe {
-				prefix = "Me"
-			}
-
-			formatted := fmt.Sprintf("[%s] %s: %s", ts.Format("2006-01-02 15:04:05"), prefix, content)
-			rawMessages = append(rawMessages, formatted)
-		}
-
-		// Reverse
-		var messages []string
-		for i := len(rawMessages) - 1; i >= 0; i-- {
-			messages = append(messages, rawMessages[i])
-		}
-		return messages, maxUnix, nil
+		return h.formatLines(messages, true), maxUnix(messages, 0), nil
 	}
 
-	// Normal case: Get messages strictly > sinceUnix
-	query := `
-	SELECT sender_jid, content, timestamp, is_from_me 
-	FROM messages 
-	WHERE chat_jid = ? AND timestamp > ?
-	ORDER BY timestamp ASC`
-
-	sinceTime := time.Unix(sinceUnix, 0)
-	rows, err := h.db.Query(query, chatJID, sinceTime)
+	// Normal case: Get messages strictly > sinceUnix, and older ones edited or deleted
+	// since, so running tasks see the change
+	since := time.Unix(sinceUnix, 0)
+	messages, err := h.queryContext(`m.chat_jid = ? AND (m.timestamp > ? OR m.edited_at > ? OR m.deleted_at > ?) ORDER BY m.timestamp ASC`, chatJID, since, since, since)
 	if err != nil {
 		return nil, 0, fmt.Errorf("failed to query new messages: %w", err)
 	}
-	defer rows.Close()
-
-	var messages []string
-	var maxUnix int64 = sinceUnix
-
-	for rows.Next() {
-		var sender string
-		var content string
-		var ts time.Time
-		var isFromMe bool
-		if err := rows.Scan(&sender, &content, &ts, &isFromMe); err != nil {
-			return nil, 0, err
-		}
-
-		if ts.Unix() > maxUnix {
-			maxUnix = ts.Unix()
-		}
-
-		prefix := "User"
-		if isFromMe {
-			prefix = "Me"
-		}
-
-		formatted := fmt.Sprintf("[%s] %s: %s", ts.Format("2006-01-02 15:04:05"), prefix, content)
-		messages = append(messages, formatted)
-	}
-
-	return messages, maxUnix, nil
+	return h.formatLines(messages, false), maxUnix(messages, sinceUnix), nil
 }
@@ -0,0 +1,160 @@
+package history
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// Poll is a WhatsApp poll, sent by us or received
+type Poll struct {
+	MessageID  string
+	ChatJID    string
+	SenderJID  string
+	Question   string
+	Options    []string
+	Selectable int // Maximum options per voter, 0 means any
+	CreatedAt  time.Time
+	IsFromMe   bool
+}
+
+// PollResult aggregates the latest vote of every voter
+type PollResult struct {
+	Poll
+	Counts map[string]int      // Votes per option
+	Voters map[string][]string // Voter JIDs per option
+	Total  int                 // Voters with at least one option selected
+}
+
+const pollsSchema = `
+CREATE TABLE IF NOT EXISTS polls (
+	message_id TEXT PRIMARY KEY,
+	chat_jid TEXT,
+	sender_jid TEXT,
+	question TEXT,
+	options TEXT,
+	selectable INTEGER,
+	created_at DATETIME,
+	is_from_me BOOLEAN
+);
+CREATE INDEX IF NOT EXISTS idx_polls_chat ON polls(chat_jid, created_at);
+
+CREATE TABLE IF NOT EXISTS poll_votes (
+	poll_id TEXT,
+	voter_jid TEXT,
+	options TEXT,
+	updated_at DATETIME,
+	PRIMARY KEY (poll_id, voter_jid)
+);
+`
+
+// SavePoll stores a poll; saving it again is a no-op
+func (h *HistoryStore) SavePoll(p Poll) error {
+	options, err := json.Marshal(p.Options)
+	if err != nil {
+		return fmt.Errorf("failed to encode poll options: %w", err)
+	}
+	query := `INSERT OR IGNORE INTO polls (message_id, chat_jid, sender_jid, question, options, selectable, created_at, is_from_me) VALUES (?, ?, ?, ?, ?, ?, ?, ?)`
+	if _, err := h.db.Exec(query, p.MessageID, p.ChatJID, p.SenderJID, p.Question, string(options), p.Selectable, p.CreatedAt, p.IsFromMe); err != nil {
+		return fmt.Errorf("failed to save poll: %w", err)
+	}
+	return nil
+}
+
+// GetPoll returns a poll by its message ID, or nil if it isn't stored
+func (h *HistoryStore) GetPoll(messageID string) (*Poll, error) {
+	polls, err := h.queryPolls(`WHERE message_id = ?`, messageID)
+	if err != nil || len(polls) == 0 {
+		return nil, err
+	}
+	return &polls[0], nil
+}
+
+// RecentPolls returns the latest polls of a chat ("" for every chat), newest first
+func (h *HistoryStore) RecentPolls(chatJID string, limit int) ([]Poll, error) {
+	if chatJID == "" {
+		return h.queryPolls(`ORDER BY created_at DESC LIMIT ?`, limit)
+	}
+	return h.queryPolls(`WHERE chat_jid = ? ORDER BY created_at DESC LIMIT ?`, chatJID, limit)
+}
+
+func (h *HistoryStore) queryPolls(where string, args ...interface{}) ([]Poll, error) {
+	rows, err := h.db.Query(`SELECT message_id, chat_jid, sender_jid, question, options, selectable, created_at, is_from_me FROM polls `+where, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query polls: %w", err)
+	}
+	defer rows.Close()
+
+	var polls []Poll
+	for rows.Next() {
+		var p Poll
+		var options string
+		if err := rows.Scan(&p.MessageID, &p.ChatJID, &p.SenderJID, &p.Question, &options, &p.Selectable, &p.CreatedAt, &p.IsFromMe); err != nil {
+			return nil, err
+		}
+		json.Unmarshal([]byte(options), &p.Options)
+		polls = append(polls, p)
+	}
+	return polls, rows.Err()
+}
+
+// SaveVote stores the selection of a voter, replacing their previous vote unless it's newer.
+// An empty selection withdraws the vote.
+func (h *HistoryStore) SaveVote(pollID, voterJID string, selected []string, at time.Time) error {
+	options, err := json.Marshal(selected)
+	if err != nil {
+		return fmt.Errorf("failed to encode vote: %w", err)
+	}
+	query := `INSERT INTO poll_votes (poll_id, voter_jid, options, updated_at) VALUES (?, ?, ?, ?)
+	ON CONFLICT(poll_id, voter_jid) DO UPDATE SET options = excluded.options, updated_at = excluded.updated_at
+	WHERE excluded.updated_at >= poll_votes.updated_at`
+	if _, err := h.db.Exec(query, pollID, voterJID, string(options), at); err != nil {
+		return fmt.Errorf("failed to save vote: %w", err)
+	}
+	return nil
+}
+
+// PollResults aggregates the votes of a poll, nil if the poll isn't stored
+func (h *HistoryStore) PollResults(pollID string) (*PollResult, error) {
+	poll, err := h.GetPoll(pollID)
+	if err != nil || poll == nil {
+		return nil, err
+	}
+	rows, err := h.db.Query(`SELECT voter_jid, options FROM poll_votes WHERE poll_id = ? ORDER BY updated_at`, pollID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query votes: %w", err)
+	}
+	defer rows.Close()
+
+	result := &PollResult{Poll: *poll, Counts: make(map[string]int), Voters: make(map[string][]string)}
+	for rows.Next() {
+		var voter, options string
+		if err := rows.Scan(&voter, &options); err != nil {
+			return nil, err
+		}
+		var selected []string
+		json.Unmarshal([]byte(options), &selected)
+		if len(selected) > 0 {
+			result.Total++
+		}
+		for _, option := range selected {
+			result.Counts[option]++
+			result.Voters[option] = append(result.Voters[option], voter)
+		}
+	}
+	return result, rows.Err()
+}
+
+// Summary renders the tally in option order, e.g. `"Which day?": 3 votes for Friday, 1 vote for Saturday (4 voters)`
+func (r *PollResult) Summary() string {
+	parts := make([]string, len(r.Options))
+	for i, option := range r.Options {
+		unit := "votes"
+		if r.Counts[option] == 1 {
+			unit = "vote"
+		}
+		parts[i] = fmt.Sprintf("%d %s for %s", r.Counts[option], unit, option)
+	}
+	return fmt.Sprintf("%q: %s (%d voters)", r.Question, strings.Join(parts, ", "), r.Total)
+}
@@ -0,0 +1,43 @@
+package history
+
+import (
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestPollVotes(t *testing.T) {
+	h, err := New(filepath.Join(t.TempDir(), "history.db"))
+	if err != nil {
+		t.Fatalf("New failed: %v", err)
+	}
+
+	now := time.Now()
+	poll := Poll{MessageID: "P1", ChatJID: "g@g.us", SenderJID: "Me", Question: "Which day?", Options: []string{"Friday", "Saturday"}, Selectable: 1, CreatedAt: now, IsFromMe: true}
+	if err := h.SavePoll(poll); err != nil {
+		t.Fatalf("SavePoll failed: %v", err)
+	}
+
+	h.SaveVote("P1", "a@s.whatsapp.net", []string{"Saturday"}, now)
+	h.SaveVote("P1", "a@s.whatsapp.net", []string{"Friday"}, now.Add(time.Minute)) // Changed their mind
+	h.SaveVote("P1", "a@s.whatsapp.net", []string{"Saturday"}, now)                // Late delivery of the old vote
+	h.SaveVote("P1", "b@s.whatsapp.net", []string{"Friday"}, now)
+	h.SaveVote("P1", "c@s.whatsapp.net", []string{"Friday"}, now)
+	h.SaveVote("P1", "d@s.whatsapp.net", nil, now) // Withdrawn
+
+	result, err := h.PollResults("P1")
+	if err != nil || result == nil {
+		t.Fatalf("PollResults failed: %v", err)
+	}
+	want := `"Which day?": 3 votes for Friday, 0 votes for Saturday (3 voters)`
+	if got := result.Summary(); got != want {
+		t.Errorf("Summary() = %s, want %s", got, want)
+	}
+
+	if polls, _ := h.RecentPolls("other@g.us", 5); len(polls) != 0 {
+		t.Errorf("expected no polls in another chat, got %d", len(polls))
+	}
+	if missing, _ := h.PollResults("nope"); missing != nil {
+		t.Error("expected nil results for an unknown poll")
+	}
+}
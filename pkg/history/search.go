@@ -0,0 +1,188 @@
+package history
+
+import (
+	"fmt"
+	"strings"
+	"time"
+)
+
+// The full-text index mirrors messages.content through triggers, so SaveMessage,
+// EditMessage and RevokeMessage keep it in sync. FTS5 needs the sqlite_fts5 build tag.
+const ftsSchema = `
+CREATE VIRTUAL TABLE IF NOT EXISTS messages_fts USING fts5(content, content='messages', content_rowid='id');
+CREATE TRIGGER IF NOT EXISTS messages_fts_insert AFTER INSERT ON messages BEGIN
+	INSERT INTO messages_fts(rowid, content) VALUES (new.id, new.content);
+END;
+CREATE TRIGGER IF NOT EXISTS messages_fts_delete AFTER DELETE ON messages BEGIN
+	INSERT INTO messages_fts(messages_fts, rowid, content) VALUES ('delete', old.id, old.content);
+END;
+CREATE TRIGGER IF NOT EXISTS messages_fts_update AFTER UPDATE OF content ON messages BEGIN
+	INSERT INTO messages_fts(messages_fts, rowid, content) VALUES ('delete', old.id, old.content);
+	INSERT INTO messages_fts(rowid, content) VALUES (new.id, new.content);
+END;
+`
+
+// initFTS creates the full-text index, rebuilding it when the triggers are new (first run,
+// or after running without FTS5). Without FTS5 support the triggers are dropped so writes
+// keep working, and Search falls back to LIKE.
+func (h *HistoryStore) initFTS() {
+	var synced int
+	h.db.QueryRow(`SELECT COUNT(*) FROM sqlite_master WHERE type = 'trigger' AND name = 'messages_fts_insert'`).Scan(&synced)
+	_, err := h.db.Exec(ftsSchema)
+	if err == nil {
+		// An index created by an FTS5 build exists even without the module, probe it
+		_, err = h.db.Exec(`SELECT rowid FROM messages_fts LIMIT 1`)
+	}
+	if err != nil {
+		fmt.Printf("Full-text search unavailable, searching history with LIKE (build with -tags sqlite_fts5): %v\n", err)
+		h.db.Exec(`DROP TRIGGER IF EXISTS messages_fts_insert; DROP TRIGGER IF EXISTS messages_fts_delete; DROP TRIGGER IF EXISTS messages_fts_update;`)
+		return
+	}
+	h.fts = true
+	if synced == 0 {
+		if _, err := h.db.Exec(`INSERT INTO messages_fts(messages_fts) VALUES ('rebuild')`); err != nil {
+			fmt.Printf("Failed to backfill the full-text index: %v\n", err)
+		}
+	}
+}
+
+// SearchQuery filters a history search. Zero fields match everything.
+type SearchQuery struct {
+	Keywords string // Every word must appear
+	ChatJID  string // A JID, or a number matching any JID of that user
+	Sender   string // A JID or number, or "me" for our own messages
+	Since    time.Time
+	Until    time.Time
+	Limit    int // Defaults to 20, newest first
+}
+
+// SearchResult is a matching message with a snippet around the keywords
+type SearchResult struct {
+	StoredMessage
+	Sender  string // Display name, as in the context lines
+	Snippet string
+}
+
+// Format renders the result on one line, e.g. "[2024-01-01 12:00:00 #3EB0C7] 5491100000000@s.whatsapp.net Juan: ...[address]..."
+func (r *SearchResult) Format() string {
+	return fmt.Sprintf("[%s #%s] %s %s: %s", r.Timestamp.Format("2006-01-02 15:04:05"), r.MessageID, r.ChatJID, r.Sender, strings.ReplaceAll(r.Snippet, "\n", " "))
+}
+
+// jidFilter matches a column against a JID, or any JID of a number
+func jidFilter(column, value string) (string, []interface{}) {
+	if strings.Contains(value, "@") {
+		return column + " = ?", []interface{}{value}
+	}
+	return "(" + column + " LIKE ? OR " + column + " LIKE ?)", []interface{}{value + "@%", value + ":%"}
+}
+
+// ftsQuery quotes every word so user input can't break the FTS5 syntax
+func ftsQuery(keywords string) string {
+	words := strings.Fields(keywords)
+	for i, w := range words {
+		words[i] = `"` + strings.ReplaceAll(w, `"`, `""`) + `"`
+	}
+	return strings.Join(words, " ")
+}
+
+// Search finds messages by keywords, chat, sender and date range, newest first
+func (h *HistoryStore) Search(q SearchQuery) ([]SearchResult, error) {
+	where := []string{"m.deleted_at IS NULL"}
+	var args []interface{}
+	from := "messages m"
+	snippet := "m.content"
+	useFTS := h.fts && len(strings.Fields(q.Keywords)) > 0
+
+	if words := strings.Fields(q.Keywords); len(words) > 0 {
+		if useFTS {
+			from = "messages_fts JOIN messages m ON m.id = messages_fts.rowid"
+			snippet = "snippet(messages_fts, 0, '[', ']', '...', 16)"
+			where = append(where, "messages_fts MATCH ?")
+			args = append(args, ftsQuery(q.Keywords))
+		} else {
+			for _, w := range words {
+				where = append(where, "m.content LIKE ?")
+				args = append(args, "%"+w+"%")
+			}
+		}
+	}
+	if q.ChatJID != "" {
+		clause, values := jidFilter("m.chat_jid", q.ChatJID)
+		where = append(where, clause)
+		args = append(args, values...)
+	}
+	if strings.EqualFold(q.Sender, "me") {
+		where = append(where, "m.is_from_me = 1")
+	} else if q.Sender != "" {
+		clause, values := jidFilter("m.sender_jid", q.Sender)
+		where = append(where, clause)
+		args = append(args, values...)
+	}
+	if !q.Since.IsZero() {
+		where = append(where, "m.timestamp >= ?")
+		args = append(args, q.Since)
+	}
+	if !q.Until.IsZero() {
+		where = append(where, "m.timestamp < ?")
+		args = append(args, q.Until)
+	}
+	limit := q.Limit
+	if limit <= 0 {
+		limit = 20
+	}
+
+	query := `SELECT m.message_id, m.chat_jid, m.sender_jid, m.content, m.timestamp, m.is_from_me, ` + snippet + ` FROM ` + from
+	query += " WHERE " + strings.Join(where, " AND ")
+	query += " ORDER BY m.timestamp DESC LIMIT ?"
+	args = append(args, limit)
+
+	rows, err := h.db.Query(query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to search history: %w", err)
+	}
+	defer rows.Close()
+
+	var results []SearchResult
+	for rows.Next() {
+		var r SearchResult
+		if err := rows.Scan(&r.MessageID, &r.ChatJID, &r.SenderJID, &r.Content, &r.Timestamp, &r.IsFromMe, &r.Snippet); err != nil {
+			return nil, err
+		}
+		if !useFTS {
+			r.Snippet = likeSnippet(r.Content, q.Keywords)
+		}
+		r.Sender = h.senderName(r.ChatJID, r.SenderJID, r.IsFromMe)
+		results = append(results, r)
+	}
+	return results, rows.Err()
+}
+
+// likeSnippet cuts the content around the first keyword, like the FTS5 snippet
+func likeSnippet(content, keywords string) string {
+	content = strings.ReplaceAll(content, "\n", " ")
+	runes := []rune(content)
+	if len(runes) <= 120 {
+		return content
+	}
+	start := 0
+	if words := strings.Fields(keywords); len(words) > 0 {
+		if i := strings.Index(strings.ToLower(content), strings.ToLower(words[0])); i > 0 {
+			start = len([]rune(content[:i])) - 40
+		}
+	}
+	if start < 0 {
+		start = 0
+	}
+	end := start + 120
+	if end > len(runes) {
+		end = len(runes)
+	}
+	snippet := string(runes[start:end])
+	if start > 0 {
+		snippet = "..." + snippet
+	}
+	if end < len(runes) {
+		snippet += "..."
+	}
+	return snippet
+}
@@ -0,0 +1,56 @@
+package history
+
+import (
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestSearch(t *testing.T) {
+	dbPath := filepath.Join(t.TempDir(), "history.db")
+	h, err := New(dbPath)
+	if err != nil {
+		t.Fatalf("New failed: %v", err)
+	}
+
+	juan := "5491100000000@s.whatsapp.net"
+	lastMonth := time.Now().AddDate(0, -1, 0)
+	h.SaveMessage("A1", juan, juan, "My new address is Av. Corrientes 1234, 5th floor", lastMonth, false)
+	h.SaveMessage("A2", juan, "Me", "Thanks! I'll send the package to that address", lastMonth.Add(time.Minute), true)
+	h.SaveMessage("B1", "5491199999999@s.whatsapp.net", "5491199999999@s.whatsapp.net", "What's your address?", time.Now(), false)
+
+	results, err := h.Search(SearchQuery{Keywords: "address", ChatJID: "5491100000000"})
+	if err != nil {
+		t.Fatalf("Search failed: %v", err)
+	}
+	if len(results) != 2 || results[0].MessageID != "A2" {
+		t.Fatalf("expected both messages of the chat newest first, got %+v", results)
+	}
+	if !strings.Contains(results[1].Format(), "#A1") {
+		t.Errorf("expected the message ID in %q", results[1].Format())
+	}
+
+	results, _ = h.Search(SearchQuery{Keywords: "corrientes", Sender: juan, Until: time.Now().AddDate(0, 0, -7)})
+	if len(results) != 1 || results[0].MessageID != "A1" {
+		t.Errorf("expected the address message, got %+v", results)
+	}
+	results, _ = h.Search(SearchQuery{Keywords: "address", Sender: "me"})
+	if len(results) != 1 || results[0].MessageID != "A2" {
+		t.Errorf("expected our own message, got %+v", results)
+	}
+	if _, err := h.Search(SearchQuery{Keywords: `"unbalanced AND (`}); err != nil {
+		t.Errorf("keywords with query syntax should not fail: %v", err)
+	}
+
+	// Edits and deletions keep the index in sync; reopening doesn't duplicate the backfill
+	h.EditMessage(juan, "A1", "Moved again, now at Santa Fe 100", time.Now())
+	h.RevokeMessage(juan, "A2", time.Now())
+	h, _ = New(dbPath)
+	if results, _ := h.Search(SearchQuery{Keywords: "address"}); len(results) != 1 || results[0].MessageID != "B1" {
+		t.Errorf("expected only the other chat after the edit and deletion, got %+v", results)
+	}
+	if results, _ := h.Search(SearchQuery{Keywords: "santa fe"}); len(results) != 1 {
+		t.Errorf("expected the edited text to be found, got %+v", results)
+	}
+}
@@ -0,0 +1,108 @@
+package history
+
+import (
+	"database/sql"
+	"fmt"
+	"time"
+)
+
+// Edits keep the replaced text in message_versions; revocations leave a tombstone
+// (empty content, deleted_at set) so the context shows "(deleted)" instead of the
+// retracted text. edited_at and deleted_at are columns of messages (see New).
+const versionsSchema = `
+CREATE TABLE IF NOT EXISTS message_versions (
+	id INTEGER PRIMARY KEY AUTOINCREMENT,
+	chat_jid TEXT,
+	message_id TEXT,
+	content TEXT,
+	replaced_at DATETIME
+);
+CREATE INDEX IF NOT EXISTS idx_versions_message ON message_versions(chat_jid, message_id);
+`
+
+// ChangeResult is the outcome of applying an edit or revocation to a stored message
+type ChangeResult int
+
+const (
+	ChangeMissing  ChangeResult = iota // The original isn't stored
+	ChangeApplied                      // The original now has the change
+	ChangeDeleted                      // The original was already deleted, the change is dropped
+	ChangeRejected                     // The change doesn't come from the author (set by Messenger.RecordChange)
+)
+
+// Version is a previous text of an edited or deleted message
+type Version struct {
+	Content    string
+	ReplacedAt time.Time
+}
+
+// replace archives the current text of a message and applies the SET clause
+func (h *HistoryStore) replace(chatJID, messageID string, at time.Time, set string, values ...interface{}) (ChangeResult, error) {
+	tx, err := h.db.Begin()
+	if err != nil {
+		return ChangeMissing, err
+	}
+	defer tx.Rollback()
+
+	var deleted bool
+	err = tx.QueryRow(`SELECT deleted_at IS NOT NULL FROM messages WHERE chat_jid = ? AND message_id = ?`, chatJID, messageID).Scan(&deleted)
+	if err == sql.ErrNoRows {
+		return ChangeMissing, nil
+	}
+	if err != nil {
+		return ChangeMissing, err
+	}
+	if deleted {
+		return ChangeDeleted, nil
+	}
+
+	if _, err := tx.Exec(`INSERT INTO message_versions (chat_jid, message_id, content, replaced_at)
+		SELECT chat_jid, message_id, content, ? FROM messages WHERE chat_jid = ? AND message_id = ?`, at, chatJID, messageID); err != nil {
+		return ChangeMissing, err
+	}
+	if _, err := tx.Exec(`UPDATE messages SET `+set+` WHERE chat_jid = ? AND message_id = ?`, append(values, chatJID, messageID)...); err != nil {
+		return ChangeMissing, err
+	}
+	return ChangeApplied, tx.Commit()
+}
+
+// EditMessage replaces the text of a message, keeping the previous one. Edits of
+// deleted messages are dropped (ChangeDeleted).
+func (h *HistoryStore) EditMessage(chatJID, messageID, content string, at time.Time) (ChangeResult, error) {
+	at = at.Truncate(time.Second) // Task cursors are unix seconds
+	result, err := h.replace(chatJID, messageID, at, `content = ?, edited_at = ?`, content, at)
+	if err != nil {
+		return result, fmt.Errorf("failed to edit message: %w", err)
+	}
+	return result, nil
+}
+
+// RevokeMessage turns a message deleted for everyone into a tombstone, keeping its
+// text as a version
+func (h *HistoryStore) RevokeMessage(chatJID, messageID string, at time.Time) (ChangeResult, error) {
+	at = at.Truncate(time.Second)
+	result, err := h.replace(chatJID, messageID, at, `content = '', deleted_at = ?`, at)
+	if err != nil {
+		return result, fmt.Errorf("failed to revoke message: %w", err)
+	}
+	return result, nil
+}
+
+// Versions returns the previous texts of a message, oldest first
+func (h *HistoryStore) Versions(chatJID, messageID string) ([]Version, error) {
+	rows, err := h.db.Query(`SELECT content, replaced_at FROM message_versions WHERE chat_jid = ? AND message_id = ? ORDER BY id`, chatJID, messageID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query message versions: %w", err)
+	}
+	defer rows.Close()
+
+	var versions []Version
+	for rows.Next() {
+		var v Version
+		if err := rows.Scan(&v.Content, &v.ReplacedAt); err != nil {
+			return nil, err
+		}
+		versions = append(versions, v)
+	}
+	return versions, rows.Err()
+}
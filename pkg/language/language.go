@@ -0,0 +1,148 @@
+package language
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"regexp"
+	"strings"
+	"sync"
+	"whatsabladerunner/pkg/behaviors"
+)
+
+// Default is used when no language is configured. The prompts were Spanish before the
+// language became configurable, so installs without a setup language keep it.
+const Default = "Spanish"
+
+// Overrides stores per-contact languages in config/languages.json
+type Overrides struct {
+	Path string
+	mu   sync.Mutex
+}
+
+type overridesFile struct {
+	Contacts map[string]string `json:"contacts"` // JID or number -> language name
+}
+
+// NewOverrides creates Overrides for the given file
+func NewOverrides(path string) *Overrides {
+	return &Overrides{Path: path}
+}
+
+func (o *Overrides) load() (*overridesFile, error) {
+	f := &overridesFile{Contacts: map[string]string{}}
+	data, err := os.ReadFile(o.Path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return f, nil
+		}
+		return nil, fmt.Errorf("failed to read language overrides: %w", err)
+	}
+	if err := json.Unmarshal(data, f); err != nil {
+		return nil, fmt.Errorf("failed to parse language overrides: %w", err)
+	}
+	if f.Contacts == nil {
+		f.Contacts = map[string]string{}
+	}
+	return f, nil
+}
+
+// For returns the language set for a chat, or "" if none
+func (o *Overrides) For(chatJID string) string {
+	if chatJID == "" {
+		return ""
+	}
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	f, err := o.load()
+	if err != nil {
+		fmt.Printf("Warning: %v\n", err)
+		return ""
+	}
+	for contact, lang := range f.Contacts {
+		if behaviors.MatchJID(contact, chatJID) {
+			return lang
+		}
+	}
+	return ""
+}
+
+// Set sets the language of a contact. An empty language removes the override.
+func (o *Overrides) Set(contact, lang string) error {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	f, err := o.load()
+	if err != nil {
+		return err
+	}
+	if lang == "" {
+		delete(f.Contacts, contact)
+	} else {
+		f.Contacts[contact] = lang
+	}
+	data, err := json.MarshalIndent(f, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to marshal language overrides: %w", err)
+	}
+	if err := os.WriteFile(o.Path, data, 0644); err != nil {
+		return fmt.Errorf("failed to write language overrides: %w", err)
+	}
+	return nil
+}
+
+// stopwords are frequent words that tell the languages apart
+var stopwords = map[string][]string{
+	"Spanish":    {"el", "la", "los", "las", "que", "de", "y", "es", "en", "un", "una", "por", "para", "con", "no", "hola", "gracias", "pero", "como", "está", "tu", "usted", "muy", "sí", "qué", "del", "al"},
+	"English":    {"the", "and", "is", "you", "to", "of", "it", "that", "for", "with", "your", "are", "this", "have", "hello", "hi", "thanks", "please", "what", "can", "we", "be", "not", "my", "i"},
+	"Portuguese": {"o", "os", "as", "que", "de", "e", "é", "não", "um", "uma", "para", "com", "você", "obrigado", "obrigada", "olá", "muito", "sim", "está", "do", "da", "mas", "em", "no", "na"},
+	"French":     {"le", "la", "les", "et", "est", "vous", "je", "de", "un", "une", "pour", "avec", "pas", "bonjour", "merci", "oui", "que", "qui", "des", "du", "ce", "mais", "tu", "nous"},
+	"Italian":    {"il", "lo", "la", "gli", "che", "di", "e", "è", "non", "un", "una", "per", "con", "ciao", "grazie", "sono", "sì", "come", "del", "della", "ma", "anche", "tu"},
+	"German":     {"der", "die", "das", "und", "ist", "nicht", "ich", "du", "sie", "ein", "eine", "mit", "für", "hallo", "danke", "ja", "nein", "zu", "den", "auf", "wir", "bitte"},
+}
+
+var (
+	wordRe        = regexp.MustCompile(`[\p{L}]+`)
+	contactLineRe = regexp.MustCompile(`^\[[^\]]*\] ([^:\n]+): `)
+	replyRe       = regexp.MustCompile(`^\(replying to #\S+( [^:]+: "(?:[^"\\]|\\.)*")?\) `)
+)
+
+// Detect guesses the language of the contact from history lines ("[ts] Name: text").
+// Only the contacts' messages count (not "Me:" lines, nor the text they quote); it
+// returns "" when there isn't enough evidence.
+func Detect(contextMsgs []string) string {
+	scores := map[string]int{}
+	for _, line := range contextMsgs {
+		loc := contactLineRe.FindStringSubmatchIndex(line)
+		if loc == nil || line[loc[2]:loc[3]] == "Me" {
+			continue
+		}
+		text := replyRe.ReplaceAllString(line[loc[1]:], "")
+		for _, word := range wordRe.FindAllString(strings.ToLower(text), -1) {
+			for lang, words := range stopwords {
+				for _, w := range words {
+					if w == word {
+						scores[lang]++
+						break
+					}
+				}
+			}
+		}
+	}
+
+	best, second := "", 0
+	for lang, score := range scores {
+		if best == "" || score > scores[best] || (score == scores[best] && lang < best) {
+			if best != "" {
+				second = scores[best]
+			}
+			best = lang
+		} else if score > second {
+			second = score
+		}
+	}
+	// Need a few hits and a clear margin over the runner-up
+	if best == "" || scores[best] < 3 || scores[best] < second*3/2 {
+		return ""
+	}
+	return best
+}
@@ -0,0 +1,54 @@
+package language
+
+import (
+	"path/filepath"
+	"testing"
+)
+
+func TestDetect(t *testing.T) {
+	cases := []struct {
+		name string
+		msgs []string
+		want string
+	}{
+		{"english bot", []string{
+			"[2024-01-01 10:00:00] Me: Hola, necesito ayuda con mi pedido",
+			"[2024-01-01 10:00:05] User: Hello! Thanks for contacting us. Please tell me your order number and we can check it for you.",
+		}, "English"},
+		{"spanish contact", []string{
+			"[2024-01-01 10:00:00] User: Hola, ¿cómo estás? Te escribo por el tema de la reunión del lunes",
+			"[2024-01-01 10:00:05] User: Gracias, no hay apuro",
+		}, "Spanish"},
+		{"only my messages", []string{
+			"[2024-01-01 10:00:00] Me: Hello, thanks for the help with the order, you are great",
+		}, ""},
+		{"named group members", []string{
+			`[2024-01-01 10:00:00 #A2] Juan: (replying to #A1 Me: "Hello, thanks for the help with the order, you are great") Hola, gracias a vos por todo el trabajo`,
+		}, "Spanish"},
+		{"too short", []string{"[2024-01-01 10:00:00] User: ok"}, ""},
+	}
+	for _, c := range cases {
+		if got := Detect(c.msgs); got != c.want {
+			t.Errorf("%s: Detect = %q, want %q", c.name, got, c.want)
+		}
+	}
+}
+
+func TestOverrides(t *testing.T) {
+	o := NewOverrides(filepath.Join(t.TempDir(), "languages.json"))
+	if got := o.For("5491100000001@s.whatsapp.net"); got != "" {
+		t.Fatalf("expected no override, got %q", got)
+	}
+	if err := o.Set("5491100000001", "English"); err != nil {
+		t.Fatalf("Set failed: %v", err)
+	}
+	if got := o.For("5491100000001@s.whatsapp.net"); got != "English" {
+		t.Errorf("For = %q, want English", got)
+	}
+	if err := o.Set("5491100000001", ""); err != nil {
+		t.Fatalf("Set failed: %v", err)
+	}
+	if got := o.For("5491100000001@s.whatsapp.net"); got != "" {
+		t.Errorf("expected override removed, got %q", got)
+	}
+}
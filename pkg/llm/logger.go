@@ -7,6 +7,19 @@ import (
 	"time"
 )
 
+// LogTag returns the log tag of a call: the "log_tag" option, followed by the
+// "trace_id" option that correlates the call with the audit log
+func LogTag(options map[string]interface{}) string {
+	tag, _ := options["log_tag"].(string)
+	if trace, _ := options["trace_id"].(string); trace != "" {
+		if tag != "" {
+			tag += "-"
+		}
+		tag += trace
+	}
+	return tag
+}
+
 // LogLLM handles logging of LLM prompts and responses.
 // Filename convention: ISO8601 date with ms + llm engine [+ tag] + prompt|response.
 func LogLLM(engine string, tag string, messages []Message, response *Message) {
@@ -0,0 +1,77 @@
+package mcp
+
+import (
+	"encoding/json"
+	"fmt"
+	"unicode/utf8"
+	"whatsabladerunner/pkg/bot/actions"
+)
+
+// ToolAction exposes an MCP tool as a Blady action
+type ToolAction struct {
+	Server *Server
+	Tool   Tool
+	name   string
+}
+
+func (a *ToolAction) GetSchema() actions.ActionSchema {
+	schema := a.Tool.InputSchema
+	if len(schema) == 0 {
+		schema = json.RawMessage(`{"type": "object"}`)
+	}
+	description := a.Tool.Description
+	if description == "" {
+		description = fmt.Sprintf("Tool %s of the %s MCP server.", a.Tool.Name, a.Server.Name)
+	}
+	return actions.ActionSchema{Name: a.name, Description: description, Parameters: schema}
+}
+
+// Review renders the tool call. MCP servers are outside Blady, so calls are external.
+func (a *ToolAction) Review(ctx actions.ActionContext, payload json.RawMessage) actions.Review {
+	return actions.Review{
+		Summary:  fmt.Sprintf("call MCP tool %s/%s with %s", a.Server.Name, a.Tool.Name, string(payload)),
+		Target:   "mcp:" + a.Server.Name,
+		External: true,
+	}
+}
+
+func (a *ToolAction) Execute(ctx actions.ActionContext, payload json.RawMessage) error {
+	args := payload
+	// Accept stringified JSON objects too
+	var s string
+	if err := json.Unmarshal(payload, &s); err == nil {
+		args = json.RawMessage(s)
+	}
+	if len(args) == 0 || string(args) == "null" {
+		args = json.RawMessage(`{}`)
+	}
+	var obj map[string]interface{}
+	if err := json.Unmarshal(args, &obj); err != nil {
+		return fmt.Errorf("invalid arguments for %s: %w", a.name, err)
+	}
+
+	text, isError, err := a.Server.CallTool(a.Tool.Name, args)
+	if err != nil {
+		if ctx.ToolOutputs != nil {
+			*ctx.ToolOutputs = append(*ctx.ToolOutputs, fmt.Sprintf("Action '%s' failed: %v", a.name, err))
+		}
+		return err
+	}
+	if len(text) > maxResultBytes {
+		cut := maxResultBytes
+		for cut > 0 && !utf8.RuneStart(text[cut]) {
+			cut--
+		}
+		text = fmt.Sprintf("%s\n...[truncated %d bytes]", text[:cut], len(text)-cut)
+	}
+
+	status := "result"
+	if isError {
+		status = "error"
+	}
+	fmt.Printf("MCP tool %s/%s returned %s (%d bytes)\n", a.Server.Name, a.Tool.Name, status, len(text))
+	if ctx.ToolOutputs != nil {
+		*ctx.ToolOutputs = append(*ctx.ToolOutputs, fmt.Sprintf("Action '%s' %s:\n%s", a.name, status, text))
+	}
+	return nil
+}
@@ -0,0 +1,418 @@
+package mcp
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"os/exec"
+	"strings"
+	"sync"
+	"sync/atomic"
+)
+
+// ProtocolVersion is the MCP revision the client speaks
+const ProtocolVersion = "2025-06-18"
+
+// rpcMessage is a JSON-RPC 2.0 request, notification or response
+type rpcMessage struct {
+	JSONRPC string           `json:"jsonrpc"`
+	ID      *json.RawMessage `json:"id,omitempty"`
+	Method  string           `json:"method,omitempty"`
+	Params  json.RawMessage  `json:"params,omitempty"`
+	Result  json.RawMessage  `json:"result,omitempty"`
+	Error   *rpcError        `json:"error,omitempty"`
+}
+
+type rpcError struct {
+	Code    int    `json:"code"`
+	Message string `json:"message"`
+}
+
+func (e *rpcError) Error() string {
+	return fmt.Sprintf("rpc error %d: %s", e.Code, e.Message)
+}
+
+// transport sends JSON-RPC messages to a server
+type transport interface {
+	call(ctx context.Context, method string, params interface{}) (json.RawMessage, error)
+	notify(method string, params interface{}) error
+	alive() bool
+	close() error
+}
+
+func encodeRequest(id int64, method string, params interface{}) ([]byte, error) {
+	msg := map[string]interface{}{"jsonrpc": "2.0", "method": method}
+	if id != 0 {
+		msg["id"] = id
+	}
+	if params != nil {
+		msg["params"] = params
+	}
+	return json.Marshal(msg)
+}
+
+// --- stdio ---
+
+// stdioTransport talks to a server process over newline-delimited JSON on stdin/stdout
+type stdioTransport struct {
+	cmd    *exec.Cmd
+	stdin  io.WriteCloser
+	nextID atomic.Int64
+
+	writeMu sync.Mutex
+	mu      sync.Mutex
+	pending map[int64]chan rpcMessage
+	done    chan struct{}
+	err     error
+}
+
+func startStdio(name string, command []string, env map[string]string, dir string) (*stdioTransport, error) {
+	cmd := exec.Command(command[0], command[1:]...)
+	cmd.Dir = dir
+	// Like exec custom actions, servers don't inherit Blady's environment (API keys,
+	// secrets): only what they need to run plus the configured variables
+	cmd.Env = []string{}
+	for _, name := range []string{"PATH", "HOME"} {
+		if v, ok := os.LookupEnv(name); ok {
+			cmd.Env = append(cmd.Env, name+"="+v)
+		}
+	}
+	for k, v := range env {
+		cmd.Env = append(cmd.Env, k+"="+expandEnv(v))
+	}
+	stdin, err := cmd.StdinPipe()
+	if err != nil {
+		return nil, err
+	}
+	stdout, err := cmd.StdoutPipe()
+	if err != nil {
+		return nil, err
+	}
+	cmd.Stderr = &prefixWriter{prefix: "[MCP " + name + "] "}
+	if err := cmd.Start(); err != nil {
+		return nil, fmt.Errorf("failed to start MCP server %s: %w", name, err)
+	}
+
+	t := &stdioTransport{cmd: cmd, stdin: stdin, pending: map[int64]chan rpcMessage{}, done: make(chan struct{})}
+	go t.read(stdout)
+	return t, nil
+}
+
+func (t *stdioTransport) read(stdout io.Reader) {
+	scanner := bufio.NewScanner(stdout)
+	scanner.Buffer(make([]byte, 64*1024), 16*1024*1024)
+	for scanner.Scan() {
+		line := bytes.TrimSpace(scanner.Bytes())
+		if len(line) == 0 {
+			continue
+		}
+		var msg rpcMessage
+		if err := json.Unmarshal(line, &msg); err != nil {
+			fmt.Printf("MCP: ignoring invalid message: %v\n", err)
+			continue
+		}
+		t.dispatch(msg)
+	}
+	err := scanner.Err()
+	if err == nil {
+		err = io.EOF
+	}
+	t.cmd.Wait()
+
+	t.mu.Lock()
+	t.err = fmt.Errorf("server exited: %w", err)
+	for id, ch := range t.pending {
+		close(ch)
+		delete(t.pending, id)
+	}
+	t.mu.Unlock()
+	close(t.done)
+}
+
+func (t *stdioTransport) dispatch(msg rpcMessage) {
+	switch {
+	case msg.Method != "" && msg.ID != nil:
+		// Request from the server: only ping is supported
+		reply := map[string]interface{}{"jsonrpc": "2.0", "id": msg.ID}
+		if msg.Method == "ping" {
+			reply["result"] = map[string]interface{}{}
+		} else {
+			reply["error"] = rpcError{Code: -32601, Message: "method not found"}
+		}
+		if data, err := json.Marshal(reply); err == nil {
+			t.write(data)
+		}
+	case msg.Method != "":
+		// Notification, nothing to do
+	case msg.ID != nil:
+		var id int64
+		if err := json.Unmarshal(*msg.ID, &id); err != nil {
+			return
+		}
+		t.mu.Lock()
+		ch, ok := t.pending[id]
+		delete(t.pending, id)
+		t.mu.Unlock()
+		if ok {
+			ch <- msg
+		}
+	}
+}
+
+func (t *stdioTransport) write(data []byte) error {
+	t.writeMu.Lock()
+	defer t.writeMu.Unlock()
+	_, err := t.stdin.Write(append(data, '\n'))
+	return err
+}
+
+func (t *stdioTransport) call(ctx context.Context, method string, params interface{}) (json.RawMessage, error) {
+	id := t.nextID.Add(1)
+	data, err := encodeRequest(id, method, params)
+	if err != nil {
+		return nil, err
+	}
+	ch := make(chan rpcMessage, 1)
+	t.mu.Lock()
+	if t.err != nil {
+		t.mu.Unlock()
+		return nil, t.err
+	}
+	t.pending[id] = ch
+	t.mu.Unlock()
+
+	if err := t.write(data); err != nil {
+		t.mu.Lock()
+		delete(t.pending, id)
+		t.mu.Unlock()
+		return nil, fmt.Errorf("failed to write to MCP server: %w", err)
+	}
+
+	select {
+	case msg, ok := <-ch:
+		if !ok {
+			return nil, t.closedErr()
+		}
+		if msg.Error != nil {
+			return nil, msg.Error
+		}
+		return msg.Result, nil
+	case <-ctx.Done():
+		t.mu.Lock()
+		delete(t.pending, id)
+		t.mu.Unlock()
+		return nil, ctx.Err()
+	}
+}
+
+func (t *stdioTransport) closedErr() error {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return t.err
+}
+
+func (t *stdioTransport) notify(method string, params interface{}) error {
+	data, err := encodeRequest(0, method, params)
+	if err != nil {
+		return err
+	}
+	return t.write(data)
+}
+
+func (t *stdioTransport) alive() bool {
+	select {
+	case <-t.done:
+		return false
+	default:
+		return true
+	}
+}
+
+func (t *stdioTransport) close() error {
+	t.stdin.Close()
+	if t.cmd.Process != nil {
+		t.cmd.Process.Kill()
+	}
+	<-t.done
+	return nil
+}
+
+// prefixWriter logs server stderr line by line
+type prefixWriter struct {
+	prefix string
+}
+
+func (w *prefixWriter) Write(p []byte) (int, error) {
+	for _, line := range strings.Split(strings.TrimRight(string(p), "\n"), "\n") {
+		fmt.Println(w.prefix + line)
+	}
+	return len(p), nil
+}
+
+// --- streamable HTTP ---
+
+// errSessionExpired is returned when the server no longer knows our session (HTTP 404).
+// The request was rejected, so the server must be initialized again and the call can be repeated.
+var errSessionExpired = errors.New("MCP session expired")
+
+// httpTransport talks to a server over the MCP streamable HTTP transport
+type httpTransport struct {
+	url     string
+	headers map[string]string
+	client  *http.Client
+	nextID  atomic.Int64
+
+	mu        sync.Mutex
+	sessionID string
+	expired   bool
+}
+
+func newHTTPTransport(url string, headers map[string]string) *httpTransport {
+	return &httpTransport{url: url, headers: headers, client: &http.Client{}}
+}
+
+func (t *httpTransport) post(ctx context.Context, body []byte) (*http.Response, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, t.url, bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Accept", "application/json, text/event-stream")
+	req.Header.Set("MCP-Protocol-Version", ProtocolVersion)
+	for k, v := range t.headers {
+		req.Header.Set(k, expandEnv(v))
+	}
+	t.mu.Lock()
+	if t.sessionID != "" {
+		req.Header.Set("Mcp-Session-Id", t.sessionID)
+	}
+	t.mu.Unlock()
+
+	resp, err := t.client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	if id := resp.Header.Get("Mcp-Session-Id"); id != "" {
+		t.mu.Lock()
+		t.sessionID = id
+		t.mu.Unlock()
+	}
+	if resp.StatusCode == http.StatusNotFound && req.Header.Get("Mcp-Session-Id") != "" {
+		resp.Body.Close()
+		t.mu.Lock()
+		t.expired = true
+		t.mu.Unlock()
+		return nil, errSessionExpired
+	}
+	if resp.StatusCode >= 400 {
+		data, _ := io.ReadAll(io.LimitReader(resp.Body, 2000))
+		resp.Body.Close()
+		return nil, fmt.Errorf("MCP server returned status %d: %s", resp.StatusCode, strings.TrimSpace(string(data)))
+	}
+	return resp, nil
+}
+
+func (t *httpTransport) call(ctx context.Context, method string, params interface{}) (json.RawMessage, error) {
+	id := t.nextID.Add(1)
+	body, err := encodeRequest(id, method, params)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := t.post(ctx, body)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	var msg *rpcMessage
+	if strings.HasPrefix(resp.Header.Get("Content-Type"), "text/event-stream") {
+		msg, err = readSSEResponse(resp.Body, id)
+	} else {
+		msg = &rpcMessage{}
+		err = json.NewDecoder(resp.Body).Decode(msg)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read MCP response: %w", err)
+	}
+	if msg.Error != nil {
+		return nil, msg.Error
+	}
+	return msg.Result, nil
+}
+
+// readSSEResponse reads server-sent events until the response with the given id
+func readSSEResponse(r io.Reader, id int64) (*rpcMessage, error) {
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 64*1024), 16*1024*1024)
+	var data strings.Builder
+	for scanner.Scan() {
+		line := scanner.Text()
+		if strings.HasPrefix(line, "data:") {
+			data.WriteString(strings.TrimPrefix(strings.TrimPrefix(line, "data:"), " "))
+			continue
+		}
+		if line != "" || data.Len() == 0 {
+			continue
+		}
+		// End of an event
+		var msg rpcMessage
+		err := json.Unmarshal([]byte(data.String()), &msg)
+		data.Reset()
+		if err != nil || msg.ID == nil || msg.Method != "" {
+			continue
+		}
+		var got int64
+		if json.Unmarshal(*msg.ID, &got) == nil && got == id {
+			return &msg, nil
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	return nil, fmt.Errorf("stream ended without a response")
+}
+
+func (t *httpTransport) notify(method string, params interface{}) error {
+	body, err := encodeRequest(0, method, params)
+	if err != nil {
+		return err
+	}
+	resp, err := t.post(context.Background(), body)
+	if err != nil {
+		return err
+	}
+	resp.Body.Close()
+	return nil
+}
+
+func (t *httpTransport) alive() bool {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return !t.expired
+}
+
+func (t *httpTransport) close() error {
+	t.mu.Lock()
+	sessionID, expired := t.sessionID, t.expired
+	t.mu.Unlock()
+	if sessionID == "" || expired {
+		return nil
+	}
+	req, err := http.NewRequest(http.MethodDelete, t.url, nil)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Mcp-Session-Id", sessionID)
+	resp, err := t.client.Do(req)
+	if err != nil {
+		return err
+	}
+	resp.Body.Close()
+	return nil
+}
@@ -0,0 +1,375 @@
+package mcp
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+	"whatsabladerunner/pkg/bot/actions"
+)
+
+const (
+	defaultCallTimeout = 60 * time.Second
+	maxRestarts        = 3                // Restarts allowed within restartWindow
+	restartWindow      = 10 * time.Minute // before a server is given up
+	maxResultBytes     = 8000
+)
+
+// ServerConfig is a server entry in config/mcp.json
+type ServerConfig struct {
+	Command        []string          `json:"command,omitempty"` // stdio: executable and arguments
+	Env            map[string]string `json:"env,omitempty"`     // stdio: extra environment, values may use ${env:NAME}
+	Dir            string            `json:"dir,omitempty"`     // stdio: working directory
+	URL            string            `json:"url,omitempty"`     // streamable HTTP endpoint, instead of command
+	Headers        map[string]string `json:"headers,omitempty"` // HTTP headers, values may use ${env:NAME}
+	Tools          []string          `json:"tools,omitempty"`   // Allowlist of tool names; empty means all
+	Exclude        []string          `json:"exclude,omitempty"` // Tools never exposed
+	Prefix         *string           `json:"prefix,omitempty"`  // Action name prefix, defaults to "<server>_"
+	TimeoutSeconds int               `json:"timeout_seconds,omitempty"`
+	Disabled       bool              `json:"disabled,omitempty"`
+}
+
+// Config is the content of config/mcp.json
+type Config struct {
+	Servers map[string]ServerConfig `json:"servers"`
+}
+
+// Tool is a tool listed by a server
+type Tool struct {
+	Name        string          `json:"name"`
+	Description string          `json:"description"`
+	InputSchema json.RawMessage `json:"inputSchema"`
+}
+
+// Server is a connection to a configured MCP server, restarted on demand
+type Server struct {
+	Name   string
+	Config ServerConfig
+	Tools  []Tool
+
+	mu       sync.Mutex
+	t        transport
+	restarts []time.Time
+	retrying bool // A background reconnection is running
+	closed   bool
+}
+
+// Manager owns the configured servers
+type Manager struct {
+	Servers []*Server
+}
+
+// LoadConfig reads config/mcp.json. A missing file means no servers.
+func LoadConfig(path string) (*Config, error) {
+	cfg := &Config{}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return cfg, nil
+		}
+		return nil, fmt.Errorf("failed to read MCP config: %w", err)
+	}
+	if err := json.Unmarshal(data, cfg); err != nil {
+		return nil, fmt.Errorf("failed to parse MCP config: %w", err)
+	}
+	return cfg, nil
+}
+
+// Start connects to every enabled server and lists its tools. Servers that
+// fail to start are logged and retried by Actions within the restart budget.
+func Start(cfg *Config) *Manager {
+	m := &Manager{}
+	names := make([]string, 0, len(cfg.Servers))
+	for name := range cfg.Servers {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	for _, name := range names {
+		sc := cfg.Servers[name]
+		if sc.Disabled {
+			continue
+		}
+		if (len(sc.Command) == 0) == (sc.URL == "") {
+			fmt.Printf("MCP server %s: needs either a command or a url\n", name)
+			continue
+		}
+		s := &Server{Name: name, Config: sc}
+		m.Servers = append(m.Servers, s)
+		if err := s.connect(); err != nil {
+			fmt.Printf("MCP server %s: %v\n", name, err)
+			s.restarts = append(s.restarts, time.Now())
+			continue
+		}
+		fmt.Printf("MCP server %s: %d tools\n", name, len(s.Tools))
+	}
+	return m
+}
+
+// Close stops every server
+func (m *Manager) Close() {
+	for _, s := range m.Servers {
+		s.mu.Lock()
+		s.closed = true
+		if s.t != nil {
+			s.t.close()
+			s.t = nil
+		}
+		s.mu.Unlock()
+	}
+}
+
+// Actions returns one action per allowed tool. Servers that failed to start are
+// reconnected in the background, so their tools reach the next bots.
+func (m *Manager) Actions() []actions.Action {
+	var list []actions.Action
+	for _, s := range m.Servers {
+		s.mu.Lock()
+		tools := s.Tools
+		if s.t == nil {
+			s.retry()
+		}
+		s.mu.Unlock()
+		for _, tool := range tools {
+			list = append(list, &ToolAction{Server: s, Tool: tool, name: s.actionName(tool.Name)})
+		}
+	}
+	return list
+}
+
+// retry reconnects a server in the background if the restart budget allows it.
+// Called with s.mu held.
+func (s *Server) retry() {
+	if s.retrying || s.closed || !s.canRestart(time.Now()) {
+		return
+	}
+	s.retrying = true
+	go func() {
+		s.mu.Lock()
+		defer s.mu.Unlock()
+		s.retrying = false
+		if s.closed {
+			return
+		}
+		if err := s.ensure(); err != nil {
+			fmt.Printf("MCP server %s: %v\n", s.Name, err)
+			return
+		}
+		fmt.Printf("MCP server %s: %d tools\n", s.Name, len(s.Tools))
+	}()
+}
+
+func (s *Server) timeout() time.Duration {
+	if s.Config.TimeoutSeconds > 0 {
+		return time.Duration(s.Config.TimeoutSeconds) * time.Second
+	}
+	return defaultCallTimeout
+}
+
+// connect starts the transport, runs the initialize handshake and lists the tools
+func (s *Server) connect() error {
+	var t transport
+	if len(s.Config.Command) > 0 {
+		st, err := startStdio(s.Name, s.Config.Command, s.Config.Env, s.Config.Dir)
+		if err != nil {
+			return err
+		}
+		t = st
+	} else {
+		t = newHTTPTransport(s.Config.URL, s.Config.Headers)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), s.timeout())
+	defer cancel()
+	_, err := t.call(ctx, "initialize", map[string]interface{}{
+		"protocolVersion": ProtocolVersion,
+		"capabilities":    map[string]interface{}{},
+		"clientInfo":      map[string]interface{}{"name": "whatsabladerunner", "version": "1.0"},
+	})
+	if err != nil {
+		t.close()
+		return fmt.Errorf("initialize failed: %w", err)
+	}
+	if err := t.notify("notifications/initialized", nil); err != nil {
+		t.close()
+		return fmt.Errorf("initialized notification failed: %w", err)
+	}
+
+	var tools []Tool
+	cursor := ""
+	for {
+		params := map[string]interface{}{}
+		if cursor != "" {
+			params["cursor"] = cursor
+		}
+		raw, err := t.call(ctx, "tools/list", params)
+		if err != nil {
+			t.close()
+			return fmt.Errorf("tools/list failed: %w", err)
+		}
+		var page struct {
+			Tools      []Tool `json:"tools"`
+			NextCursor string `json:"nextCursor"`
+		}
+		if err := json.Unmarshal(raw, &page); err != nil {
+			t.close()
+			return fmt.Errorf("invalid tools/list result: %w", err)
+		}
+		tools = append(tools, page.Tools...)
+		if page.NextCursor == "" {
+			break
+		}
+		cursor = page.NextCursor
+	}
+
+	s.t = t
+	s.Tools = nil
+	for _, tool := range tools {
+		if s.allowed(tool.Name) {
+			s.Tools = append(s.Tools, tool)
+		}
+	}
+	return nil
+}
+
+// allowed applies the server tool allowlist and exclusions
+func (s *Server) allowed(tool string) bool {
+	for _, ex := range s.Config.Exclude {
+		if ex == tool {
+			return false
+		}
+	}
+	if len(s.Config.Tools) == 0 {
+		return true
+	}
+	for _, t := range s.Config.Tools {
+		if t == tool {
+			return true
+		}
+	}
+	return false
+}
+
+var invalidNameRe = regexp.MustCompile(`[^A-Za-z0-9_]+`)
+
+func (s *Server) actionName(tool string) string {
+	prefix := s.Name + "_"
+	if s.Config.Prefix != nil {
+		prefix = *s.Config.Prefix
+	}
+	return invalidNameRe.ReplaceAllString(prefix+tool, "_")
+}
+
+// canRestart forgets the restarts older than restartWindow and reports whether
+// the budget allows another one
+func (s *Server) canRestart(now time.Time) bool {
+	recent := s.restarts[:0]
+	for _, t := range s.restarts {
+		if now.Sub(t) < restartWindow {
+			recent = append(recent, t)
+		}
+	}
+	s.restarts = recent
+	return len(s.restarts) < maxRestarts
+}
+
+// ensure restarts a dead server (or one whose HTTP session expired), within the restart budget
+func (s *Server) ensure() error {
+	if s.t != nil && s.t.alive() {
+		return nil
+	}
+	now := time.Now()
+	if !s.canRestart(now) {
+		return fmt.Errorf("MCP server %s keeps failing, not restarting it", s.Name)
+	}
+	s.restarts = append(s.restarts, now)
+	fmt.Printf("MCP server %s is down, restarting\n", s.Name)
+	if s.t != nil {
+		s.t.close()
+		s.t = nil
+	}
+	return s.connect()
+}
+
+// CallTool calls a tool, restarting the server first if it died. Calls are only
+// retried when an HTTP server rejected them for an expired session; otherwise the
+// tool may have run before the server went away. It returns the text of the result
+// and whether the tool reported an error.
+func (s *Server) CallTool(name string, args json.RawMessage) (string, bool, error) {
+	s.mu.Lock()
+	if err := s.ensure(); err != nil {
+		s.mu.Unlock()
+		return "", false, err
+	}
+	t := s.t
+	s.mu.Unlock()
+
+	ctx, cancel := context.WithTimeout(context.Background(), s.timeout())
+	defer cancel()
+	params := map[string]interface{}{"name": name, "arguments": args}
+	raw, err := t.call(ctx, "tools/call", params)
+	if errors.Is(err, errSessionExpired) {
+		s.mu.Lock()
+		if err = s.ensure(); err == nil {
+			t = s.t
+		}
+		s.mu.Unlock()
+		if err == nil {
+			raw, err = t.call(ctx, "tools/call", params)
+		}
+	}
+	if err != nil {
+		return "", false, fmt.Errorf("MCP %s/%s: %w", s.Name, name, err)
+	}
+
+	var result struct {
+		Content []struct {
+			Type     string `json:"type"`
+			Text     string `json:"text"`
+			MimeType string `json:"mimeType"`
+			Resource struct {
+				URI  string `json:"uri"`
+				Text string `json:"text"`
+			} `json:"resource"`
+		} `json:"content"`
+		StructuredContent json.RawMessage `json:"structuredContent"`
+		IsError           bool            `json:"isError"`
+	}
+	if err := json.Unmarshal(raw, &result); err != nil {
+		return "", false, fmt.Errorf("MCP %s/%s: invalid result: %w", s.Name, name, err)
+	}
+	var parts []string
+	for _, c := range result.Content {
+		switch c.Type {
+		case "text":
+			parts = append(parts, c.Text)
+		case "resource":
+			if c.Resource.Text != "" {
+				parts = append(parts, c.Resource.Text)
+			} else {
+				parts = append(parts, "[resource "+c.Resource.URI+"]")
+			}
+		default:
+			parts = append(parts, fmt.Sprintf("[%s %s]", c.Type, c.MimeType))
+		}
+	}
+	if len(parts) == 0 && len(result.StructuredContent) > 0 {
+		parts = append(parts, string(result.StructuredContent))
+	}
+	return strings.Join(parts, "\n"), result.IsError, nil
+}
+
+var envRefRe = regexp.MustCompile(`\$\{env:([A-Za-z0-9_]+)\}`)
+
+// expandEnv replaces ${env:NAME} references
+func expandEnv(value string) string {
+	return envRefRe.ReplaceAllStringFunc(value, func(ref string) string {
+		return os.Getenv(envRefRe.FindStringSubmatch(ref)[1])
+	})
+}
@@ -0,0 +1,173 @@
+package mcp
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"testing"
+	"whatsabladerunner/pkg/bot/actions"
+)
+
+// TestMain turns the test binary into a stdio MCP server when asked to
+func TestMain(m *testing.M) {
+	if os.Getenv("MCP_TEST_SERVER") == "1" {
+		runTestServer()
+		return
+	}
+	os.Exit(m.Run())
+}
+
+func runTestServer() {
+	scanner := bufio.NewScanner(os.Stdin)
+	out := json.NewEncoder(os.Stdout)
+	for scanner.Scan() {
+		var req struct {
+			ID     *int64 `json:"id"`
+			Method string `json:"method"`
+			Params struct {
+				Name      string            `json:"name"`
+				Arguments map[string]string `json:"arguments"`
+			} `json:"params"`
+		}
+		if err := json.Unmarshal(scanner.Bytes(), &req); err != nil || req.ID == nil {
+			continue
+		}
+		var result interface{}
+		switch req.Method {
+		case "initialize":
+			result = map[string]interface{}{"protocolVersion": ProtocolVersion, "capabilities": map[string]interface{}{"tools": map[string]interface{}{}}}
+		case "tools/list":
+			result = map[string]interface{}{"tools": []map[string]interface{}{
+				{"name": "echo", "description": "Echo the text", "inputSchema": map[string]interface{}{"type": "object", "properties": map[string]interface{}{"text": map[string]string{"type": "string"}}}},
+				{"name": "crash", "inputSchema": map[string]interface{}{"type": "object"}},
+				{"name": "delete-all", "inputSchema": map[string]interface{}{"type": "object"}},
+			}}
+		case "tools/call":
+			switch req.Params.Name {
+			case "crash":
+				os.Exit(1)
+			case "echo":
+				result = map[string]interface{}{"content": []map[string]string{{"type": "text", "text": "echo: " + req.Params.Arguments["text"] + " " + os.Getenv("MCP_TEST_GREETING") + os.Getenv("MCP_TEST_LEAK")}}}
+			default:
+				result = map[string]interface{}{"content": []map[string]string{{"type": "text", "text": "unknown tool"}}, "isError": true}
+			}
+		}
+		out.Encode(map[string]interface{}{"jsonrpc": "2.0", "id": *req.ID, "result": result})
+	}
+}
+
+func TestStdioServer(t *testing.T) {
+	t.Setenv("GREETING_SOURCE", "hola")
+	t.Setenv("MCP_TEST_LEAK", " leaked") // Not configured, so the server must not see it
+	m := Start(&Config{Servers: map[string]ServerConfig{
+		"local": {
+			Command: []string{os.Args[0]},
+			Env:     map[string]string{"MCP_TEST_SERVER": "1", "MCP_TEST_GREETING": "${env:GREETING_SOURCE}"},
+			Exclude: []string{"delete-all"},
+		},
+	}})
+	defer m.Close()
+
+	registry := actions.NewRegistry()
+	for _, act := range m.Actions() {
+		registry.Register(act)
+	}
+	if _, ok := registry.Get("local_delete_all"); ok {
+		t.Errorf("excluded tool was registered")
+	}
+	echo, ok := registry.Get("local_echo")
+	if !ok {
+		t.Fatalf("local_echo not registered, got %d actions", len(m.Actions()))
+	}
+
+	call := func(act actions.Action, payload string) []string {
+		ctx := actions.ActionContext{ToolOutputs: &[]string{}}
+		act.Execute(ctx, json.RawMessage(payload))
+		return *ctx.ToolOutputs
+	}
+	if out := call(echo, `{"text": "hi"}`); len(out) != 1 || !strings.HasSuffix(out[0], "echo: hi hola") {
+		t.Fatalf("unexpected echo output: %v", out)
+	}
+
+	// The server dies and is restarted on the next call
+	crash, _ := registry.Get("local_crash")
+	if out := call(crash, `{}`); len(out) != 1 || !strings.Contains(out[0], "failed") {
+		t.Errorf("expected a failure for crash, got %v", out)
+	}
+	if out := call(echo, `"{\"text\": \"again\"}"`); len(out) != 1 || !strings.HasSuffix(out[0], "echo: again hola") {
+		t.Errorf("unexpected output after restart: %v", out)
+	}
+}
+
+func TestHTTPServerSSE(t *testing.T) {
+	var sessions []string
+	known, started := map[string]bool{}, 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		session := r.Header.Get("Mcp-Session-Id")
+		sessions = append(sessions, session)
+		if session != "" && !known[session] {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		var req struct {
+			ID     *int64 `json:"id"`
+			Method string `json:"method"`
+		}
+		json.NewDecoder(r.Body).Decode(&req)
+		if req.ID == nil {
+			w.WriteHeader(http.StatusAccepted)
+			return
+		}
+		var result string
+		switch req.Method {
+		case "initialize":
+			started++
+			id := fmt.Sprintf("s%d", started)
+			known[id] = true
+			w.Header().Set("Mcp-Session-Id", id)
+			result = `{"protocolVersion": "` + ProtocolVersion + `"}`
+		case "tools/list":
+			result = `{"tools": [{"name": "weather", "inputSchema": {"type": "object"}}]}`
+		case "tools/call":
+			result = `{"content": [{"type": "text", "text": "sunny"}]}`
+		}
+		w.Header().Set("Content-Type", "text/event-stream")
+		fmt.Fprintf(w, "data: {\"jsonrpc\":\"2.0\",\"method\":\"notifications/progress\"}\n\n")
+		fmt.Fprintf(w, "data: {\"jsonrpc\":\"2.0\",\"id\":%d,\"result\":%s}\n\n", *req.ID, result)
+	}))
+	defer server.Close()
+
+	prefix := ""
+	m := Start(&Config{Servers: map[string]ServerConfig{"remote": {URL: server.URL, Prefix: &prefix}}})
+	acts := m.Actions()
+	if len(acts) != 1 || acts[0].GetSchema().Name != "weather" {
+		t.Fatalf("unexpected actions: %d", len(acts))
+	}
+	ctx := actions.ActionContext{ToolOutputs: &[]string{}}
+	if err := acts[0].Execute(ctx, json.RawMessage(`{}`)); err != nil {
+		t.Fatalf("Execute failed: %v", err)
+	}
+	if out := *ctx.ToolOutputs; len(out) != 1 || !strings.HasSuffix(out[0], "sunny") {
+		t.Errorf("unexpected output: %v", out)
+	}
+	if sessions[len(sessions)-1] != "s1" {
+		t.Errorf("session id not sent: %v", sessions)
+	}
+
+	// The server forgets the session: the call is rejected, so it's repeated in a new one
+	delete(known, "s1")
+	ctx = actions.ActionContext{ToolOutputs: &[]string{}}
+	if err := acts[0].Execute(ctx, json.RawMessage(`{}`)); err != nil {
+		t.Fatalf("Execute after session expiry failed: %v", err)
+	}
+	if out := *ctx.ToolOutputs; len(out) != 1 || !strings.HasSuffix(out[0], "sunny") {
+		t.Errorf("unexpected output after session expiry: %v", out)
+	}
+	if sessions[len(sessions)-1] != "s2" {
+		t.Errorf("expected a new session, got %v", sessions)
+	}
+}
@@ -88,7 +88,7 @@ func (c *Client) Chat(messages []llm.Message, options map[string]interface{}) (*
 			}
 
 			fmt.Printf("[Ollama] Success! Response received (length: %d chars).\n", len(chatResp.Message.Content))
-			logTag, _ := options["log_tag"].(string)
+			logTag := llm.LogTag(options)
 			llm.LogLLM("ollama", logTag, messages, &chatResp.Message)
 			return &chatResp.Message, nil
 		}
@@ -107,7 +107,7 @@ func (c *Client) Chat(messages []llm.Message, options map[string]interface{}) (*
 	if c.ErrorHandler != nil {
 		c.ErrorHandler(finalErr)
 	}
-	logTag, _ := options["log_tag"].(string)
+	logTag := llm.LogTag(options)
 	llm.LogLLM("ollama", logTag, messages, nil)
 	return nil, finalErr
 }
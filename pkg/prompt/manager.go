@@ -5,6 +5,7 @@ import (
 	"fmt"
 	"os"
 	"path/filepath"
+	"strconv"
 	"strings"
 	"text/template"
 	"time"
@@ -52,8 +53,12 @@ func (pm *PromptManager) LoadSystemPrompt(lang string) (string, error) {
 }
 
 type WatcherData struct {
-	ProposedMessage string
+	ProposedMessage string // Message text or rendering of the proposed action
+	Action          string // Action type, e.g. response
+	Target          string // Who or what the action reaches
+	Language        string // Language expected in the target chat
 	Context         string
+	RuleIDs         []string // Rules to give a verdict for
 }
 
 func (pm *PromptManager) LoadModePrompt(mode string, data ModeData) (string, error) {
@@ -88,7 +93,114 @@ func (pm *PromptManager) LoadModePrompt(mode string, data ModeData) (string, err
 	return sb.String(), nil
 }
 
+// WatcherRule is a rule file from config/watcher/rules/ with its scope metadata.
+// Scope fields come from an optional header between "---" lines, e.g. "contacts: 5491100000000, 12345@g.us".
+// Empty scope fields match everything.
+type WatcherRule struct {
+	ID        string   // File name without extension
+	Content   string   // Rendered rule text
+	Contacts  []string // Target chats (JIDs or numbers)
+	Tasks     []int    // Task IDs
+	Behaviors []string // Behavior template names or IDs
+	Actions   []string // Action types
+}
+
+// LoadWatcherRules renders every rule file with its scope metadata
+func (pm *PromptManager) LoadWatcherRules(data WatcherData) ([]WatcherRule, error) {
+	dir := filepath.Join(pm.ConfigDir, "watcher", "rules")
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read dir %s: %w", dir, err)
+	}
+
+	var rules []WatcherRule
+	for _, entry := range entries {
+		if entry.IsDir() || !strings.HasSuffix(entry.Name(), ".txt") {
+			continue
+		}
+		path := filepath.Join(dir, entry.Name())
+		raw, err := os.ReadFile(path)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read file %s: %w", path, err)
+		}
+		rule, body, err := parseRuleHeader(string(raw))
+		if err != nil {
+			return nil, fmt.Errorf("invalid header in %s: %w", path, err)
+		}
+		rule.ID = strings.TrimSuffix(entry.Name(), ".txt")
+		rule.Content, err = pm.renderString(path, body, data)
+		if err != nil {
+			return nil, err
+		}
+		rules = append(rules, rule)
+	}
+	return rules, nil
+}
+
+// parseRuleHeader splits the optional scope header from the rule body
+func parseRuleHeader(raw string) (WatcherRule, string, error) {
+	var rule WatcherRule
+	normalized := strings.ReplaceAll(raw, "\r\n", "\n")
+	if !strings.HasPrefix(normalized, "---\n") {
+		return rule, raw, nil
+	}
+	rest := normalized[len("---\n"):]
+	end := strings.Index(rest, "\n---")
+	if end < 0 {
+		return rule, "", fmt.Errorf("header not closed with ---")
+	}
+	header := rest[:end]
+	body := strings.TrimPrefix(rest[end+len("\n---"):], "\n")
+
+	for _, line := range strings.Split(header, "\n") {
+		line = strings.TrimSpace(line)
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		key, value, ok := strings.Cut(line, ":")
+		if !ok {
+			return rule, "", fmt.Errorf("invalid header line '%s'", line)
+		}
+		var values []string
+		for _, v := range strings.Split(value, ",") {
+			if v = strings.TrimSpace(v); v != "" {
+				values = append(values, v)
+			}
+		}
+		switch strings.TrimSpace(strings.ToLower(key)) {
+		case "contacts":
+			rule.Contacts = values
+		case "tasks":
+			for _, v := range values {
+				id, err := strconv.Atoi(strings.TrimPrefix(v, "#"))
+				if err != nil {
+					return rule, "", fmt.Errorf("invalid task id '%s'", v)
+				}
+				rule.Tasks = append(rule.Tasks, id)
+			}
+		case "behaviors":
+			rule.Behaviors = values
+		case "actions":
+			rule.Actions = values
+		default:
+			return rule, "", fmt.Errorf("unknown header key '%s'", key)
+		}
+	}
+	return rule, body, nil
+}
+
+// LoadWatcherPrompt builds the watcher prompt with every rule
 func (pm *PromptManager) LoadWatcherPrompt(data WatcherData) (string, error) {
+	rules, err := pm.LoadWatcherRules(data)
+	if err != nil {
+		return "", fmt.Errorf("failed to load watcher rules directory: %w", err)
+	}
+	return pm.LoadWatcherRulesPrompt(data, rules)
+}
+
+// LoadWatcherRulesPrompt builds the watcher prompt for the given rules; the
+// watcher answers with one verdict per rule.
+func (pm *PromptManager) LoadWatcherRulesPrompt(data WatcherData, rules []WatcherRule) (string, error) {
 	var sb strings.Builder
 
 	// 1. Load context.txt
@@ -100,13 +212,14 @@ func (pm *PromptManager) LoadWatcherPrompt(data WatcherData) (string, error) {
 	sb.WriteString(contextContent)
 	sb.WriteString("\n")
 
-	// 2. Load Rules Directory Files
-	dir := filepath.Join(pm.ConfigDir, "watcher", "rules")
-	rulesContent, err := pm.renderTemplates(dir, data)
-	if err != nil {
-		return "", fmt.Errorf("failed to load watcher rules directory: %w", err)
+	// 2. Rules, each under its ID
+	data.RuleIDs = nil
+	for _, rule := range rules {
+		sb.WriteString(fmt.Sprintf("## Rule `%s`\n", rule.ID))
+		sb.WriteString(rule.Content)
+		sb.WriteString("\n")
+		data.RuleIDs = append(data.RuleIDs, rule.ID)
 	}
-	sb.WriteString(rulesContent)
 
 	// 3. Load protocol.txt
 	protocolPath := filepath.Join(pm.ConfigDir, "watcher", "protocol.txt")
@@ -181,9 +294,12 @@ func (pm *PromptManager) renderFile(path string, data interface{}) (string, erro
 	if err != nil {
 		return "", fmt.Errorf("failed to read file %s: %w", path, err)
 	}
+	return pm.renderString(path, string(content), data)
+}
 
+func (pm *PromptManager) renderString(path, content string, data interface{}) (string, error) {
 	// Create a new template and parse the content
-	tmpl, err := template.New(filepath.Base(path)).Parse(string(content))
+	tmpl, err := template.New(filepath.Base(path)).Parse(content)
 	if err != nil {
 		return "", fmt.Errorf("failed to parse template %s: %w", path, err)
 	}
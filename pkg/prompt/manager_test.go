@@ -71,3 +71,32 @@ func TestLoadBehaviorPrompt(t *testing.T) {
 		t.Error("Prompt does not contain MY_CONTEXT")
 	}
 }
+
+func TestLoadWatcherRulesScope(t *testing.T) {
+	tmpDir := t.TempDir()
+	rulesDir := filepath.Join(tmpDir, "watcher", "rules")
+	if err := os.MkdirAll(rulesDir, 0755); err != nil {
+		t.Fatalf("failed to create rules dir: %v", err)
+	}
+	files := map[string]string{
+		"00_basic.txt": "Be nice to {{.Target}}.",
+		"10_bank.txt":  "---\ncontacts: 5491100000000, 123@g.us\ntasks: 4, #5\nactions: response\n---\nNo transfers.",
+	}
+	for name, content := range files {
+		if err := os.WriteFile(filepath.Join(rulesDir, name), []byte(content), 0644); err != nil {
+			t.Fatalf("failed to write %s: %v", name, err)
+		}
+	}
+
+	rules, err := NewPromptManager(tmpDir).LoadWatcherRules(WatcherData{Target: "bob"})
+	if err != nil {
+		t.Fatalf("LoadWatcherRules failed: %v", err)
+	}
+	if len(rules) != 2 || rules[0].ID != "00_basic" || rules[0].Content != "Be nice to bob." {
+		t.Fatalf("unexpected rules: %+v", rules)
+	}
+	bank := rules[1]
+	if bank.Content != "No transfers." || len(bank.Contacts) != 2 || len(bank.Tasks) != 2 || bank.Tasks[1] != 5 || bank.Actions[0] != "response" {
+		t.Errorf("unexpected bank rule: %+v", bank)
+	}
+}
@@ -22,22 +22,33 @@ const (
 
 // Task represents a task stored as a JSON file
 type Task struct {
-	ID                     int    `json:"id"`
-	Objective              string `json:"objective"`
-	OriginalOrders         string `json:"original_orders"`
-	Contact                string `json:"contact"`
-	ChatID                 string `json:"chat_id,omitempty"` // Chat JID where task is active (may differ from Contact for bots)
-	Status                 string `json:"status"`
-	LastProcessedTimestamp int64  `json:"last_processed_timestamp,omitempty"` // Unix timestamp of last processed message
-	ScheduleDatetime       string `json:"schedule_datetime,omitempty"`        // ISO 8601 formatted string (YYYY-MM-DDTHH:MM)
+	ID                     int      `json:"id"`
+	Objective              string   `json:"objective"`
+	OriginalOrders         string   `json:"original_orders"`
+	Contact                string   `json:"contact"`
+	ChatID                 string   `json:"chat_id,omitempty"` // Chat JID where task is active (may differ from Contact for bots)
+	Status                 string   `json:"status"`
+	LastProcessedTimestamp int64    `json:"last_processed_timestamp,omitempty"` // Unix timestamp of last processed message
+	ScheduleDatetime       string   `json:"schedule_datetime,omitempty"`        // ISO 8601 formatted string (YYYY-MM-DDTHH:MM)
+	WatcherPolicy          string   `json:"watcher_policy,omitempty"`           // WatcherRetry, or empty to let the master decide
+	Language               string   `json:"language,omitempty"`                 // Prompt language override for this task
+	Actions                []string `json:"actions,omitempty"`                  // Allowed actions, narrowing the task mode permissions
+	Shadow                 bool     `json:"shadow,omitempty"`                   // Capture outbound side effects instead of performing them
 }
 
+// WatcherRetry makes blocked actions of a task always retry with the watcher feedback; the master can't overrule them
+const WatcherRetry = "retry"
+
 // CreateTaskContent represents the content of a create_task action
 type CreateTaskContent struct {
-	Objective        string `json:"objective"`
-	Contact          string `json:"contact"`
-	OriginalOrders   string `json:"original_orders"`
-	ScheduleDatetime string `json:"schedule_datetime,omitempty"`
+	Objective        string   `json:"objective"`
+	Contact          string   `json:"contact"`
+	OriginalOrders   string   `json:"original_orders"`
+	ScheduleDatetime string   `json:"schedule_datetime,omitempty"`
+	WatcherPolicy    string   `json:"watcher_policy,omitempty"`
+	Language         string   `json:"language,omitempty"`
+	Actions          []string `json:"actions,omitempty"`
+	Shadow           bool     `json:"shadow,omitempty"`
 }
 
 // Reporter is an interface for reporting task status changes to the user via a kernel (e.g. Batata)
@@ -316,6 +327,26 @@ func (tm *TaskManager) ResumeTask(id int) error {
 	return nil
 }
 
+// FinishTask marks an active task as finished
+func (tm *TaskManager) FinishTask(id int) error {
+	task, err := tm.LoadTask(id)
+	if err != nil {
+		return err
+	}
+
+	if task.Status == StatusFinished || task.Status == StatusUnconfirmed {
+		return fmt.Errorf("task %d cannot be finished (current status: %s)", id, task.Status)
+	}
+
+	task.Status = StatusFinished
+	if err := tm.SaveTask(task); err != nil {
+		return err
+	}
+
+	fmt.Printf("[TaskManager] Finished task %d: status changed to %s\n", id, task.Status)
+	return nil
+}
+
 // GetTaskByContact finds an active (running or pending) task for the given contact or chat ID
 func (tm *TaskManager) GetTaskByContact(contactOrChatID string) (*Task, error) {
 	entries, err := os.ReadDir(tm.TasksDir)
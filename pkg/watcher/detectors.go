@@ -0,0 +1,213 @@
+package watcher
+
+import (
+	"math/big"
+	"regexp"
+	"strings"
+	"unicode"
+)
+
+// Built-in detectors
+const (
+	DetectPhone      = "phone"
+	DetectEmail      = "email"
+	DetectCard       = "card"
+	DetectIBAN       = "iban"
+	DetectCBU        = "cbu"
+	DetectNationalID = "national_id"
+	DetectAddress    = "address"
+	DetectSecrets    = "secrets"
+)
+
+// span is a match position in a text
+type span struct {
+	start, end int
+}
+
+var (
+	phoneRe   = regexp.MustCompile(`\+?\(?\b\d[\d\s().-]{6,}\d\b`)
+	dateRe    = regexp.MustCompile(`\b\d{4}[-/.]\d{1,2}[-/.]\d{1,2}\b|\b\d{1,2}[-/.]\d{1,2}[-/.]\d{4}\b`)
+	refRe     = regexp.MustCompile(`(?i)(?:#|n[º°o]\.?|order|pedido|orden|factura|invoice|ref\.?)\s*$`)
+	emailRe   = regexp.MustCompile(`(?i)[a-z0-9._%+-]+@[a-z0-9.-]+\.[a-z]{2,}`)
+	cardRe    = regexp.MustCompile(`\b(?:\d[ -]?){12,18}\d\b`)
+	ibanRe    = regexp.MustCompile(`\b[A-Z]{2}\d{2}(?: ?[A-Z0-9]){11,30}\b`)
+	cbuRe     = regexp.MustCompile(`\b\d{22}\b`)
+	dniRe     = regexp.MustCompile(`\b\d{1,2}\.\d{3}\.\d{3}\b|(?i)\b(?:dni|documento|passport|pasaporte)\s*(?:n[º°o]\.?|#|:)?\s*[A-Z]?\d{6,9}\b`)
+	cuitRe    = regexp.MustCompile(`\b(?:20|23|24|27|30|33|34)-?\d{8}-?\d\b`)
+	addressRe = regexp.MustCompile(`(?i)\b(?:calle|av\.?|avenida|avda\.?|pasaje|bv\.?|boulevard|ruta|street|st\.|road|rd\.|avenue|ave\.?)\s+[\p{L}0-9 .']{2,40}?\s\d{1,5}\b`)
+)
+
+// detect returns the spans found by a built-in detector
+func detect(detector, text string, secrets []string) []span {
+	switch detector {
+	case DetectPhone:
+		return findPhones(text)
+	case DetectEmail:
+		return filterSpans(text, emailRe, nil)
+	case DetectCard:
+		return filterSpans(text, cardRe, func(s string) bool {
+			d := digitsOnly(s)
+			return len(d) >= 13 && len(d) <= 19 && luhn(d)
+		})
+	case DetectIBAN:
+		return filterSpans(text, ibanRe, validIBAN)
+	case DetectCBU:
+		return filterSpans(text, cbuRe, validCBU)
+	case DetectNationalID:
+		spans := filterSpans(text, dniRe, nil)
+		return append(spans, filterSpans(text, cuitRe, func(s string) bool { return validCUIT(digitsOnly(s)) })...)
+	case DetectAddress:
+		return filterSpans(text, addressRe, nil)
+	case DetectSecrets:
+		return findSecrets(text, secrets)
+	}
+	return nil
+}
+
+// findPhones returns the runs of 8 to 15 digits that aren't dates, times or
+// references like "#20240001" or "pedido nº 12345678"
+func findPhones(text string) []span {
+	var spans []span
+	for _, loc := range phoneRe.FindAllStringIndex(text, -1) {
+		s := text[loc[0]:loc[1]]
+		if n := countDigits(s); n < 8 || n > 15 || dateRe.MatchString(s) {
+			continue
+		}
+		if loc[1] < len(text) && text[loc[1]] == ':' {
+			continue // Runs into a time, e.g. "01 12:00"
+		}
+		if refRe.MatchString(text[max(0, loc[0]-12):loc[0]]) {
+			continue
+		}
+		spans = append(spans, span{loc[0], loc[1]})
+	}
+	return spans
+}
+
+// filterSpans returns the regex matches accepted by valid (all if nil)
+func filterSpans(text string, re *regexp.Regexp, valid func(string) bool) []span {
+	var spans []span
+	for _, loc := range re.FindAllStringIndex(text, -1) {
+		if valid == nil || valid(text[loc[0]:loc[1]]) {
+			spans = append(spans, span{loc[0], loc[1]})
+		}
+	}
+	return spans
+}
+
+// findSecrets finds case-insensitive occurrences of the secrets
+func findSecrets(text string, secrets []string) []span {
+	var spans []span
+	lower := strings.ToLower(text)
+	for _, secret := range secrets {
+		s := strings.ToLower(secret)
+		for from := 0; ; {
+			i := strings.Index(lower[from:], s)
+			if i < 0 {
+				break
+			}
+			spans = append(spans, span{from + i, from + i + len(s)})
+			from += i + len(s)
+		}
+	}
+	return spans
+}
+
+func countDigits(s string) int {
+	n := 0
+	for _, r := range s {
+		if unicode.IsDigit(r) {
+			n++
+		}
+	}
+	return n
+}
+
+func digitsOnly(s string) string {
+	var sb strings.Builder
+	for _, r := range s {
+		if r >= '0' && r <= '9' {
+			sb.WriteRune(r)
+		}
+	}
+	return sb.String()
+}
+
+// luhn validates card numbers
+func luhn(digits string) bool {
+	sum := 0
+	double := false
+	for i := len(digits) - 1; i >= 0; i-- {
+		d := int(digits[i] - '0')
+		if double {
+			d *= 2
+			if d > 9 {
+				d -= 9
+			}
+		}
+		sum += d
+		double = !double
+	}
+	return sum%10 == 0
+}
+
+// validIBAN checks the ISO 13616 mod 97 checksum
+func validIBAN(s string) bool {
+	s = strings.ReplaceAll(s, " ", "")
+	if len(s) < 15 || len(s) > 34 {
+		return false
+	}
+	rearranged := s[4:] + s[:4]
+	var sb strings.Builder
+	for _, r := range rearranged {
+		switch {
+		case r >= '0' && r <= '9':
+			sb.WriteRune(r)
+		case r >= 'A' && r <= 'Z':
+			sb.WriteString(big.NewInt(int64(r - 'A' + 10)).String())
+		default:
+			return false
+		}
+	}
+	n, ok := new(big.Int).SetString(sb.String(), 10)
+	if !ok {
+		return false
+	}
+	return new(big.Int).Mod(n, big.NewInt(97)).Int64() == 1
+}
+
+// validCBU checks both check digits of an Argentine CBU
+func validCBU(s string) bool {
+	if len(s) != 22 {
+		return false
+	}
+	check := func(block string, weights []int) bool {
+		sum := 0
+		for i, w := range weights {
+			sum += int(block[i]-'0') * w
+		}
+		return (10-sum%10)%10 == int(block[len(weights)]-'0')
+	}
+	return check(s[:8], []int{7, 1, 3, 9, 7, 1, 3}) &&
+		check(s[8:], []int{3, 9, 7, 1, 3, 9, 7, 1, 3, 9, 7, 1, 3})
+}
+
+// validCUIT checks the check digit of an Argentine CUIT/CUIL
+func validCUIT(s string) bool {
+	if len(s) != 11 {
+		return false
+	}
+	weights := []int{5, 4, 3, 2, 7, 6, 5, 4, 3, 2}
+	sum := 0
+	for i, w := range weights {
+		sum += int(s[i]-'0') * w
+	}
+	check := 11 - sum%11
+	switch check {
+	case 11:
+		check = 0
+	case 10:
+		check = 9
+	}
+	return check == int(s[10]-'0')
+}
@@ -0,0 +1,378 @@
+package watcher
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"whatsabladerunner/pkg/behaviors"
+)
+
+// Rule verdicts
+const (
+	VerdictBlock    = "block"    // Never run the action
+	VerdictRedact   = "redact"   // Replace the match before running the action
+	VerdictEscalate = "escalate" // Ask the LLM watcher
+	VerdictAllow    = "allow"    // Run the action without asking the LLM watcher
+)
+
+// LLM review modes
+const (
+	LLMReviewAlways     = "always"      // The LLM watcher reviews every action not blocked or allowed by a rule
+	LLMReviewOnEscalate = "on_escalate" // Only actions with an escalate verdict reach the LLM watcher
+)
+
+// LLM watcher call modes
+const (
+	LLMModeBatch    = "batch"    // One call returning a verdict per rule
+	LLMModeParallel = "parallel" // One concurrent call per rule
+)
+
+// Failure policies, for when the LLM watcher can't give a verdict
+const (
+	FailClosed = "fail_closed" // Withhold the action until the master decides
+	FailOpen   = "fail_open"   // Run the action and log it
+	RulesOnly  = "rules_only"  // Trust the deterministic rules: run unless a rule escalated
+)
+
+// FailurePolicy sets the failure policy for a scope. Empty lists match anything.
+type FailurePolicy struct {
+	Policy   string   `json:"policy"`
+	Contacts []string `json:"contacts,omitempty"`
+	Tasks    []int    `json:"tasks,omitempty"`
+	Modes    []string `json:"modes,omitempty"` // command, task or behavior
+}
+
+// Rule is a deterministic watcher rule, from config/watcher/rules.json
+type Rule struct {
+	Name     string   `json:"name"`
+	Detector string   `json:"detector,omitempty"` // Built-in detector (phone, email, card, iban, cbu, national_id, address, secrets)
+	Pattern  string   `json:"pattern,omitempty"`  // User regex, used when no detector is set
+	Verdict  string   `json:"verdict"`
+	Contacts []string `json:"contacts,omitempty"` // Only for these target chats (JIDs or numbers)
+	Tasks    []int    `json:"tasks,omitempty"`    // Only for these task IDs
+
+	re *regexp.Regexp
+}
+
+// RuleSet is the ordered list of rules. For overlapping matches the first rule wins.
+type RuleSet struct {
+	Rules     []Rule `json:"rules"`
+	LLMReview string `json:"llm_review"`
+	LLMMode   string `json:"llm_mode"` // How rule files in config/watcher/rules/ are evaluated
+	OnError   string `json:"on_error"` // Failure policy when no scoped one matches
+	// OnErrorScopes are checked in order before OnError
+	OnErrorScopes []FailurePolicy `json:"on_error_scopes,omitempty"`
+
+	secrets []string
+}
+
+// Scope identifies the action being checked, for scoped rules
+type Scope struct {
+	Contact string // Target chat
+	TaskID  int
+	Mode    string
+}
+
+// Match is a rule match in a text
+type Match struct {
+	Rule    string
+	Verdict string
+	Text    string
+}
+
+// Result is the combined outcome of the rules for a text
+type Result struct {
+	Verdict  string // Most severe verdict (block > escalate > allow > redact), empty if nothing matched
+	Redacted string // Text with the redact matches replaced
+	Matches  []Match
+}
+
+// DefaultRules are used when config/watcher/rules.json doesn't exist
+func DefaultRules() []Rule {
+	return []Rule{
+		{Name: "secrets", Detector: DetectSecrets, Verdict: VerdictBlock},
+		{Name: "card", Detector: DetectCard, Verdict: VerdictBlock},
+		{Name: "iban", Detector: DetectIBAN, Verdict: VerdictBlock},
+		{Name: "cbu", Detector: DetectCBU, Verdict: VerdictBlock},
+		{Name: "national_id", Detector: DetectNationalID, Verdict: VerdictEscalate},
+		{Name: "email", Detector: DetectEmail, Verdict: VerdictEscalate},
+		{Name: "phone", Detector: DetectPhone, Verdict: VerdictEscalate},
+		{Name: "address", Detector: DetectAddress, Verdict: VerdictEscalate},
+	}
+}
+
+// LoadRuleSet reads rules.json and secrets.txt from the watcher config directory
+func LoadRuleSet(dir string) (*RuleSet, error) {
+	rs := &RuleSet{}
+	data, err := os.ReadFile(filepath.Join(dir, "rules.json"))
+	if err != nil {
+		if !os.IsNotExist(err) {
+			return nil, fmt.Errorf("failed to read watcher rules: %w", err)
+		}
+		rs.Rules = DefaultRules()
+	} else if err := json.Unmarshal(data, rs); err != nil {
+		return nil, fmt.Errorf("failed to parse watcher rules: %w", err)
+	}
+
+	if rs.LLMReview == "" {
+		rs.LLMReview = LLMReviewAlways
+	}
+	if rs.LLMReview != LLMReviewAlways && rs.LLMReview != LLMReviewOnEscalate {
+		return nil, fmt.Errorf("invalid llm_review '%s'", rs.LLMReview)
+	}
+	if rs.LLMMode == "" {
+		rs.LLMMode = LLMModeBatch
+	}
+	if rs.LLMMode != LLMModeBatch && rs.LLMMode != LLMModeParallel {
+		return nil, fmt.Errorf("invalid llm_mode '%s'", rs.LLMMode)
+	}
+
+	if rs.OnError == "" {
+		rs.OnError = FailClosed
+	}
+	if !validFailurePolicy(rs.OnError) {
+		return nil, fmt.Errorf("invalid on_error '%s'", rs.OnError)
+	}
+	for _, fp := range rs.OnErrorScopes {
+		if !validFailurePolicy(fp.Policy) {
+			return nil, fmt.Errorf("invalid on_error_scopes policy '%s'", fp.Policy)
+		}
+	}
+
+	for i := range rs.Rules {
+		if err := rs.Rules[i].compile(); err != nil {
+			return nil, err
+		}
+	}
+
+	secrets, err := loadSecrets(filepath.Join(dir, "secrets.txt"))
+	if err != nil {
+		return nil, err
+	}
+	rs.secrets = secrets
+	return rs, nil
+}
+
+func validFailurePolicy(policy string) bool {
+	return policy == FailClosed || policy == FailOpen || policy == RulesOnly
+}
+
+// FailurePolicyFor returns the failure policy of the first scope matching, or OnError
+func (rs *RuleSet) FailurePolicyFor(scope Scope) string {
+	for _, fp := range rs.OnErrorScopes {
+		if inScope(fp.Contacts, fp.Tasks, scope) && (len(fp.Modes) == 0 || containsString(fp.Modes, scope.Mode)) {
+			return fp.Policy
+		}
+	}
+	if rs.OnError == "" {
+		return FailClosed
+	}
+	return rs.OnError
+}
+
+func containsString(list []string, s string) bool {
+	for _, item := range list {
+		if item == s {
+			return true
+		}
+	}
+	return false
+}
+
+func (r *Rule) compile() error {
+	switch r.Verdict {
+	case VerdictBlock, VerdictRedact, VerdictEscalate, VerdictAllow:
+	default:
+		return fmt.Errorf("rule '%s': invalid verdict '%s'", r.Name, r.Verdict)
+	}
+	if r.Detector != "" {
+		switch r.Detector {
+		case DetectPhone, DetectEmail, DetectCard, DetectIBAN, DetectCBU, DetectNationalID, DetectAddress, DetectSecrets:
+		default:
+			return fmt.Errorf("rule '%s': unknown detector '%s'", r.Name, r.Detector)
+		}
+		return nil
+	}
+	if r.Pattern == "" {
+		return fmt.Errorf("rule '%s': needs a detector or a pattern", r.Name)
+	}
+	re, err := regexp.Compile(r.Pattern)
+	if err != nil {
+		return fmt.Errorf("rule '%s': invalid pattern: %w", r.Name, err)
+	}
+	r.re = re
+	return nil
+}
+
+// loadSecrets reads one secret per line. Blank lines, comments and very short entries are skipped.
+func loadSecrets(path string) ([]string, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("failed to read watcher secrets: %w", err)
+	}
+	defer f.Close()
+
+	var secrets []string
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if len(line) < 4 || strings.HasPrefix(line, "#") {
+			continue
+		}
+		secrets = append(secrets, line)
+	}
+	return secrets, scanner.Err()
+}
+
+// applies reports whether the rule is in scope
+func (r *Rule) applies(scope Scope) bool {
+	return inScope(r.Contacts, r.Tasks, scope)
+}
+
+// inScope checks contact and task restrictions. Empty lists match anything.
+func inScope(contacts []string, tasks []int, scope Scope) bool {
+	if len(contacts) > 0 {
+		found := false
+		for _, c := range contacts {
+			if scope.Contact != "" && behaviors.MatchJID(c, scope.Contact) {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return false
+		}
+	}
+	if len(tasks) > 0 {
+		found := false
+		for _, id := range tasks {
+			if id == scope.TaskID {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return false
+		}
+	}
+	return true
+}
+
+func (r *Rule) find(text string, secrets []string) []span {
+	if r.re != nil {
+		return filterSpans(text, r.re, nil)
+	}
+	return detect(r.Detector, text, secrets)
+}
+
+// claim is a span owned by a rule
+type claim struct {
+	span
+	rule *Rule
+}
+
+// Evaluate runs the rules in scope over a text
+func (rs *RuleSet) Evaluate(text string, scope Scope) Result {
+	var claims []claim
+	for i := range rs.Rules {
+		rule := &rs.Rules[i]
+		if !rule.applies(scope) {
+			continue
+		}
+		for _, s := range rule.find(text, rs.secrets) {
+			overlaps := false
+			for _, c := range claims {
+				if s.start < c.end && c.start < s.end {
+					overlaps = true
+					break
+				}
+			}
+			if !overlaps {
+				claims = append(claims, claim{s, rule})
+			}
+		}
+	}
+
+	sort.Slice(claims, func(i, j int) bool { return claims[i].start < claims[j].start })
+	result := Result{}
+	var sb strings.Builder
+	last := 0
+	for _, c := range claims {
+		result.Matches = append(result.Matches, Match{Rule: c.rule.Name, Verdict: c.rule.Verdict, Text: text[c.start:c.end]})
+		if severity(c.rule.Verdict) > severity(result.Verdict) {
+			result.Verdict = c.rule.Verdict
+		}
+		if c.rule.Verdict == VerdictRedact {
+			sb.WriteString(text[last:c.start])
+			sb.WriteString("[REDACTED:" + c.rule.Name + "]")
+			last = c.end
+		}
+	}
+	sb.WriteString(text[last:])
+	result.Redacted = sb.String()
+	return result
+}
+
+func severity(verdict string) int {
+	switch verdict {
+	case VerdictRedact:
+		return 1
+	case VerdictAllow:
+		return 2
+	case VerdictEscalate:
+		return 3
+	case VerdictBlock:
+		return 4
+	}
+	return 0
+}
+
+// RulesFor returns the names of the matched rules with the given verdict
+func (r Result) RulesFor(verdict string) []string {
+	var names []string
+	seen := map[string]bool{}
+	for _, m := range r.Matches {
+		if m.Verdict == verdict && !seen[m.Rule] {
+			seen[m.Rule] = true
+			names = append(names, m.Rule)
+		}
+	}
+	return names
+}
+
+// RedactPayload applies the redact rules to every string of a JSON payload
+func (rs *RuleSet) RedactPayload(payload json.RawMessage, scope Scope) json.RawMessage {
+	var v interface{}
+	if err := json.Unmarshal(payload, &v); err != nil {
+		return payload
+	}
+	redacted, err := json.Marshal(rs.redactValue(v, scope))
+	if err != nil {
+		return payload
+	}
+	return redacted
+}
+
+func (rs *RuleSet) redactValue(v interface{}, scope Scope) interface{} {
+	switch val := v.(type) {
+	case string:
+		return rs.Evaluate(val, scope).Redacted
+	case []interface{}:
+		for i := range val {
+			val[i] = rs.redactValue(val[i], scope)
+		}
+	case map[string]interface{}:
+		for k := range val {
+			val[k] = rs.redactValue(val[k], scope)
+		}
+	}
+	return v
+}
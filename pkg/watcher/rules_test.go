@@ -0,0 +1,102 @@
+package watcher
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+)
+
+func TestDetectors(t *testing.T) {
+	cases := []struct {
+		detector string
+		text     string
+		want     bool
+	}{
+		{DetectCard, "mi tarjeta es 4111 1111 1111 1111", true},
+		{DetectCard, "número 4111 1111 1111 1112", false},
+		{DetectIBAN, "IBAN: DE89 3704 0044 0532 0130 00", true},
+		{DetectIBAN, "IBAN: DE88 3704 0044 0532 0130 00", false},
+		{DetectCBU, "CBU 2850590940090418135201", true},
+		{DetectCBU, "CBU 2850590940090418135202", false},
+		{DetectNationalID, "mi DNI es 30.123.456", true},
+		{DetectNationalID, "CUIT 20-12345678-6", true},
+		{DetectEmail, "escribime a juan.perez@example.com", true},
+		{DetectPhone, "llamame al +54 9 11 5555-1234", true},
+		{DetectPhone, "son las 18:30", false},
+		{DetectPhone, "turno el 2024-01-01 12:00", false},
+		{DetectPhone, "vence el 01/02/2024 a las 10:30", false},
+		{DetectPhone, "tu pedido #20240001234 está en camino", false},
+		{DetectPhone, "factura nº 0001-00012345", false},
+		{DetectPhone, "mi número es 11 5555-1234", true},
+		{DetectAddress, "vivo en Avenida Corrientes 1234", true},
+	}
+	for _, c := range cases {
+		got := len(detect(c.detector, c.text, nil)) > 0
+		if got != c.want {
+			t.Errorf("%s on %q: got %v, want %v", c.detector, c.text, got, c.want)
+		}
+	}
+}
+
+func TestEvaluateScopeAndPrecedence(t *testing.T) {
+	rs := &RuleSet{
+		Rules: []Rule{
+			{Name: "friend_phone", Detector: DetectPhone, Verdict: VerdictAllow, Contacts: []string{"5491100000001"}},
+			{Name: "task_phone", Detector: DetectPhone, Verdict: VerdictRedact, Tasks: []int{7}},
+			{Name: "phone", Detector: DetectPhone, Verdict: VerdictEscalate},
+			{Name: "card", Detector: DetectCard, Verdict: VerdictBlock},
+			{Name: "secrets", Detector: DetectSecrets, Verdict: VerdictBlock},
+		},
+		secrets: []string{"hunter22"},
+	}
+	for i := range rs.Rules {
+		if err := rs.Rules[i].compile(); err != nil {
+			t.Fatal(err)
+		}
+	}
+	msg := "mi número es +54 9 11 5555-1234"
+
+	if r := rs.Evaluate(msg, Scope{Contact: "5491100000001@s.whatsapp.net"}); r.Verdict != VerdictAllow {
+		t.Errorf("expected allow for friend, got %q", r.Verdict)
+	}
+	r := rs.Evaluate(msg, Scope{Contact: "5491100000002@s.whatsapp.net", TaskID: 7})
+	if r.Verdict != VerdictRedact || !strings.Contains(r.Redacted, "[REDACTED:task_phone]") {
+		t.Errorf("expected redact in task 7, got %+v", r)
+	}
+	if r := rs.Evaluate(msg, Scope{}); r.Verdict != VerdictEscalate {
+		t.Errorf("expected escalate, got %q", r.Verdict)
+	}
+	if r := rs.Evaluate("la clave es HUNTER22 y la tarjeta 4111111111111111", Scope{}); r.Verdict != VerdictBlock || len(r.RulesFor(VerdictBlock)) != 2 {
+		t.Errorf("expected block by secrets and card, got %+v", r)
+	}
+
+	payload := rs.RedactPayload(json.RawMessage(`{"text": "`+msg+`"}`), Scope{TaskID: 7})
+	if !strings.Contains(string(payload), "[REDACTED:task_phone]") {
+		t.Errorf("payload not redacted: %s", payload)
+	}
+}
+
+func TestFailurePolicyFor(t *testing.T) {
+	rs := &RuleSet{
+		OnError: FailClosed,
+		OnErrorScopes: []FailurePolicy{
+			{Policy: FailOpen, Contacts: []string{"5491100000001"}},
+			{Policy: RulesOnly, Modes: []string{"behavior"}},
+			{Policy: FailClosed, Tasks: []int{7}, Modes: []string{"task"}},
+		},
+	}
+	cases := []struct {
+		scope Scope
+		want  string
+	}{
+		{Scope{Contact: "5491100000001@s.whatsapp.net", Mode: "task", TaskID: 7}, FailOpen},
+		{Scope{Contact: "5491100000002@s.whatsapp.net", Mode: "behavior"}, RulesOnly},
+		{Scope{Contact: "5491100000002@s.whatsapp.net", Mode: "task", TaskID: 3}, FailClosed},
+		{Scope{Mode: "command"}, FailClosed},
+	}
+	for _, c := range cases {
+		if got := rs.FailurePolicyFor(c.scope); got != c.want {
+			t.Errorf("FailurePolicyFor(%+v) = %s, want %s", c.scope, got, c.want)
+		}
+	}
+}
@@ -0,0 +1,139 @@
+package watcher
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Rule kinds in the stats
+const (
+	KindDetector = "rule" // Deterministic rule from rules.json
+	KindLLM      = "llm"  // Rule file reviewed by the LLM watcher
+)
+
+// RuleStats tallies how a rule behaved
+type RuleStats struct {
+	Kind       string `json:"kind"`
+	Checks     int    `json:"checks"` // LLM rules: times evaluated; deterministic rules: times matched
+	Blocks     int    `json:"blocks"`
+	LastBlock  int64  `json:"last_block,omitempty"`
+	LastReason string `json:"last_reason,omitempty"`
+}
+
+// Stats keeps the per-rule tallies in a JSON file. Use one Stats per file: the lock
+// doesn't span instances.
+type Stats struct {
+	Path string
+	mu   sync.Mutex
+}
+
+// NewStats creates a Stats stored at path
+func NewStats(path string) *Stats {
+	return &Stats{Path: path}
+}
+
+func (s *Stats) load() (map[string]*RuleStats, error) {
+	stats := map[string]*RuleStats{}
+	data, err := os.ReadFile(s.Path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return stats, nil
+		}
+		return nil, fmt.Errorf("failed to read watcher stats: %w", err)
+	}
+	if err := json.Unmarshal(data, &stats); err != nil {
+		return nil, fmt.Errorf("failed to parse watcher stats: %w", err)
+	}
+	return stats, nil
+}
+
+func (s *Stats) save(stats map[string]*RuleStats) error {
+	data, err := json.MarshalIndent(stats, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to marshal watcher stats: %w", err)
+	}
+	if err := os.MkdirAll(filepath.Dir(s.Path), 0755); err != nil {
+		return fmt.Errorf("failed to create watcher stats directory: %w", err)
+	}
+	if err := os.WriteFile(s.Path, data, 0644); err != nil {
+		return fmt.Errorf("failed to write watcher stats: %w", err)
+	}
+	return nil
+}
+
+// Record counts one check of a rule, and the block if it blocked
+func (s *Stats) Record(kind, rule string, blocked bool, reason string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	stats, err := s.load()
+	if err != nil {
+		return err
+	}
+	key := kind + ":" + rule
+	st, ok := stats[key]
+	if !ok {
+		st = &RuleStats{Kind: kind}
+		stats[key] = st
+	}
+	st.Checks++
+	if blocked {
+		st.Blocks++
+		st.LastBlock = time.Now().Unix()
+		st.LastReason = reason
+	}
+	return s.save(stats)
+}
+
+// Reset clears all tallies
+func (s *Stats) Reset() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.save(map[string]*RuleStats{})
+}
+
+// Format renders the tallies, most blocking rules first
+func (s *Stats) Format() (string, error) {
+	s.mu.Lock()
+	stats, err := s.load()
+	s.mu.Unlock()
+	if err != nil {
+		return "", err
+	}
+	if len(stats) == 0 {
+		return "No watcher activity recorded.", nil
+	}
+
+	keys := make([]string, 0, len(stats))
+	for k := range stats {
+		keys = append(keys, k)
+	}
+	sort.Slice(keys, func(i, j int) bool {
+		a, b := stats[keys[i]], stats[keys[j]]
+		if a.Blocks != b.Blocks {
+			return a.Blocks > b.Blocks
+		}
+		return keys[i] < keys[j]
+	})
+
+	var sb strings.Builder
+	sb.WriteString("Watcher rules (blocks/checks):")
+	for _, k := range keys {
+		st := stats[k]
+		line := fmt.Sprintf("\n- %s: %d/%d", k, st.Blocks, st.Checks)
+		if st.Checks > 0 {
+			line += fmt.Sprintf(" (%d%%)", st.Blocks*100/st.Checks)
+		}
+		if st.LastReason != "" {
+			line += fmt.Sprintf(", last %s: %s", time.Unix(st.LastBlock, 0).Format("01-02 15:04"), st.LastReason)
+		}
+		sb.WriteString(line)
+	}
+	return sb.String(), nil
+}
@@ -0,0 +1,64 @@
+package whatsapp
+
+import (
+	"context"
+
+	"go.mau.fi/whatsmeow"
+	"go.mau.fi/whatsmeow/types"
+)
+
+// Directory resolves display names from the contact store, for the history context lines
+type Directory struct {
+	Client func() *whatsmeow.Client // The client may be replaced after a re-pairing
+}
+
+// Name returns the saved, push or business name of a JID, or "" if unknown.
+// LIDs (anonymous group participants) are mapped to their phone number first.
+func (d *Directory) Name(jid string) string {
+	client := d.Client()
+	if client == nil || client.Store.Contacts == nil {
+		return ""
+	}
+	parsed, err := types.ParseJID(jid)
+	if err != nil {
+		return ""
+	}
+	ctx := context.Background()
+	info, err := client.Store.Contacts.GetContact(ctx, phoneJID(ctx, client, parsed))
+	if err != nil || !info.Found {
+		return ""
+	}
+	for _, name := range []string{info.FullName, info.FirstName, info.PushName, info.BusinessName} {
+		if name != "" {
+			return name
+		}
+	}
+	return ""
+}
+
+// phoneJID drops the device of a JID and maps a LID to its phone number when known
+func phoneJID(ctx context.Context, client *whatsmeow.Client, jid types.JID) types.JID {
+	jid = jid.ToNonAD()
+	if jid.Server == types.HiddenUserServer && client.Store.LIDs != nil {
+		if pn, err := client.Store.LIDs.GetPNForLID(ctx, jid); err == nil && !pn.IsEmpty() {
+			return pn
+		}
+	}
+	return jid
+}
+
+// IsMe reports whether the JID is our own account, by phone number or LID
+func (d *Directory) IsMe(jid string) bool {
+	client := d.Client()
+	if client == nil || client.Store.ID == nil {
+		return false
+	}
+	parsed, err := types.ParseJID(jid)
+	if err != nil {
+		return false
+	}
+	if parsed.User == client.Store.ID.User && parsed.Server == client.Store.ID.Server {
+		return true
+	}
+	return !client.Store.LID.IsEmpty() && parsed.User == client.Store.LID.User && parsed.Server == client.Store.LID.Server
+}
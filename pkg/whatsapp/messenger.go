@@ -0,0 +1,279 @@
+package whatsapp
+
+import (
+	"context"
+	"fmt"
+	"math/rand"
+	"time"
+	"whatsabladerunner/pkg/history"
+
+	"go.mau.fi/whatsmeow"
+	waProto "go.mau.fi/whatsmeow/proto/waE2E"
+	"go.mau.fi/whatsmeow/types"
+	"google.golang.org/protobuf/proto"
+)
+
+// RevokeWindow is how long a message can be deleted for everyone after it was sent
+const RevokeWindow = 48 * time.Hour
+
+// Messenger reacts to, marks as read, edits and deletes messages stored in the history
+type Messenger struct {
+	Client  func() *whatsmeow.Client // The client may be replaced after a re-pairing
+	History *history.HistoryStore
+}
+
+// ReactionText renders a reaction as a history line, e.g. `(reacted 👍 to #3EB0C7: "see you friday")`
+func ReactionText(emoji, messageID, quoted string) string {
+	if emoji == "" {
+		return fmt.Sprintf("(removed the reaction to #%s)", messageID)
+	}
+	text := fmt.Sprintf("(reacted %s to #%s", emoji, messageID)
+	if quoted != "" {
+		if r := []rune(quoted); len(r) > 80 {
+			quoted = string(r[:80]) + "..."
+		}
+		text += fmt.Sprintf(": %q", quoted)
+	}
+	return text + ")"
+}
+
+// EditedText renders an edit as a line, e.g. "(edited #3EB0C7) new text". The history
+// applies edits to the original message; the line is only stored when it isn't there.
+func EditedText(messageID, text string) string {
+	return fmt.Sprintf("(edited #%s) %s", messageID, text)
+}
+
+// DeletedText renders a revocation as a line, e.g. "(deleted #3EB0C7)"
+func DeletedText(messageID string) string {
+	return fmt.Sprintf("(deleted #%s)", messageID)
+}
+
+func parseChat(chatJID string) (types.JID, error) {
+	chat, err := types.ParseJID(chatJID)
+	if err != nil {
+		return types.JID{}, fmt.Errorf("invalid chat JID %s: %w", chatJID, err)
+	}
+	return chat, nil
+}
+
+// stored resolves the chat and the stored message
+func (m *Messenger) stored(chatJID, messageID string) (*whatsmeow.Client, types.JID, *history.StoredMessage, error) {
+	client := m.Client()
+	if client == nil || client.Store.ID == nil {
+		return nil, types.JID{}, nil, fmt.Errorf("whatsapp is not connected")
+	}
+	chat, err := parseChat(chatJID)
+	if err != nil {
+		return nil, types.JID{}, nil, err
+	}
+	msg, err := m.History.GetMessage(chatJID, messageID)
+	if err != nil {
+		return nil, types.JID{}, nil, err
+	}
+	if msg == nil {
+		return nil, types.JID{}, nil, fmt.Errorf("message #%s not found in this chat", messageID)
+	}
+	return client, chat, msg, nil
+}
+
+// senderOf returns the author of a stored message
+func senderOf(client *whatsmeow.Client, chat types.JID, msg *history.StoredMessage) types.JID {
+	if msg.IsFromMe {
+		return client.Store.ID.ToNonAD()
+	}
+	if sender, err := types.ParseJID(msg.SenderJID); err == nil {
+		return sender
+	}
+	return chat
+}
+
+// ownRecent checks that the message is ours and younger than the window
+func ownRecent(msg *history.StoredMessage, window time.Duration, what string) error {
+	if !msg.IsFromMe {
+		return fmt.Errorf("#%s is not your message, only your own messages can be %s", msg.MessageID, what)
+	}
+	if time.Since(msg.Timestamp) > window {
+		return fmt.Errorf("#%s is too old to be %s (limit %s)", msg.MessageID, what, window)
+	}
+	return nil
+}
+
+// React sends an emoji reaction (empty removes it) and records it in the history
+func (m *Messenger) React(chatJID, messageID, emoji string) error {
+	client, chat, msg, err := m.stored(chatJID, messageID)
+	if err != nil {
+		return err
+	}
+	resp, err := SendWithStealth(context.Background(), client, chat, client.BuildReaction(chat, senderOf(client, chat, msg), messageID, emoji))
+	if err != nil {
+		return fmt.Errorf("failed to send reaction: %w", err)
+	}
+	return m.History.SaveMessage(resp.ID, chatJID, "Me", ReactionText(emoji, messageID, msg.Content), time.Now(), true)
+}
+
+// MarkRead sends read receipts after a reading delay. Without IDs it marks the latest incoming messages.
+func (m *Messenger) MarkRead(chatJID string, messageIDs []string) error {
+	client := m.Client()
+	if client == nil {
+		return fmt.Errorf("whatsapp is not connected")
+	}
+	chat, err := parseChat(chatJID)
+	if err != nil {
+		return err
+	}
+
+	var messages []history.StoredMessage
+	if len(messageIDs) == 0 {
+		if messages, err = m.History.GetIncoming(chatJID, 10); err != nil {
+			return err
+		}
+	}
+	for _, id := range messageIDs {
+		msg, err := m.History.GetMessage(chatJID, id)
+		if err != nil {
+			return err
+		}
+		if msg == nil {
+			return fmt.Errorf("message #%s not found in this chat", id)
+		}
+		if !msg.IsFromMe {
+			messages = append(messages, *msg)
+		}
+	}
+	if len(messages) == 0 {
+		return nil
+	}
+
+	// Read time: 1-3 seconds plus ~15ms per character, capped at 10 seconds
+	chars := 0
+	for _, msg := range messages {
+		chars += len(msg.Content)
+	}
+	delay := time.Duration(1000+rand.Int63n(2000))*time.Millisecond + time.Duration(chars*15)*time.Millisecond
+	if delay > 10*time.Second {
+		delay = 10 * time.Second
+	}
+	time.Sleep(delay)
+
+	// Receipts are per sender in groups
+	bySender := make(map[string][]types.MessageID)
+	for _, msg := range messages {
+		bySender[msg.SenderJID] = append(bySender[msg.SenderJID], msg.MessageID)
+	}
+	for senderJID, ids := range bySender {
+		sender, _ := types.ParseJID(senderJID)
+		if err := client.MarkRead(context.Background(), ids, time.Now(), chat, sender); err != nil {
+			return fmt.Errorf("failed to mark messages as read: %w", err)
+		}
+	}
+	return nil
+}
+
+// Edit replaces the text of one of our messages within whatsmeow.EditWindow
+func (m *Messenger) Edit(chatJID, messageID, text string) error {
+	client, chat, msg, err := m.stored(chatJID, messageID)
+	if err != nil {
+		return err
+	}
+	if err := ownRecent(msg, whatsmeow.EditWindow, "edited"); err != nil {
+		return err
+	}
+	edit := client.BuildEdit(chat, messageID, &waProto.Message{Conversation: proto.String(text)})
+	if _, err := SendWithStealth(context.Background(), client, chat, edit); err != nil {
+		return fmt.Errorf("failed to edit message: %w", err)
+	}
+	_, err = m.History.EditMessage(chatJID, messageID, text, time.Now())
+	return err
+}
+
+// Delete revokes one of our messages for everyone within RevokeWindow
+func (m *Messenger) Delete(chatJID, messageID string) error {
+	client, chat, msg, err := m.stored(chatJID, messageID)
+	if err != nil {
+		return err
+	}
+	if err := ownRecent(msg, RevokeWindow, "deleted"); err != nil {
+		return err
+	}
+	if _, err := SendWithStealth(context.Background(), client, chat, client.BuildRevoke(chat, types.EmptyJID, messageID)); err != nil {
+		return fmt.Errorf("failed to delete message: %w", err)
+	}
+	_, err = m.History.RevokeMessage(chatJID, messageID, time.Now())
+	return err
+}
+
+// RecordChange applies an incoming edit or revocation (Normalize types "edit" and
+// "revoke") to the original message. Only the author of the original may change it,
+// and group admins may revoke it: senderJID and fromMe describe who sent the change,
+// and the protocol key in meta must name the original's author. ChangeMissing means
+// the original isn't stored, so the caller can keep the change as a line of its own.
+func (m *Messenger) RecordChange(chatJID, senderJID string, fromMe bool, meta history.Meta, at time.Time) (history.ChangeResult, error) {
+	if meta.Type != "edit" && meta.Type != "revoke" {
+		return history.ChangeMissing, nil
+	}
+	original, err := m.History.GetMessage(chatJID, meta.QuotedID)
+	if err != nil || original == nil {
+		return history.ChangeMissing, err
+	}
+	keyFromMe, _ := meta.Payload["from_me"].(bool)
+	participant, _ := meta.Payload["participant"].(string)
+	if keyFromMe != original.IsFromMe || (participant != "" && !original.IsFromMe && !m.sameUser(participant, original.SenderJID)) {
+		return history.ChangeRejected, nil
+	}
+	author := fromMe == original.IsFromMe && (fromMe || m.sameUser(senderJID, original.SenderJID))
+	if !author && !(meta.Type == "revoke" && m.isGroupAdmin(chatJID, senderJID, fromMe)) {
+		return history.ChangeRejected, nil
+	}
+
+	if meta.Type == "edit" {
+		text, _ := meta.Payload["text"].(string)
+		return m.History.EditMessage(chatJID, meta.QuotedID, text, at)
+	}
+	return m.History.RevokeMessage(chatJID, meta.QuotedID, at)
+}
+
+// sameUser reports whether two JIDs are the same account, whatever the device or
+// the addressing (phone number or LID)
+func (m *Messenger) sameUser(a, b string) bool {
+	ja, errA := types.ParseJID(a)
+	jb, errB := types.ParseJID(b)
+	if errA != nil || errB != nil {
+		return false
+	}
+	if ja.ToNonAD() == jb.ToNonAD() {
+		return true
+	}
+	if m.Client == nil || m.Client() == nil {
+		return false
+	}
+	client, ctx := m.Client(), context.Background()
+	return phoneJID(ctx, client, ja) == phoneJID(ctx, client, jb)
+}
+
+// isGroupAdmin reports whether the sender of a change administers the group chat
+func (m *Messenger) isGroupAdmin(chatJID, senderJID string, fromMe bool) bool {
+	chat, err := types.ParseJID(chatJID)
+	if err != nil || chat.Server != types.GroupServer || m.Client == nil || m.Client() == nil {
+		return false
+	}
+	client := m.Client()
+	if fromMe && client.Store.ID != nil {
+		senderJID = client.Store.ID.String()
+	}
+	info, err := client.GetGroupInfo(context.Background(), chat)
+	if err != nil {
+		fmt.Printf("Failed to get group info of %s: %v\n", chatJID, err)
+		return false
+	}
+	for _, p := range info.Participants {
+		if !p.IsAdmin && !p.IsSuperAdmin {
+			continue
+		}
+		for _, jid := range []types.JID{p.JID, p.PhoneNumber, p.LID} {
+			if !jid.IsEmpty() && m.sameUser(jid.String(), senderJID) {
+				return true
+			}
+		}
+	}
+	return false
+}
@@ -0,0 +1,54 @@
+package whatsapp
+
+import (
+	"path/filepath"
+	"testing"
+	"time"
+
+	"whatsabladerunner/pkg/history"
+)
+
+func TestRecordChange(t *testing.T) {
+	h, err := history.New(filepath.Join(t.TempDir(), "history.db"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	m := &Messenger{History: h}
+	group := "120363000000000001@g.us"
+	alice, bob := "5491100000001@s.whatsapp.net", "5491100000002@s.whatsapp.net"
+	now := time.Now()
+	h.SaveMessage("A1", group, alice, "See you at 8", now, false)
+	h.SaveMessage("A2", group, alice, "Bring snacks", now, false)
+
+	edit := func(id, participant, text string) history.Meta {
+		return history.Meta{Type: "edit", QuotedID: id, Payload: map[string]any{"text": text, "from_me": false, "participant": participant}}
+	}
+	cases := []struct {
+		name   string
+		sender string
+		fromMe bool
+		meta   history.Meta
+		want   history.ChangeResult
+	}{
+		{"other member edits", bob, false, edit("A1", alice, "See you at 10"), history.ChangeRejected},
+		{"key names another author", alice, false, edit("A1", bob, "See you at 10"), history.ChangeRejected},
+		{"we edit a contact message", "", true, edit("A1", alice, "See you at 10"), history.ChangeRejected},
+		{"not an admin revokes", bob, false, history.Meta{Type: "revoke", QuotedID: "A2", Payload: map[string]any{"from_me": false, "participant": alice}}, history.ChangeRejected},
+		{"author edits from another device", "5491100000001:3@s.whatsapp.net", false, edit("A1", alice, "See you at 9"), history.ChangeApplied},
+		{"author revokes", alice, false, history.Meta{Type: "revoke", QuotedID: "A2", Payload: map[string]any{"from_me": false, "participant": alice}}, history.ChangeApplied},
+		{"edit after revoke", alice, false, edit("A2", alice, "Bring drinks"), history.ChangeDeleted},
+		{"unknown original", alice, false, edit("GONE", alice, "Hi"), history.ChangeMissing},
+	}
+	for _, c := range cases {
+		got, err := m.RecordChange(group, c.sender, c.fromMe, c.meta, now.Add(time.Minute))
+		if err != nil {
+			t.Fatalf("%s: %v", c.name, err)
+		}
+		if got != c.want {
+			t.Errorf("%s: got %v, want %v", c.name, got, c.want)
+		}
+	}
+	if msg, _ := h.GetMessage(group, "A1"); msg == nil || msg.Content != "See you at 9" {
+		t.Errorf("A1 should hold the author's edit, got %+v", msg)
+	}
+}
@@ -0,0 +1,399 @@
+package whatsapp
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+	"whatsabladerunner/pkg/history"
+
+	waProto "go.mau.fi/whatsmeow/proto/waE2E"
+)
+
+// unwrap strips the ephemeral, view-once and other wrappers. History sync messages
+// arrive wrapped; live events are usually unwrapped already.
+func unwrap(msg *waProto.Message) (*waProto.Message, bool) {
+	viewOnce := false
+	for i := 0; i < 5 && msg != nil; i++ {
+		var inner *waProto.FutureProofMessage
+		switch {
+		case msg.GetEphemeralMessage() != nil:
+			inner = msg.GetEphemeralMessage()
+		case msg.GetViewOnceMessage() != nil:
+			inner, viewOnce = msg.GetViewOnceMessage(), true
+		case msg.GetViewOnceMessageV2() != nil:
+			inner, viewOnce = msg.GetViewOnceMessageV2(), true
+		case msg.GetViewOnceMessageV2Extension() != nil:
+			inner, viewOnce = msg.GetViewOnceMessageV2Extension(), true
+		case msg.GetEditedMessage() != nil:
+			inner = msg.GetEditedMessage()
+		case msg.GetDocumentWithCaptionMessage() != nil:
+			inner = msg.GetDocumentWithCaptionMessage()
+		case msg.GetGroupMentionedMessage() != nil:
+			inner = msg.GetGroupMentionedMessage()
+		case msg.GetLottieStickerMessage() != nil:
+			inner = msg.GetLottieStickerMessage()
+		default:
+			return msg, viewOnce
+		}
+		msg = inner.GetMessage()
+	}
+	return msg, viewOnce
+}
+
+// contextInfo returns the context info of a message, whatever its type
+func contextInfo(msg *waProto.Message) *waProto.ContextInfo {
+	for _, info := range []*waProto.ContextInfo{
+		msg.GetExtendedTextMessage().GetContextInfo(),
+		msg.GetImageMessage().GetContextInfo(),
+		msg.GetVideoMessage().GetContextInfo(),
+		msg.GetPtvMessage().GetContextInfo(),
+		msg.GetAudioMessage().GetContextInfo(),
+		msg.GetDocumentMessage().GetContextInfo(),
+		msg.GetStickerMessage().GetContextInfo(),
+		msg.GetLocationMessage().GetContextInfo(),
+		msg.GetLiveLocationMessage().GetContextInfo(),
+		msg.GetContactMessage().GetContextInfo(),
+		msg.GetContactsArrayMessage().GetContextInfo(),
+		msg.GetButtonsResponseMessage().GetContextInfo(),
+		msg.GetListResponseMessage().GetContextInfo(),
+		msg.GetTemplateButtonReplyMessage().GetContextInfo(),
+		msg.GetInteractiveResponseMessage().GetContextInfo(),
+		msg.GetGroupInviteMessage().GetContextInfo(),
+		msg.GetEventMessage().GetContextInfo(),
+	} {
+		if info != nil {
+			return info
+		}
+	}
+	return nil
+}
+
+// duration renders seconds as "1:05"
+func duration(seconds uint32) string {
+	return fmt.Sprintf("%d:%02d", seconds/60, seconds%60)
+}
+
+// withCaption appends a caption to a media tag, e.g. "[Image] look at this"
+func withCaption(tag, caption string) string {
+	if caption == "" {
+		return tag
+	}
+	return tag + " " + caption
+}
+
+// vcardPhones extracts the phone numbers of a vCard
+func vcardPhones(vcard string) []string {
+	var phones []string
+	for _, line := range strings.Split(vcard, "\n") {
+		line = strings.TrimSpace(line)
+		if strings.HasPrefix(strings.ToUpper(line), "TEL") {
+			if i := strings.LastIndex(line, ":"); i >= 0 {
+				phones = append(phones, strings.TrimSpace(line[i+1:]))
+			}
+		}
+	}
+	return phones
+}
+
+// contactEntry renders a shared contact, e.g. "Juan: +54 9 11 1234-5678"
+func contactEntry(c *waProto.ContactMessage) (string, map[string]interface{}) {
+	phones := vcardPhones(c.GetVcard())
+	text := c.GetDisplayName()
+	if len(phones) > 0 {
+		text += ": " + strings.Join(phones, ", ")
+	}
+	return text, map[string]interface{}{"name": c.GetDisplayName(), "phones": phones, "vcard": c.GetVcard()}
+}
+
+// locationText renders coordinates with their place and a maps link
+func locationText(tag string, lat, lng float64, place ...string) string {
+	var parts []string
+	for _, p := range place {
+		if p != "" {
+			parts = append(parts, p)
+		}
+	}
+	text := tag
+	if len(parts) > 0 {
+		text += " " + strings.Join(parts, ", ")
+	}
+	return text + fmt.Sprintf(" (%.6f, %.6f) https://maps.google.com/?q=%.6f,%.6f", lat, lng, lat, lng)
+}
+
+// choice is a button or row the contact can pick
+type choice struct {
+	Text  string `json:"text"`
+	ID    string `json:"id,omitempty"`
+	URL   string `json:"url,omitempty"`
+	Phone string `json:"phone,omitempty"`
+}
+
+// choicesText lists the choices under a header, the IDs are what button_response needs
+func choicesText(header string, choices []choice) string {
+	if len(choices) == 0 {
+		return ""
+	}
+	text := "\n\n" + header
+	for _, c := range choices {
+		switch {
+		case c.URL != "":
+			text += fmt.Sprintf("\n- \"%s\" -> url: %s", c.Text, c.URL)
+		case c.Phone != "":
+			text += fmt.Sprintf("\n- \"%s\" -> phone: %s", c.Text, c.Phone)
+		default:
+			text += fmt.Sprintf("\n- \"%s\" -> id: %s", c.Text, c.ID)
+		}
+	}
+	return text
+}
+
+// joinLines joins the non-empty parts of a structured message
+func joinLines(parts ...string) string {
+	var lines []string
+	for _, p := range parts {
+		if p = strings.TrimSpace(p); p != "" {
+			lines = append(lines, p)
+		}
+	}
+	return strings.Join(lines, "\n")
+}
+
+// interactiveEntry renders an interactive (native flow) message and its buttons
+func interactiveEntry(im *waProto.InteractiveMessage) (string, []choice) {
+	var choices []choice
+	for _, b := range im.GetNativeFlowMessage().GetButtons() {
+		var params struct {
+			DisplayText string `json:"display_text"`
+			ID          string `json:"id"`
+			URL         string `json:"url"`
+			PhoneNumber string `json:"phone_number"`
+		}
+		json.Unmarshal([]byte(b.GetButtonParamsJSON()), &params)
+		if params.DisplayText == "" {
+			params.DisplayText = b.GetName()
+		}
+		choices = append(choices, choice{Text: params.DisplayText, ID: params.ID, URL: params.URL, Phone: params.PhoneNumber})
+	}
+	text := joinLines(im.GetHeader().GetTitle(), im.GetBody().GetText(), im.GetFooter().GetText())
+	return text + choicesText("[Opciones - responder con el id]:", choices), choices
+}
+
+// Normalize renders any message as a history line, with its type and structured
+// payload. It returns "" for messages with nothing to show (receipts, key
+// distribution). Stateful types are finished by the caller: poll votes need
+// decrypting and reactions quote the target from the history.
+func Normalize(msg *waProto.Message) (string, history.Meta) {
+	msg, viewOnce := unwrap(msg)
+	info := contextInfo(msg)
+	meta := history.Meta{Type: "unknown", QuotedID: info.GetStanzaID(), Mentions: info.GetMentionedJID()}
+	if msg == nil {
+		return "", meta
+	}
+
+	var text string
+	payload := map[string]interface{}{}
+	switch {
+	case msg.GetConversation() != "":
+		meta.Type, text = "text", msg.GetConversation()
+	case msg.GetExtendedTextMessage() != nil:
+		et := msg.GetExtendedTextMessage()
+		meta.Type, text = "text", et.GetText()
+		if et.GetMatchedText() != "" {
+			payload["link"] = et.GetMatchedText()
+			payload["link_title"] = et.GetTitle()
+		}
+
+	case msg.GetImageMessage() != nil:
+		im := msg.GetImageMessage()
+		meta.Type, text = "image", withCaption("[Image]", im.GetCaption())
+		payload["mimetype"], payload["caption"] = im.GetMimetype(), im.GetCaption()
+		viewOnce = viewOnce || im.GetViewOnce()
+	case msg.GetVideoMessage() != nil || msg.GetPtvMessage() != nil:
+		vm := msg.GetVideoMessage()
+		if vm == nil {
+			vm = msg.GetPtvMessage()
+		}
+		tag := "[Video " + duration(vm.GetSeconds()) + "]"
+		if vm.GetGifPlayback() {
+			tag = "[GIF]"
+		}
+		meta.Type, text = "video", withCaption(tag, vm.GetCaption())
+		payload["mimetype"], payload["caption"], payload["seconds"] = vm.GetMimetype(), vm.GetCaption(), vm.GetSeconds()
+		viewOnce = viewOnce || vm.GetViewOnce()
+	case msg.GetAudioMessage() != nil:
+		am := msg.GetAudioMessage()
+		meta.Type, text = "audio", "[Audio "+duration(am.GetSeconds())+"]"
+		if am.GetPTT() {
+			meta.Type, text = "voice", "[Voice note "+duration(am.GetSeconds())+"]"
+		}
+		payload["mimetype"], payload["seconds"] = am.GetMimetype(), am.GetSeconds()
+		viewOnce = viewOnce || am.GetViewOnce()
+	case msg.GetDocumentMessage() != nil:
+		dm := msg.GetDocumentMessage()
+		name := dm.GetFileName()
+		if name == "" {
+			name = dm.GetTitle()
+		}
+		meta.Type, text = "document", withCaption(fmt.Sprintf("[Document: %s]", name), dm.GetCaption())
+		payload["mimetype"], payload["filename"], payload["caption"], payload["pages"] = dm.GetMimetype(), name, dm.GetCaption(), dm.GetPageCount()
+	case msg.GetStickerMessage() != nil:
+		sm := msg.GetStickerMessage()
+		meta.Type, text = "sticker", "[Sticker]"
+		if sm.GetAccessibilityLabel() != "" {
+			text = fmt.Sprintf("[Sticker: %s]", sm.GetAccessibilityLabel())
+		}
+		payload["mimetype"], payload["animated"] = sm.GetMimetype(), sm.GetIsAnimated()
+
+	case msg.GetLocationMessage() != nil:
+		lm := msg.GetLocationMessage()
+		meta.Type, text = "location", locationText("[Location]", lm.GetDegreesLatitude(), lm.GetDegreesLongitude(), lm.GetName(), lm.GetAddress(), lm.GetComment())
+		payload["latitude"], payload["longitude"], payload["name"], payload["address"], payload["url"] = lm.GetDegreesLatitude(), lm.GetDegreesLongitude(), lm.GetName(), lm.GetAddress(), lm.GetURL()
+	case msg.GetLiveLocationMessage() != nil:
+		ll := msg.GetLiveLocationMessage()
+		meta.Type, text = "live_location", locationText("[Live location]", ll.GetDegreesLatitude(), ll.GetDegreesLongitude(), ll.GetCaption())
+		payload["latitude"], payload["longitude"], payload["caption"] = ll.GetDegreesLatitude(), ll.GetDegreesLongitude(), ll.GetCaption()
+	case msg.GetContactMessage() != nil:
+		entry, data := contactEntry(msg.GetContactMessage())
+		meta.Type, text, payload = "contact", "[Contact] "+entry, data
+	case msg.GetContactsArrayMessage() != nil:
+		var entries []string
+		var contacts []map[string]interface{}
+		for _, c := range msg.GetContactsArrayMessage().GetContacts() {
+			entry, data := contactEntry(c)
+			entries = append(entries, entry)
+			contacts = append(contacts, data)
+		}
+		meta.Type, text = "contacts", "[Contacts] "+strings.Join(entries, "; ")
+		payload["contacts"] = contacts
+
+	case pollCreation(msg) != nil:
+		p := pollCreation(msg)
+		options := make([]string, len(p.GetOptions()))
+		for i, o := range p.GetOptions() {
+			options[i] = o.GetOptionName()
+		}
+		meta.Type, text = "poll", PollText(p.GetName(), options, int(p.GetSelectableOptionsCount()))
+		payload["question"], payload["options"], payload["selectable"] = p.GetName(), options, p.GetSelectableOptionsCount()
+	case msg.GetPollUpdateMessage() != nil:
+		meta.Type = "poll_vote"
+		payload["poll_id"] = msg.GetPollUpdateMessage().GetPollCreationMessageKey().GetID()
+	case msg.GetReactionMessage() != nil:
+		rm := msg.GetReactionMessage()
+		meta.Type, meta.QuotedID, text = "reaction", rm.GetKey().GetID(), ReactionText(rm.GetText(), rm.GetKey().GetID(), "")
+		payload["emoji"] = rm.GetText()
+	case msg.GetProtocolMessage() != nil:
+		pm := msg.GetProtocolMessage()
+		meta.Type, meta.QuotedID = "protocol", pm.GetKey().GetID()
+		payload["protocol"] = pm.GetType().String()
+		// Author of the target message, checked by Messenger.RecordChange
+		payload["from_me"] = pm.GetKey().GetFromMe()
+		if participant := pm.GetKey().GetParticipant(); participant != "" {
+			payload["participant"] = participant
+		}
+		switch pm.GetType() {
+		case waProto.ProtocolMessage_MESSAGE_EDIT:
+			// The caller applies edits and revocations to the original message (Messenger.RecordChange)
+			edited, _ := Normalize(pm.GetEditedMessage())
+			meta.Type, text = "edit", EditedText(pm.GetKey().GetID(), edited)
+			payload["text"] = edited
+		case waProto.ProtocolMessage_REVOKE:
+			meta.Type, text = "revoke", DeletedText(pm.GetKey().GetID())
+		}
+
+	case msg.GetButtonsMessage() != nil:
+		bm := msg.GetButtonsMessage()
+		var choices []choice
+		text = bm.GetContentText()
+		if len(bm.GetButtons()) > 0 {
+			text += "\n\n[Opciones de respuesta - responder con el buttonID JSON]:"
+			for _, btn := range bm.GetButtons() {
+				text += fmt.Sprintf("\n- \"%s\" -> buttonID: %s", btn.GetButtonText().GetDisplayText(), btn.GetButtonID())
+				choices = append(choices, choice{Text: btn.GetButtonText().GetDisplayText(), ID: btn.GetButtonID()})
+			}
+		}
+		meta.Type, payload["choices"] = "buttons", choices
+	case msg.GetListMessage() != nil:
+		lm := msg.GetListMessage()
+		var choices []choice
+		text = lm.GetDescription()
+		if len(lm.GetSections()) > 0 {
+			text += "\n\n[Opciones de lista - responder con el rowID JSON]:"
+			for _, section := range lm.GetSections() {
+				for _, row := range section.GetRows() {
+					text += fmt.Sprintf("\n- \"%s\" -> rowID: %s", row.GetTitle(), row.GetRowID())
+					choices = append(choices, choice{Text: row.GetTitle(), ID: row.GetRowID()})
+				}
+			}
+		}
+		meta.Type, payload["choices"] = "list", choices
+	case msg.GetTemplateMessage() != nil:
+		tm := msg.GetTemplateMessage()
+		ht := tm.GetHydratedTemplate()
+		if ht == nil {
+			ht = tm.GetHydratedFourRowTemplate()
+		}
+		var choices []choice
+		for _, b := range ht.GetHydratedButtons() {
+			switch {
+			case b.GetQuickReplyButton() != nil:
+				choices = append(choices, choice{Text: b.GetQuickReplyButton().GetDisplayText(), ID: b.GetQuickReplyButton().GetID()})
+			case b.GetUrlButton() != nil:
+				choices = append(choices, choice{Text: b.GetUrlButton().GetDisplayText(), URL: b.GetUrlButton().GetURL()})
+			case b.GetCallButton() != nil:
+				choices = append(choices, choice{Text: b.GetCallButton().GetDisplayText(), Phone: b.GetCallButton().GetPhoneNumber()})
+			}
+		}
+		text = joinLines(ht.GetHydratedTitleText(), ht.GetHydratedContentText(), ht.GetHydratedFooterText()) + choicesText("[Opciones - responder con el id]:", choices)
+		if im := tm.GetInteractiveMessageTemplate(); ht == nil && im != nil {
+			text, choices = interactiveEntry(im)
+		}
+		meta.Type, payload["choices"] = "template", choices
+	case msg.GetInteractiveMessage() != nil:
+		var choices []choice
+		text, choices = interactiveEntry(msg.GetInteractiveMessage())
+		meta.Type, payload["choices"] = "interactive", choices
+
+	case msg.GetButtonsResponseMessage() != nil:
+		br := msg.GetButtonsResponseMessage()
+		meta.Type, text = "button_reply", fmt.Sprintf("(selected %q)", br.GetSelectedDisplayText())
+		payload["id"], payload["text"] = br.GetSelectedButtonID(), br.GetSelectedDisplayText()
+	case msg.GetListResponseMessage() != nil:
+		lr := msg.GetListResponseMessage()
+		meta.Type, text = "button_reply", fmt.Sprintf("(selected %q)", lr.GetTitle())
+		payload["id"], payload["text"] = lr.GetSingleSelectReply().GetSelectedRowID(), lr.GetTitle()
+	case msg.GetTemplateButtonReplyMessage() != nil:
+		tr := msg.GetTemplateButtonReplyMessage()
+		meta.Type, text = "button_reply", fmt.Sprintf("(selected %q)", tr.GetSelectedDisplayText())
+		payload["id"], payload["text"] = tr.GetSelectedID(), tr.GetSelectedDisplayText()
+	case msg.GetInteractiveResponseMessage() != nil:
+		ir := msg.GetInteractiveResponseMessage()
+		meta.Type, text = "button_reply", fmt.Sprintf("(selected %q)", ir.GetBody().GetText())
+		payload["id"], payload["params"] = ir.GetNativeFlowResponseMessage().GetName(), ir.GetNativeFlowResponseMessage().GetParamsJSON()
+
+	case msg.GetGroupInviteMessage() != nil:
+		gi := msg.GetGroupInviteMessage()
+		meta.Type, text = "group_invite", withCaption(fmt.Sprintf("[Group invite: %s]", gi.GetGroupName()), gi.GetCaption())
+		payload["group"], payload["name"], payload["code"] = gi.GetGroupJID(), gi.GetGroupName(), gi.GetInviteCode()
+	case msg.GetEventMessage() != nil:
+		ev := msg.GetEventMessage()
+		start := time.Unix(ev.GetStartTime(), 0)
+		tag := fmt.Sprintf("[Event: %s, %s]", ev.GetName(), start.Format("2006-01-02 15:04"))
+		if ev.GetIsCanceled() {
+			tag = fmt.Sprintf("[Canceled event: %s]", ev.GetName())
+		}
+		meta.Type, text = "event", joinLines(tag, ev.GetDescription(), ev.GetLocation().GetName())
+		payload["name"], payload["start"], payload["canceled"], payload["link"] = ev.GetName(), start, ev.GetIsCanceled(), ev.GetJoinLink()
+	}
+
+	if viewOnce {
+		payload["view_once"] = true
+		if text != "" {
+			text = "[View once] " + text
+		}
+	}
+	if len(payload) > 0 {
+		meta.Payload = payload
+	}
+	return text, meta
+}
@@ -0,0 +1,66 @@
+package whatsapp
+
+import (
+	"strings"
+	"testing"
+
+	"go.mau.fi/whatsmeow/proto/waCommon"
+	waProto "go.mau.fi/whatsmeow/proto/waE2E"
+	"google.golang.org/protobuf/proto"
+)
+
+func TestNormalize(t *testing.T) {
+	cases := []struct {
+		name     string
+		msg      *waProto.Message
+		wantType string
+		wantText string
+	}{
+		{"text", &waProto.Message{Conversation: proto.String("hola")}, "text", "hola"},
+		{"reply", &waProto.Message{ExtendedTextMessage: &waProto.ExtendedTextMessage{
+			Text:        proto.String("yes"),
+			ContextInfo: &waProto.ContextInfo{StanzaID: proto.String("A1")},
+		}}, "text", "yes"},
+		{"location", &waProto.Message{LocationMessage: &waProto.LocationMessage{
+			DegreesLatitude: proto.Float64(-34.6), DegreesLongitude: proto.Float64(-58.4), Name: proto.String("Obelisco"),
+		}}, "location", "[Location] Obelisco (-34.600000, -58.400000)"},
+		{"contact", &waProto.Message{ContactMessage: &waProto.ContactMessage{
+			DisplayName: proto.String("Juan"),
+			Vcard:       proto.String("BEGIN:VCARD\nFN:Juan\nTEL;type=CELL;waid=5491100000000:+54 9 11 0000-0000\nEND:VCARD"),
+		}}, "contact", "[Contact] Juan: +54 9 11 0000-0000"},
+		{"view once voice note", &waProto.Message{ViewOnceMessageV2: &waProto.FutureProofMessage{Message: &waProto.Message{
+			AudioMessage: &waProto.AudioMessage{PTT: proto.Bool(true), Seconds: proto.Uint32(65)},
+		}}}, "voice", "[View once] [Voice note 1:05]"},
+		{"sticker", &waProto.Message{EphemeralMessage: &waProto.FutureProofMessage{Message: &waProto.Message{
+			StickerMessage: &waProto.StickerMessage{},
+		}}}, "sticker", "[Sticker]"},
+		{"poll", &waProto.Message{PollCreationMessageV3: &waProto.PollCreationMessage{
+			Name:                   proto.String("Which day?"),
+			Options:                []*waProto.PollCreationMessage_Option{{OptionName: proto.String("Fri")}, {OptionName: proto.String("Sat")}},
+			SelectableOptionsCount: proto.Uint32(1),
+		}}, "poll", "[Poll] Which day? Options: Fri / Sat"},
+		{"button reply", &waProto.Message{ButtonsResponseMessage: &waProto.ButtonsResponseMessage{
+			Response:         &waProto.ButtonsResponseMessage_SelectedDisplayText{SelectedDisplayText: "Confirm"},
+			SelectedButtonID: proto.String("ok"),
+		}}, "button_reply", `(selected "Confirm")`},
+		{"revoke", &waProto.Message{ProtocolMessage: &waProto.ProtocolMessage{
+			Type: waProto.ProtocolMessage_REVOKE.Enum(), Key: &waCommon.MessageKey{ID: proto.String("A1")},
+		}}, "revoke", "(deleted #A1)"},
+		{"key distribution", &waProto.Message{SenderKeyDistributionMessage: &waProto.SenderKeyDistributionMessage{}}, "unknown", ""},
+	}
+	for _, c := range cases {
+		text, meta := Normalize(c.msg)
+		if meta.Type != c.wantType || !strings.HasPrefix(text, c.wantText) || (c.wantText == "" && text != "") {
+			t.Errorf("%s: Normalize = %q (%s), want %q (%s)", c.name, text, meta.Type, c.wantText, c.wantType)
+		}
+	}
+
+	_, meta := Normalize(cases[1].msg)
+	if meta.QuotedID != "A1" {
+		t.Errorf("expected the quoted ID, got %q", meta.QuotedID)
+	}
+	_, meta = Normalize(cases[4].msg)
+	if meta.Payload["view_once"] != true || meta.Payload["seconds"] != uint32(65) {
+		t.Errorf("unexpected payload %v", meta.Payload)
+	}
+}
@@ -0,0 +1,173 @@
+package whatsapp
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"strings"
+	"time"
+	"whatsabladerunner/pkg/history"
+
+	"go.mau.fi/whatsmeow"
+	waProto "go.mau.fi/whatsmeow/proto/waE2E"
+	"go.mau.fi/whatsmeow/types/events"
+)
+
+// PollText renders a poll as a history line, e.g. "[Poll] Which day? Options: Friday / Saturday"
+func PollText(question string, options []string, selectable int) string {
+	text := fmt.Sprintf("[Poll] %s Options: %s", question, strings.Join(options, " / "))
+	if selectable != 1 {
+		text += " (multiple choice)"
+	}
+	return text
+}
+
+// VoteText renders a vote as a history line with the current tally
+func VoteText(pollID string, selected []string, result *history.PollResult) string {
+	text := fmt.Sprintf("(removed the vote in poll #%s", pollID)
+	if len(selected) > 0 {
+		text = fmt.Sprintf("(voted %s in poll #%s", strings.Join(selected, ", "), pollID)
+	}
+	if result != nil {
+		text += " " + result.Summary()
+	}
+	return text + ")"
+}
+
+// pollCreation returns the poll of a message, whatever its version
+func pollCreation(msg *waProto.Message) *waProto.PollCreationMessage {
+	for _, p := range []*waProto.PollCreationMessage{msg.GetPollCreationMessage(), msg.GetPollCreationMessageV2(), msg.GetPollCreationMessageV3(), msg.GetPollCreationMessageV5()} {
+		if p != nil {
+			return p
+		}
+	}
+	return nil
+}
+
+// IsPoll reports whether the message creates a poll
+func IsPoll(msg *waProto.Message) bool {
+	return pollCreation(msg) != nil
+}
+
+// matchOptions maps the selected option hashes back to the option names
+func matchOptions(options []string, hashes [][]byte) []string {
+	var selected []string
+	optionHashes := whatsmeow.HashPollOptions(options)
+	for i, h := range optionHashes {
+		for _, voted := range hashes {
+			if bytes.Equal(h, voted) {
+				selected = append(selected, options[i])
+				break
+			}
+		}
+	}
+	return selected
+}
+
+// SendPoll sends a poll to a chat and stores it, returning the poll ID
+func (m *Messenger) SendPoll(chatJID, question string, options []string, multi bool) (string, error) {
+	client := m.Client()
+	if client == nil || client.Store.ID == nil {
+		return "", fmt.Errorf("whatsapp is not connected")
+	}
+	chat, err := parseChat(chatJID)
+	if err != nil {
+		return "", err
+	}
+	selectable := 1
+	if multi {
+		selectable = 0
+	}
+	resp, err := SendWithStealth(context.Background(), client, chat, client.BuildPollCreation(question, options, selectable))
+	if err != nil {
+		return "", fmt.Errorf("failed to send poll: %w", err)
+	}
+	poll := history.Poll{MessageID: resp.ID, ChatJID: chatJID, SenderJID: "Me", Question: question, Options: options, Selectable: selectable, CreatedAt: time.Now(), IsFromMe: true}
+	if err := m.History.SavePoll(poll); err != nil {
+		return resp.ID, err
+	}
+	return resp.ID, m.History.SaveMessage(resp.ID, chatJID, "Me", PollText(question, options, selectable), time.Now(), true)
+}
+
+// PollResults summarizes a poll, or the latest polls of a chat ("" for every chat) when pollID is empty
+func (m *Messenger) PollResults(chatJID, pollID string, limit int) ([]string, error) {
+	var polls []history.Poll
+	if pollID != "" {
+		poll, err := m.History.GetPoll(pollID)
+		if err != nil {
+			return nil, err
+		}
+		if poll == nil || (chatJID != "" && poll.ChatJID != chatJID) {
+			return nil, fmt.Errorf("poll #%s not found", pollID)
+		}
+		polls = append(polls, *poll)
+	} else {
+		var err error
+		if polls, err = m.History.RecentPolls(chatJID, limit); err != nil {
+			return nil, err
+		}
+	}
+
+	var summaries []string
+	for _, poll := range polls {
+		result, err := m.History.PollResults(poll.MessageID)
+		if err != nil {
+			return nil, err
+		}
+		summaries = append(summaries, fmt.Sprintf("Poll #%s in %s (%s): %s", poll.MessageID, poll.ChatJID, poll.CreatedAt.Format("2006-01-02 15:04"), result.Summary()))
+	}
+	return summaries, nil
+}
+
+// RecordPoll stores an incoming poll and returns its history line
+func (m *Messenger) RecordPoll(evt *events.Message) string {
+	p := pollCreation(evt.Message)
+	options := make([]string, len(p.GetOptions()))
+	for i, o := range p.GetOptions() {
+		options[i] = o.GetOptionName()
+	}
+	poll := history.Poll{
+		MessageID:  evt.Info.ID,
+		ChatJID:    evt.Info.Chat.String(),
+		SenderJID:  evt.Info.Sender.String(),
+		Question:   p.GetName(),
+		Options:    options,
+		Selectable: int(p.GetSelectableOptionsCount()),
+		CreatedAt:  evt.Info.Timestamp,
+		IsFromMe:   evt.Info.IsFromMe,
+	}
+	if err := m.History.SavePoll(poll); err != nil {
+		fmt.Printf("Failed to save poll: %v\n", err)
+	}
+	return PollText(poll.Question, poll.Options, poll.Selectable)
+}
+
+// RecordVote decrypts an incoming vote update, stores it and returns its history line
+func (m *Messenger) RecordVote(evt *events.Message) (string, error) {
+	client := m.Client()
+	if client == nil {
+		return "", fmt.Errorf("whatsapp is not connected")
+	}
+	pollID := evt.Message.GetPollUpdateMessage().GetPollCreationMessageKey().GetID()
+	poll, err := m.History.GetPoll(pollID)
+	if err != nil {
+		return "", err
+	}
+	if poll == nil {
+		return "", fmt.Errorf("vote for unknown poll #%s", pollID)
+	}
+	vote, err := client.DecryptPollVote(context.Background(), evt)
+	if err != nil {
+		return "", err
+	}
+
+	selected := matchOptions(poll.Options, vote.GetSelectedOptions())
+	if err := m.History.SaveVote(pollID, evt.Info.Sender.String(), selected, evt.Info.Timestamp); err != nil {
+		return "", err
+	}
+	result, err := m.History.PollResults(pollID)
+	if err != nil {
+		return "", err
+	}
+	return VoteText(pollID, selected, result), nil
+}
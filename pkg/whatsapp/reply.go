@@ -0,0 +1,24 @@
+package whatsapp
+
+import (
+	waProto "go.mau.fi/whatsmeow/proto/waE2E"
+	"go.mau.fi/whatsmeow/types"
+	"google.golang.org/protobuf/proto"
+)
+
+// ReplyMessage builds a text message quoting an earlier message of the chat.
+// sender is the author of the quoted message (our own JID for our messages).
+func ReplyMessage(text, quotedID string, sender types.JID, quotedText string) *waProto.Message {
+	return &waProto.Message{
+		ExtendedTextMessage: &waProto.ExtendedTextMessage{
+			Text: proto.String(text),
+			ContextInfo: &waProto.ContextInfo{
+				StanzaID:    proto.String(quotedID),
+				Participant: proto.String(sender.ToNonAD().String()),
+				QuotedMessage: &waProto.Message{
+					Conversation: proto.String(quotedText),
+				},
+			},
+		},
+	}
+}
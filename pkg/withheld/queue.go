@@ -0,0 +1,229 @@
+package withheld
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Item status constants
+const (
+	StatusPending   = "pending"
+	StatusReleased  = "released"
+	StatusDiscarded = "discarded"
+	StatusRetried   = "retried"
+	StatusExpired   = "expired"
+)
+
+// DefaultTTL is how long a withheld action waits for the master before expiring
+const DefaultTTL = 24 * time.Hour
+
+// Item is an action blocked by the watcher, waiting for the master to decide
+type Item struct {
+	ID          int             `json:"id"`
+	Action      string          `json:"action"`
+	Payload     json.RawMessage `json:"payload"`
+	Summary     string          `json:"summary"` // Watcher rendering of the action
+	Reason      string          `json:"reason"`  // Why the watcher blocked it
+	Mode        string          `json:"mode"`    // command, task or behavior
+	TaskID      int             `json:"task_id,omitempty"`
+	BehaviorIDs []int           `json:"behavior_ids,omitempty"`
+	ChatJID     string          `json:"chat_jid,omitempty"` // Target chat
+	Message     string          `json:"message,omitempty"`  // Incoming message of the originating turn
+	Context     []string        `json:"context,omitempty"`
+	Status      string          `json:"status"`
+	CreatedAt   int64           `json:"created_at"`
+	ExpiresAt   int64           `json:"expires_at"`
+	ResolvedAt  int64           `json:"resolved_at,omitempty"`
+}
+
+// Source describes where the item came from, for messages to the master
+func (it *Item) Source() string {
+	switch {
+	case it.TaskID != 0:
+		return fmt.Sprintf("task %d", it.TaskID)
+	case len(it.BehaviorIDs) > 0:
+		ids := make([]string, len(it.BehaviorIDs))
+		for i, id := range it.BehaviorIDs {
+			ids[i] = "#" + strconv.Itoa(id)
+		}
+		return "behavior " + strings.Join(ids, ", ")
+	}
+	return it.Mode
+}
+
+// Queue stores withheld actions as JSON files. Items hold message text, so the files are
+// only readable by the owner; use one Queue per directory, the lock doesn't span instances.
+type Queue struct {
+	Dir string
+	TTL time.Duration
+	mu  sync.Mutex
+}
+
+// NewQueue creates a Queue for the given directory
+func NewQueue(dir string) *Queue {
+	return &Queue{Dir: dir, TTL: DefaultTTL}
+}
+
+func (q *Queue) itemPath(id int) string {
+	return filepath.Join(q.Dir, fmt.Sprintf("%d.json", id))
+}
+
+// getNextID gets the next auto-increment ID
+func (q *Queue) getNextID() (int, error) {
+	lastIDPath := filepath.Join(q.Dir, "_last_id")
+	if err := os.MkdirAll(q.Dir, 0700); err != nil {
+		return 0, fmt.Errorf("failed to create withheld directory: %w", err)
+	}
+
+	lastID := 0
+	data, err := os.ReadFile(lastIDPath)
+	if err != nil && !os.IsNotExist(err) {
+		return 0, fmt.Errorf("failed to read _last_id: %w", err)
+	}
+	if err == nil {
+		idStr := strings.TrimSpace(string(data))
+		if lastID, err = strconv.Atoi(idStr); err != nil {
+			return 0, fmt.Errorf("failed to parse _last_id '%s': %w", idStr, err)
+		}
+	}
+
+	nextID := lastID + 1
+	if err := os.WriteFile(lastIDPath, []byte(strconv.Itoa(nextID)), 0600); err != nil {
+		return 0, fmt.Errorf("failed to update _last_id: %w", err)
+	}
+	return nextID, nil
+}
+
+// Add stores a new pending item and returns it with its ID
+func (q *Queue) Add(item Item) (*Item, error) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	id, err := q.getNextID()
+	if err != nil {
+		return nil, err
+	}
+	now := time.Now()
+	item.ID = id
+	item.Status = StatusPending
+	item.CreatedAt = now.Unix()
+	item.ExpiresAt = now.Add(q.TTL).Unix()
+	if err := q.save(&item); err != nil {
+		return nil, err
+	}
+	return &item, nil
+}
+
+func (q *Queue) save(item *Item) error {
+	data, err := json.MarshalIndent(item, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to marshal withheld item %d: %w", item.ID, err)
+	}
+	if err := os.WriteFile(q.itemPath(item.ID), data, 0600); err != nil {
+		return fmt.Errorf("failed to write withheld item %d: %w", item.ID, err)
+	}
+	return nil
+}
+
+func (q *Queue) load(id int) (*Item, error) {
+	data, err := os.ReadFile(q.itemPath(id))
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, fmt.Errorf("withheld item #%d not found", id)
+		}
+		return nil, fmt.Errorf("failed to read withheld item %d: %w", id, err)
+	}
+	var item Item
+	if err := json.Unmarshal(data, &item); err != nil {
+		return nil, fmt.Errorf("failed to parse withheld item %d: %w", id, err)
+	}
+	return &item, nil
+}
+
+// expire marks a pending item as expired if its time has passed
+func (q *Queue) expire(item *Item, now time.Time) {
+	if item.Status == StatusPending && item.ExpiresAt > 0 && now.Unix() >= item.ExpiresAt {
+		item.Status = StatusExpired
+		item.ResolvedAt = now.Unix()
+		if err := q.save(item); err != nil {
+			fmt.Printf("Warning: %v\n", err)
+		}
+	}
+}
+
+// Get loads an item by ID
+func (q *Queue) Get(id int) (*Item, error) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	item, err := q.load(id)
+	if err != nil {
+		return nil, err
+	}
+	q.expire(item, time.Now())
+	return item, nil
+}
+
+// Pending returns the pending items, oldest first. Expired items are marked on the way.
+func (q *Queue) Pending() ([]Item, error) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	entries, err := os.ReadDir(q.Dir)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return []Item{}, nil
+		}
+		return nil, fmt.Errorf("failed to read withheld directory: %w", err)
+	}
+
+	now := time.Now()
+	items := []Item{}
+	for _, entry := range entries {
+		if entry.IsDir() || !strings.HasSuffix(entry.Name(), ".json") {
+			continue
+		}
+		id, err := strconv.Atoi(strings.TrimSuffix(entry.Name(), ".json"))
+		if err != nil {
+			continue
+		}
+		item, err := q.load(id)
+		if err != nil {
+			fmt.Printf("Warning: %v\n", err)
+			continue
+		}
+		q.expire(item, now)
+		if item.Status == StatusPending {
+			items = append(items, *item)
+		}
+	}
+	sort.Slice(items, func(i, j int) bool { return items[i].ID < items[j].ID })
+	return items, nil
+}
+
+// Resolve moves a pending item to a final status and returns it
+func (q *Queue) Resolve(id int, status string) (*Item, error) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	item, err := q.load(id)
+	if err != nil {
+		return nil, err
+	}
+	q.expire(item, time.Now())
+	if item.Status != StatusPending {
+		return nil, fmt.Errorf("withheld item #%d is already %s", id, item.Status)
+	}
+	item.Status = status
+	item.ResolvedAt = time.Now().Unix()
+	if err := q.save(item); err != nil {
+		return nil, err
+	}
+	return item, nil
+}
@@ -0,0 +1,46 @@
+package withheld
+
+import (
+	"os"
+	"testing"
+	"time"
+)
+
+func TestQueueLifecycle(t *testing.T) {
+	q := NewQueue(t.TempDir())
+
+	first, err := q.Add(Item{Action: "response", Summary: "hola", Mode: "task", TaskID: 3})
+	if err != nil {
+		t.Fatalf("Add failed: %v", err)
+	}
+	second, _ := q.Add(Item{Action: "send_media", Mode: "behavior", BehaviorIDs: []int{1, 2}})
+	if first.ID != 1 || second.ID != 2 {
+		t.Fatalf("unexpected IDs %d, %d", first.ID, second.ID)
+	}
+	if info, err := os.Stat(q.itemPath(first.ID)); err != nil || info.Mode().Perm() != 0600 {
+		t.Errorf("expected item file with mode 0600, got %v (%v)", info.Mode().Perm(), err)
+	}
+	if second.Source() != "behavior #1, #2" {
+		t.Errorf("unexpected source %q", second.Source())
+	}
+
+	if _, err := q.Resolve(first.ID, StatusReleased); err != nil {
+		t.Fatalf("Resolve failed: %v", err)
+	}
+	if _, err := q.Resolve(first.ID, StatusDiscarded); err == nil {
+		t.Error("expected resolving twice to fail")
+	}
+
+	q.TTL = -time.Second
+	third, _ := q.Add(Item{Action: "response"})
+	pending, err := q.Pending()
+	if err != nil {
+		t.Fatalf("Pending failed: %v", err)
+	}
+	if len(pending) != 1 || pending[0].ID != second.ID {
+		t.Fatalf("expected only #2 pending, got %+v", pending)
+	}
+	if got, _ := q.Get(third.ID); got.Status != StatusExpired {
+		t.Errorf("expected #3 expired, got %s", got.Status)
+	}
+}
@@ -43,11 +43,12 @@ type CommandWorkflow struct {
 	Contacts string
 }
 
-func NewCommandWorkflow(client llm.Client, sendFunc func(string), sendMasterFunc func(string), contacts string, startTaskCallback func(*tasks.Task), reporter tasks.Reporter, searchFunc func(string) string) *CommandWorkflow {
+func NewCommandWorkflow(client llm.Client, sendFunc func(string), sendMasterFunc func(string), contacts string, startTaskCallback func(*tasks.Task), reporter tasks.Reporter, searchFunc func(string) string, language func() string) *CommandWorkflow {
 	// Assuming config is in "config" dir relative to CWD
 	// Pass sendFunc to Bot so it can handle response actions.
 	b := bot.NewBot(client, "config", sendFunc, sendMasterFunc, contacts, reporter)
 	b.StartTaskCallback = startTaskCallback
+	b.Language = language
 	if searchFunc != nil {
 		b.SearchContactsFunc = searchFunc
 		b.ActionRegistry.Register(&actions.SearchContactsAction{